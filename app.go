@@ -32,9 +32,14 @@ type Middleware func(next HandleFunc) HandleFunc
 // http.Server type.
 type App struct {
 	mu sync.RWMutex
+	// reloadMu guards the templates of the view engines, and the templates that the viewers pick,
+	// while the files are reloaded in watch mode. A request only holds it to look them up, and
+	// executes the parsed templates that HtmlTemplate and TextTemplate swap atomically.
+	reloadMu sync.RWMutex
 
 	mux            *http.ServeMux
 	middlewares    []Middleware
+	named          map[string]Middleware
 	viewers        map[string]Viewer
 	routes         map[string]*Routing
 	handlerViewers []Viewer
@@ -43,12 +48,22 @@ type App struct {
 	fsys           fs.FS
 	watch          bool
 	watcher        *fsnotify.Watcher
+	liveReload     *liveReload
+	loaders        map[string]LoaderFunc
 	interceptor    Interceptor
 	compressors    []Compressor
+	localeCookie   string
+	locales        []string
+	defaultLocale  string
+	formats        map[string]string
+	etag           bool
+	assetPipeline  bool
 
 	funcMap        template.FuncMap
+	noBuiltinFuncs bool
 	buildAssetURLs []func(string) bool
 	AssetURLs      map[string]string
+	AssetIntegrity map[string]string
 }
 
 // New allocates an App instance and loads all view engines.
@@ -61,8 +76,10 @@ func New(opts ...Option) *App {
 		routes:         make(map[string]*Routing),
 		viewers:        make(map[string]Viewer),
 		handlerViewers: []Viewer{&JsonViewer{}},
-		funcMap:        builtins,
+		funcMap:        make(template.FuncMap),
 		AssetURLs:      make(map[string]string),
+		AssetIntegrity: make(map[string]string),
+		localeCookie:   DefaultLocaleCookie,
 	}
 
 	for _, o := range opts {
@@ -72,6 +89,19 @@ func New(opts ...Option) *App {
 		app.logger = slog.Default()
 	}
 
+	if !app.noBuiltinFuncs {
+		for name, fn := range builtins {
+			if _, ok := app.funcMap[name]; !ok {
+				app.funcMap[name] = fn
+			}
+		}
+	}
+
+	// flush is a part of streaming pages, not of the function library
+	if _, ok := app.funcMap["flush"]; !ok {
+		app.funcMap["flush"] = flush
+	}
+
 	if app.mux == nil {
 		app.mux = http.DefaultServeMux
 	}
@@ -86,12 +116,16 @@ func New(opts ...Option) *App {
 
 	if app.fsys != nil {
 		app.funcMap["asset"] = app.getAssetUrl
+		app.funcMap["assetTag"] = app.getAssetTag
 
 		for _, ve := range app.engines {
 			ve.Load(app.fsys, app)
 		}
 
 		if app.watch {
+			app.liveReload = newLiveReload()
+			app.mux.Handle(liveReloadPattern, app.liveReload)
+
 			app.watcher = fsnotify.NewWatcher(app.fsys)
 			if err := app.watcher.Add("."); err != nil {
 				app.logger.Error("xun: watcher add", slog.Any("err", err))
@@ -106,13 +140,43 @@ func New(opts ...Option) *App {
 
 func (app *App) getAssetUrl(pattern string) string {
 	// lock-free, because AssetURLs is initialized in New() in production
-	if _, ok := app.AssetURLs[pattern]; ok {
-		return app.AssetURLs[pattern]
+	defer app.rlock()()
+
+	if it, ok := app.AssetURLs[pattern]; ok {
+		return it
 	}
 
 	return pattern
 }
 
+// rlock read-locks the registries of the app in watch mode, where the files are reloaded while
+// the requests are served, and returns its unlock. It doesn't lock in production, where the
+// registries are only changed before the app serves any request.
+func (app *App) rlock() func() {
+	if !app.watch {
+		return func() {}
+	}
+
+	app.mu.RLock()
+	return app.mu.RUnlock
+}
+
+// viewer returns the named viewer. The viewers are only changed after New() in watch mode.
+func (app *App) viewer(name string) (Viewer, bool) {
+	defer app.rlock()()
+
+	v, ok := app.viewers[name]
+	return v, ok
+}
+
+// routing returns a copy of the route, which is safe to use while the files are reloaded in
+// watch mode, and false if the route has been removed.
+func (app *App) routing(r *Routing) (Routing, bool) {
+	defer app.rlock()()
+
+	return *r, !r.removed
+}
+
 // Group creates a new router group with the specified prefix.
 // It returns a Router interface that can be used to define routes
 // within the group.
@@ -131,6 +195,10 @@ func (app *App) Start() {
 	defer app.mu.Unlock()
 
 	for _, r := range app.routes {
+		if r.removed {
+			continue
+		}
+
 		keys := make([]string, 0, len(r.Viewers))
 		for _, v := range r.Viewers {
 			keys = append(keys, v.MimeType().String())
@@ -240,30 +308,35 @@ func (app *App) Next(hf HandleFunc) HandleFunc {
 // This function associates a FileViewer with a given file name
 // and registers the route in the application's routing table.
 // If a route with the same pattern already exists, it returns immediately
-// without making any changes.
+// without making any changes, unless its file has been removed in watch mode.
 func (app *App) HandleFile(name string, v *FileViewer) {
 	ro := &RoutingOptions{}
 
 	_, _, pat := splitFile(name)
 
+	ro.viewers = []Viewer{v}
+
+	hf := func(c *Context) error {
+		return v.Render(c, nil)
+	}
+
 	r, ok := app.routes[pat]
 
 	if ok {
+		if r.removed {
+			app.reviveRoute(r, ro, hf, name, v)
+		}
 		return
 	}
 
-	ro.viewers = []Viewer{v}
 	app.viewers[name] = v
 
-	hf := func(c *Context) error {
-		return v.Render(c, nil)
-	}
-
 	r = &Routing{
 		Options: ro,
 		Pattern: pat,
 		Handle:  hf,
 		chain:   app,
+		static:  true,
 	}
 
 	app.routes[pat] = r
@@ -271,18 +344,24 @@ func (app *App) HandleFile(name string, v *FileViewer) {
 	r.Viewers = append(r.Viewers, v)
 
 	app.mux.HandleFunc(pat, func(w http.ResponseWriter, req *http.Request) {
+		routing, ok := app.routing(r)
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
 		rw := app.createWriter(req, w)
 		defer rw.Close()
 
 		ctx := &Context{
 			Request:  req,
 			Response: rw,
-			Routing:  *r,
+			Routing:  routing,
 			App:      app,
 			TempData: make(map[string]any),
 		}
 
-		err := r.Next(ctx)
+		err := routing.Next(ctx)
 
 		if err == nil || errors.Is(err, ErrCancelled) {
 			return
@@ -300,29 +379,38 @@ func (app *App) HandleFile(name string, v *FileViewer) {
 // This function associates a Viewer with a given route pattern
 // and registers the route in the application's routing table.
 // If a route with the same pattern already exists, it updates
-// the existing route with the new Viewer.
-func (app *App) HandlePage(pattern string, viewName string, v Viewer) {
+// the existing route with the new Viewer. The page is rendered with
+// the data of its loader, see Loader. The options are the ones that the front
+// matter of the page declares.
+func (app *App) HandlePage(pattern string, viewName string, v Viewer, opts ...RoutingOption) {
 	ro := &RoutingOptions{
 		viewers: []Viewer{v},
 	}
 
+	for _, o := range opts {
+		o(ro)
+	}
+
+	hf := app.pageHandler(viewName, v)
+
 	r, ok := app.routes[pattern]
 	if ok {
+		if r.removed {
+			app.reviveRoute(r, ro, hf, viewName, v)
+			return
+		}
 		r.Viewers = append(r.Viewers, v)
 		return
 	}
 
 	app.viewers[viewName] = v
 
-	hf := func(c *Context) error {
-		return v.Render(c, nil)
-	}
-
 	r = &Routing{
 		Options: ro,
 		Pattern: pattern,
 		Handle:  hf,
 		chain:   app,
+		static:  true,
 	}
 
 	r.Viewers = append(r.Viewers, v)
@@ -330,32 +418,102 @@ func (app *App) HandlePage(pattern string, viewName string, v Viewer) {
 	app.routes[pattern] = r
 
 	app.mux.HandleFunc(pattern, func(w http.ResponseWriter, req *http.Request) {
+		routing, ok := app.routing(r)
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
 		rw := app.createWriter(req, w)
 		defer rw.Close()
 
 		ctx := &Context{
 			Request:  req,
 			Response: rw,
-			Routing:  *r,
+			Routing:  routing,
 			App:      app,
 			TempData: make(map[string]any),
 		}
 
-		err := r.Next(ctx)
+		if app.watch {
+			defer app.recoverOverlay(ctx)
+		}
+
+		err := routing.Next(ctx)
 
 		if err == nil || errors.Is(err, ErrCancelled) {
 			return
 		}
 
+		if errors.Is(err, ErrViewNotFound) {
+			// the page has been removed in watch mode while it was requested
+			ctx.WriteStatus(http.StatusNotFound)
+			return
+		}
+
 		logID := nextLogID()
 		ctx.WriteHeader("X-Log-Id", logID)
-		ctx.WriteStatus(http.StatusInternalServerError)
+
+		// e.g. a loader that doesn't find the data of the page
+		if p, ok := asProblem(err); ok {
+			app.writeProblem(ctx, p, err, logID)
+			return
+		}
+
+		if !app.writeOverlay(ctx, err, nil, logID) {
+			ctx.WriteStatus(http.StatusInternalServerError)
+		}
 		app.logger.Error("xun: view", slog.Any("err", err), slog.String("logid", logID))
 
 	})
 
 }
 
+// reviveRoute registers the viewer of a page or a file on a route whose viewers have been
+// removed in watch mode, e.g. when a page is deleted and created again.
+func (app *App) reviveRoute(r *Routing, ro *RoutingOptions, hf HandleFunc, name string, v Viewer) {
+	r.Options = ro
+	r.Handle = hf
+	r.Viewers = []Viewer{v}
+	r.removed = false
+	app.viewers[name] = v
+}
+
+// removeViewer unregisters the viewer of a page or a file that has been removed in watch mode.
+// ServeMux can't unregister a pattern, so a route that has no viewers left is kept as a
+// tombstone that serves 404 until a page or a file is registered on it again.
+func (app *App) removeViewer(pattern, name string, v Viewer) {
+	if app.viewers[name] == v {
+		delete(app.viewers, name)
+	}
+
+	r, ok := app.routes[pattern]
+	if !ok {
+		return
+	}
+
+	viewers := make([]Viewer, 0, len(r.Viewers))
+	for _, it := range r.Viewers {
+		if it != v {
+			viewers = append(viewers, it)
+		}
+	}
+	r.Viewers = viewers
+
+	if !r.static {
+		return
+	}
+
+	if len(viewers) == 0 {
+		r.removed = true
+		return
+	}
+
+	// e.g. pages/about.md is still there after pages/about.html is removed
+	r.Options = &RoutingOptions{viewers: []Viewer{viewers[0]}}
+	r.Handle = app.pageHandler(name, viewers[0])
+}
+
 // HandleFunc registers a route handler for the given HTTP request pattern.
 //
 // The pattern is expected to be in the format "METHOD PATTERN", where
@@ -373,6 +531,9 @@ func (app *App) HandleFunc(pattern string, hf HandleFunc, opts ...RoutingOption)
 // "Accept-Encoding" header in the HTTP request. If the header contains a
 // supported encoding or a wildcard "*", it returns a compressed ResponseWriter.
 // Otherwise, it returns a standard ResponseWriter.
+//
+// A HEAD request picks the encoding like GET, so it has the same headers, but it gets a
+// standard ResponseWriter because it has no body to compress.
 func (app *App) createWriter(req *http.Request, w http.ResponseWriter) ResponseWriter {
 	acceptEncoding := req.Header.Get("Accept-Encoding")
 
@@ -380,6 +541,11 @@ func (app *App) createWriter(req *http.Request, w http.ResponseWriter) ResponseW
 
 	for _, compressor := range app.compressors {
 		if stars || strings.Contains(acceptEncoding, compressor.AcceptEncoding()) {
+			if req.Method == http.MethodHead {
+				// HEAD gets the Content-Encoding of GET, but it has no body to compress
+				w.Header().Set("Content-Encoding", compressor.AcceptEncoding())
+				return &stdResponseWriter{ResponseWriter: w}
+			}
 			return compressor.New(w)
 		}
 	}
@@ -404,6 +570,8 @@ func (app *App) createHandler(pattern string, hf HandleFunc, opts []RoutingOptio
 		r.Options = ro
 		r.Handle = hf
 		r.chain = c
+		r.static = false
+		r.removed = false
 
 		if len(ro.viewers) > 0 {
 			// append current handler's viewer to existing viewers
@@ -429,18 +597,24 @@ func (app *App) createHandler(pattern string, hf HandleFunc, opts []RoutingOptio
 	app.routes[pattern] = r
 
 	app.mux.HandleFunc(pattern, func(w http.ResponseWriter, req *http.Request) {
+		routing, _ := app.routing(r)
+
 		rw := app.createWriter(req, w)
 		defer rw.Close()
 
 		ctx := &Context{
 			Request:  req,
 			Response: rw,
-			Routing:  *r,
+			Routing:  routing,
 			App:      app,
 			TempData: make(map[string]any),
 		}
 
-		err := r.Next(ctx)
+		if app.watch {
+			defer app.recoverOverlay(ctx)
+		}
+
+		err := routing.Next(ctx)
 
 		if err == nil || errors.Is(err, ErrCancelled) {
 			return
@@ -454,12 +628,66 @@ func (app *App) createHandler(pattern string, hf HandleFunc, opts []RoutingOptio
 
 		logID := nextLogID()
 		ctx.WriteHeader("X-Log-Id", logID)
-		ctx.WriteStatus(http.StatusInternalServerError)
+
+		if p, ok := asProblem(err); ok {
+			app.writeProblem(ctx, p, err, logID)
+			return
+		}
+
+		if !app.writeOverlay(ctx, err, nil, logID) {
+			ctx.WriteStatus(http.StatusInternalServerError)
+		}
 		app.logger.Error("xun: handle", slog.Any("err", err), slog.String("logid", logID))
 	})
 
 }
 
+// writeProblem renders the problem that a handler returned, with the log id as its instance
+// if it has none. It is logged only if it is a server error.
+func (app *App) writeProblem(ctx *Context, p *Problem, err error, logID string) {
+	if p.Instance == "" {
+		it := *p
+		it.Instance = logID
+		p = &it
+	}
+
+	if p.Status == 0 || p.Status >= http.StatusInternalServerError {
+		app.logger.Error("xun: handle", slog.Any("err", err), slog.String("logid", logID))
+	}
+
+	if err := ctx.problemViewer().Render(ctx, p); err != nil {
+		ctx.WriteStatus(http.StatusInternalServerError)
+		app.logger.Error("xun: problem", slog.Any("err", err), slog.String("logid", logID))
+	}
+}
+
+// fileChanged passes a file change to all view engines. The registries of the app are locked
+// while they are changed, so the requests are served as usual during a reload.
+func (app *App) fileChanged(event fsnotify.Event) {
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
+
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	for _, ve := range app.engines {
+		if err := ve.FileChanged(app.fsys, app, event); err != nil {
+			app.logger.Error("xun: on file changed", slog.Any("err", err))
+		}
+	}
+}
+
+// rlock read-locks the reload lock of the app that reloads a viewer or a template, and returns
+// its unlock. A viewer or a template that is created without an app is never reloaded.
+func rlock(mu *sync.RWMutex) func() {
+	if mu == nil {
+		return func() {}
+	}
+
+	mu.RLock()
+	return mu.RUnlock
+}
+
 func (app *App) enableHotReload() {
 	defer app.watcher.Stop()
 	go app.watcher.Start()
@@ -471,13 +699,10 @@ func (app *App) enableHotReload() {
 				return
 			}
 
-			var err error
-			for _, ve := range app.engines {
-				err = ve.FileChanged(app.fsys, app, event)
-				if err != nil {
-					app.logger.Error("xun: on file changed", slog.Any("err", err))
-				}
-			}
+			app.fileChanged(event)
+
+			// a failed reload is shown by the error overlay after the page is reloaded
+			app.liveReload.notify(event)
 
 		case err, ok := <-app.watcher.Errors:
 			if !ok {
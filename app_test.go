@@ -41,6 +41,20 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// serve serves a request on h with the given header pairs, and returns the recorded response, e.g.
+//
+//	rw := serve(mux, http.MethodGet, "/", "Accept", "text/html")
+func serve(h http.Handler, method, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	return rw
+}
+
 func TestJsonViewer(t *testing.T) {
 
 	m := http.NewServeMux()
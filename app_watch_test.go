@@ -1,12 +1,18 @@
 package xun
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -146,7 +152,7 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header</title></head><body><div>index</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header</title></head><body><div>index</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
 	req, err = http.NewRequest("GET", srv.URL+"/admin/", nil)
 	req.Header.Set("Accept", "text/html")
@@ -158,7 +164,7 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header</title></head><body><div>admin/index</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header</title></head><body><div>admin/index</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
 	req, err = http.NewRequest("GET", srv.URL+"/admin/user", nil)
 	req.Header.Set("Accept", "text/html")
@@ -170,7 +176,7 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header</title></head><body><div>admin/user</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header</title></head><body><div>admin/user</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
 	req, err = http.NewRequest("GET", srv.URL+"/view", nil)
 	req.Header.Set("Accept", "text/html")
@@ -182,7 +188,7 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header</title></head><body><div>shared</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header</title></head><body><div>shared</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
 	// fixed data race issue on fstest.MapFile
 	app.watcher.Stop()
@@ -229,7 +235,7 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>index updated</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>index updated</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
 	req, err = http.NewRequest("GET", srv.URL+"/admin/", nil)
 	req.Header.Set("Accept", "text/html")
@@ -241,7 +247,7 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>admin/index updated</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>admin/index updated</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
 	req, err = http.NewRequest("GET", srv.URL+"/view", nil)
 	req.Header.Set("Accept", "text/html")
@@ -253,22 +259,17 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>shared updated</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>shared updated</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
-	// deleted event is not handled in html view engine, it is not updated, and does not return 404
+	// deleted
 	req, err = http.NewRequest("GET", srv.URL+"/admin/user", nil)
 	req.Header.Set("Accept", "text/html")
 	require.NoError(t, err)
 	resp, err = client.Do(req)
 	require.NoError(t, err)
-	require.Equal(t, http.StatusOK, resp.StatusCode)
-
-	buf, err = io.ReadAll(resp.Body)
-	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header</title></head><body><div>admin/user</div></body></html>", string(buf))
-
 	// added
 	req, err = http.NewRequest("GET", srv.URL+"/about", nil)
 	require.NoError(t, err)
@@ -279,10 +280,353 @@ func TestWatchOnHtml(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>about</div></body></html>", string(buf))
+	require.Equal(t, "<html><head><title>header updated</title></head><body>layout updated:<div>about</div>"+string(liveReloadTag)+"</body></html>", string(buf))
 
 }
 
+func TestWatchOnRemove(t *testing.T) {
+	fsys := fstest.MapFS{
+		"components/button.html": {Data: []byte(`<button>{{ .Props.label }}</button>`)},
+		"layouts/main.html":      {Data: []byte(`<main>{{ block "content" . }}{{ end }}</main>`)},
+		"pages/about.html":       {Data: []byte(`<!--layout:main-->{{ define "content" }}about{{ end }}`)},
+		"pages/about.fr.html":    {Data: []byte(`<!--layout:main-->{{ define "content" }}à propos{{ end }}`)},
+		"pages/old.html":         {Data: []byte(`old`)},
+		"pages/blog.html":        {Data: []byte(`<!--layout:main-->{{ define "content" }}blog{{ end }}`)},
+		"pages/buy.html":         {Data: []byte(`{{ component "components/button" "label" "buy" }}`)},
+		"pages/docs.html":        {Data: []byte(`html docs`)},
+		"pages/docs.md":          {Data: []byte("md docs\n")},
+		"pages/home.html":        {Data: []byte(`home page`)},
+		"views/card.html":        {Data: []byte(`card`)},
+		"text/robots.txt":        {Data: []byte(`User-agent: *`)},
+		"public/logo.svg":        {Data: []byte(`<svg></svg>`)},
+		"public/index.html":      {Data: []byte(`index`)},
+	}
+
+	mux := http.NewServeMux()
+	html := &HtmlViewEngine{}
+	md := &MarkdownViewEngine{}
+	text := &TextViewEngine{}
+	static := &StaticViewEngine{}
+
+	app := New(WithMux(mux), WithFsys(fsys), WithLocales("fr"), WithViewEngines(static, html, md, text),
+		WithBuildAssetURL(func(s string) bool { return s == "/logo.svg" }))
+
+	// a handler on the route of a page is not removed with the page
+	app.Get("/home", func(c *Context) error {
+		return c.View(nil, "home")
+	})
+
+	app.Get("/card", func(c *Context) error {
+		return c.View(nil, "views/card")
+	})
+
+	app.Get("/robots.txt", func(c *Context) error {
+		return c.View(nil, "text/robots.txt")
+	})
+
+	app.Start()
+	defer app.Close()
+
+	changed := func(t *testing.T, name string, op fsnotify.Op) error {
+		var errs []error
+		for _, e := range app.engines {
+			errs = append(errs, e.FileChanged(fsys, app, fsnotify.Event{Name: name, Op: op}))
+		}
+		return errors.Join(errs...)
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		delete(fsys, "pages/old.html")
+		require.NoError(t, changed(t, "pages/old.html", fsnotify.Remove))
+
+		rw := serve(mux, http.MethodGet, "/old")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		_, ok := app.viewers["old"]
+		require.False(t, ok)
+		_, ok = html.templates["pages/old"]
+		require.False(t, ok)
+
+		// it is served again when it is created again
+		fsys["pages/old.html"] = &fstest.MapFile{Data: []byte(`old again`)}
+		require.NoError(t, changed(t, "pages/old.html", fsnotify.Create))
+
+		rw = serve(mux, http.MethodGet, "/old")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "old again", rw.Body.String())
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		fsys["pages/posts.html"] = fsys["pages/blog.html"]
+		delete(fsys, "pages/blog.html")
+
+		require.NoError(t, changed(t, "pages/blog.html", fsnotify.Remove))
+		require.NoError(t, changed(t, "pages/posts.html", fsnotify.Create))
+
+		rw := serve(mux, http.MethodGet, "/blog")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		rw = serve(mux, http.MethodGet, "/posts")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<main>blog</main>", rw.Body.String())
+
+		// the layout doesn't reload the removed page any more
+		_, ok := html.templates["layouts/main"].dependents["blog.html"]
+		require.False(t, ok)
+	})
+
+	t.Run("locale", func(t *testing.T) {
+		delete(fsys, "pages/about.fr.html")
+		require.NoError(t, changed(t, "pages/about.fr.html", fsnotify.Remove))
+
+		rw := serve(mux, http.MethodGet, "/about", "Accept-Language", "fr")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<main>about</main>", rw.Body.String())
+
+		delete(fsys, "pages/about.html")
+		require.NoError(t, changed(t, "pages/about.html", fsnotify.Remove))
+
+		rw = serve(mux, http.MethodGet, "/about")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+
+	t.Run("dependents", func(t *testing.T) {
+		delete(fsys, "components/button.html")
+		err := changed(t, "components/button.html", fsnotify.Remove)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		require.ErrorContains(t, err, "pages/buy.html depends on components/button.html")
+
+		delete(fsys, "layouts/main.html")
+		err = changed(t, "layouts/main.html", fsnotify.Remove)
+		require.ErrorContains(t, err, "pages/posts.html depends on layouts/main.html")
+		require.NotContains(t, err.Error(), "pages/about.html")
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		// the markdown page is served when the html page on the same route is removed
+		delete(fsys, "pages/docs.html")
+		require.NoError(t, changed(t, "pages/docs.html", fsnotify.Remove))
+
+		rw := serve(mux, http.MethodGet, "/docs")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<p>md docs</p>\n", rw.Body.String())
+
+		delete(fsys, "pages/docs.md")
+		require.NoError(t, changed(t, "pages/docs.md", fsnotify.Remove))
+
+		rw = serve(mux, http.MethodGet, "/docs")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		delete(fsys, "pages/home.html")
+		require.NoError(t, changed(t, "pages/home.html", fsnotify.Remove))
+
+		// the route falls back to its own viewers, as if the page never existed
+		rw := serve(mux, http.MethodGet, "/home", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "null\n", rw.Body.String())
+		require.Len(t, app.routes["GET /home"].Viewers, 1)
+	})
+
+	t.Run("views", func(t *testing.T) {
+		delete(fsys, "views/card.html")
+		require.NoError(t, changed(t, "views/card.html", fsnotify.Remove))
+
+		// the views are kept as tombstones, so they serve 404 instead of the route viewer
+		_, ok := app.viewers["views/card"]
+		require.True(t, ok)
+
+		rw := serve(mux, http.MethodGet, "/card", "Accept", "text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Equal(t, "View Not Found", rw.Body.String())
+
+		delete(fsys, "text/robots.txt")
+		require.NoError(t, changed(t, "text/robots.txt", fsnotify.Remove))
+
+		_, ok = app.viewers["text/robots.txt"]
+		require.True(t, ok)
+		_, ok = text.templates["text/robots.txt"]
+		require.False(t, ok)
+
+		rw = serve(mux, http.MethodGet, "/robots.txt", "Accept", "text/plain")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Equal(t, "View Not Found", rw.Body.String())
+	})
+
+	t.Run("static", func(t *testing.T) {
+		assetURL := app.AssetURLs["/logo.svg"]
+		require.NotEmpty(t, assetURL)
+
+		rw := serve(mux, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		delete(fsys, "public/logo.svg")
+		delete(fsys, "public/index.html")
+		require.NoError(t, changed(t, "public/logo.svg", fsnotify.Remove))
+		require.NoError(t, changed(t, "public/index.html", fsnotify.Remove))
+
+		for _, path := range []string{"/logo.svg", assetURL, "/"} {
+			rw = serve(mux, http.MethodGet, path)
+			require.Equal(t, http.StatusNotFound, rw.Code, path)
+		}
+
+		_, ok := app.AssetURLs["/logo.svg"]
+		require.False(t, ok)
+
+		fsys["public/logo.svg"] = &fstest.MapFile{Data: []byte(`<svg></svg>`)}
+		require.NoError(t, changed(t, "public/logo.svg", fsnotify.Create))
+
+		rw = serve(mux, http.MethodGet, assetURL)
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, `<svg></svg>`, rw.Body.String())
+	})
+}
+
+// TestWatchConcurrentReload reloads the files continuously while they are served, and should
+// be run with -race.
+func TestWatchConcurrentReload(t *testing.T) {
+	dir := t.TempDir()
+
+	// the files are replaced by a rename, so they are never read half written
+	write := func(name, data string) error {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path+".tmp", []byte(data), 0o644); err != nil {
+			return err
+		}
+		return os.Rename(path+".tmp", path)
+	}
+
+	require.NoError(t, errors.Join(
+		write("layouts/main.html", `<main>{{ block "content" . }}{{ end }}</main>`),
+		write("components/badge.html", `<!--props: label:string!--><b>{{ .Props.label }}</b>`),
+		write("pages/index.html", `<!--layout:main-->{{ define "content" }}{{ component "components/badge" "label" "a" }}{{ end }}`),
+		write("pages/asset.html", `{{ asset "/app.css" }}`),
+		write("pages/toggle.html", `toggle`),
+		write("text/robots.txt", `User-agent: a`),
+		write("public/app.css", `a{}`),
+	))
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	app := New(WithMux(mux), WithFsys(os.DirFS(dir)), WithWatch(),
+		WithBuildAssetURL(func(s string) bool { return s == "/app.css" }),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	app.Get("/robots.txt", func(c *Context) error {
+		return c.View(nil, "text/robots.txt")
+	})
+
+	app.Start()
+	defer app.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	errs := make(chan error, 16)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ctx.Err() == nil; i++ {
+			v := string("ab"[i%2])
+			tag := []string{"main", "section"}[i%2]
+
+			if err := errors.Join(
+				write("pages/index.html", `<!--layout:main-->{{ define "content" }}{{ component "components/badge" "label" "`+v+`" }}{{ end }}`),
+				write("layouts/main.html", `<`+tag+`>{{ block "content" . }}{{ end }}</`+tag+`>`),
+				write("components/badge.html", `<!--props: label:string!--><b>{{ .Props.label }}</b>`),
+				write("text/robots.txt", `User-agent: `+v),
+				write("public/app.css", v+`{}`),
+			); err != nil {
+				errs <- err
+				return
+			}
+
+			for _, name := range []string{"pages/index.html", "layouts/main.html", "components/badge.html", "text/robots.txt", "public/app.css"} {
+				app.fileChanged(fsnotify.Event{Name: name, Op: fsnotify.Write})
+			}
+
+			if i%2 == 0 {
+				if err := os.Remove(filepath.Join(dir, "pages/toggle.html")); err != nil {
+					errs <- err
+					return
+				}
+				app.fileChanged(fsnotify.Event{Name: "pages/toggle.html", Op: fsnotify.Remove})
+			} else {
+				if err := write("pages/toggle.html", `toggle`); err != nil {
+					errs <- err
+					return
+				}
+				app.fileChanged(fsnotify.Event{Name: "pages/toggle.html", Op: fsnotify.Create})
+			}
+		}
+	}()
+
+	tests := []struct {
+		path     string
+		accept   string
+		body     *regexp.Regexp
+		notFound bool
+	}{
+		{path: "/", accept: "text/html", body: regexp.MustCompile(`^<(main|section)><b>[ab]</b></(main|section)>$`)},
+		{path: "/asset", accept: "text/html", body: regexp.MustCompile(`^/app(-[0-9a-z]+)?\.css$`)},
+		{path: "/toggle", accept: "text/html", body: regexp.MustCompile(`^toggle$`), notFound: true},
+		{path: "/robots.txt", accept: "text/plain", body: regexp.MustCompile(`^User-agent: [ab]$`)},
+		{path: "/app.css", accept: "text/css", body: regexp.MustCompile(`^[ab]\{\}$`)},
+	}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ctx.Err() == nil; n++ {
+				test := tests[n%len(tests)]
+				req, err := http.NewRequest(http.MethodGet, srv.URL+test.path, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				req.Header.Set("Accept", test.accept)
+
+				resp, err := client.Do(req)
+				if err != nil {
+					errs <- err
+					return
+				}
+				buf, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if test.notFound && resp.StatusCode == http.StatusNotFound {
+					continue
+				}
+
+				if resp.StatusCode != http.StatusOK || !test.body.Match(buf) {
+					errs <- fmt.Errorf("%s: %d %q", test.path, resp.StatusCode, buf)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
 type mockViewEngine struct {
 }
 
@@ -293,6 +637,53 @@ func (*mockViewEngine) FileChanged(fsys fs.FS, app *App, event fsnotify.Event) e
 	return errors.New("err: unhandled error")
 }
 
+func TestWatchReloadLockPerApp(t *testing.T) {
+	newApp := func() (*App, *http.ServeMux) {
+		mux := http.NewServeMux()
+		app := New(WithMux(mux), WithFsys(fstest.MapFS{
+			"pages/index.html":      {Data: []byte(`{{ component "components/badge" }}`)},
+			"components/badge.html": {Data: []byte(`<b>badge</b>`)},
+			"text/robots.txt":       {Data: []byte(`User-agent: *`)},
+		}), WithWatch())
+
+		app.Get("/robots.txt", func(c *Context) error {
+			return c.View(nil, "text/robots.txt")
+		})
+
+		app.Start()
+		t.Cleanup(app.Close)
+		return app, mux
+	}
+
+	reloading, _ := newApp()
+	_, mux := newApp()
+
+	// a reload in one app doesn't block the requests of another app
+	reloading.reloadMu.Lock()
+	defer reloading.reloadMu.Unlock()
+
+	var codes []int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, path := range []string{"/", "/robots.txt"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Accept", "text/html, text/plain")
+			rw := httptest.NewRecorder()
+			mux.ServeHTTP(rw, req)
+
+			codes = append(codes, rw.Code)
+		}
+	}()
+
+	select {
+	case <-done:
+		require.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+	case <-time.After(time.Second):
+		require.Fail(t, "blocked by the reload of another app")
+	}
+}
+
 func TestHotReloadChannels(t *testing.T) {
 	createApp := func(ve ...ViewEngine) *App {
 		fsys := fstest.MapFS{
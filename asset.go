@@ -0,0 +1,163 @@
+package xun
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+)
+
+// bundlesFile declares the bundles of the asset pipeline, e.g.
+//
+//	{
+//	  "/assets/app.js": ["/js/htmx.js", "/js/app.js"],
+//	  "/assets/app.css": ["/css/reset.css", "/css/site.css"]
+//	}
+//
+// where a bundle is the concatenation of the public files in order.
+const bundlesFile = "bundles.json"
+
+// integrity returns the Subresource Integrity hash of the content.
+func integrity(buf []byte) string {
+	sum := sha512.Sum384(buf)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// fingerprintURL returns the name of a file with its ETag, e.g. "css/site-1a2b3c4d.css".
+func fingerprintURL(pattern, etag string) string {
+	ext := path.Ext(pattern)
+	return strings.TrimSuffix(pattern, ext) + "-" + strings.Trim(etag, "\"") + ext
+}
+
+// handleBuiltAsset serves the content that the pipeline built for the file at pattern on its
+// fingerprinted url, and records the url and the integrity hash of the content.
+func (ve *StaticViewEngine) handleBuiltAsset(app *App, pattern string, buf []byte) {
+	etag := ComputeETag(bytes.NewReader(buf))
+	assetURL := fingerprintURL(pattern, etag)
+
+	app.HandleFile(assetURL, newContentViewer(assetURL, buf, etag, cacheControl))
+
+	app.AssetURLs["/"+pattern] = "/" + assetURL
+	app.AssetIntegrity["/"+pattern] = integrity(buf)
+}
+
+// loadBundles reads bundles.json and builds all bundles. The bundles that it doesn't declare
+// any more are removed.
+func (ve *StaticViewEngine) loadBundles(fsys fs.FS, app *App) {
+	bundles := make(map[string][]string)
+
+	buf, err := fs.ReadFile(fsys, bundlesFile)
+	if err == nil {
+		var it map[string][]string
+		if err := json.Unmarshal(buf, &it); err != nil {
+			app.logger.Error("xun: load bundles", slog.Any("err", err))
+		}
+
+		for name, files := range it {
+			for i, f := range files {
+				files[i] = "/" + strings.TrimPrefix(f, "/")
+			}
+			bundles["/"+strings.TrimPrefix(name, "/")] = files
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		app.logger.Error("xun: load bundles", slog.Any("err", err))
+	}
+
+	for name := range ve.bundles {
+		if _, ok := bundles[name]; !ok {
+			ve.remove(app, "public"+name)
+		}
+	}
+
+	ve.bundles = bundles
+
+	for name := range bundles {
+		ve.buildBundle(fsys, app, name)
+	}
+}
+
+// buildBundles rebuilds the bundles that have the public file at pattern, e.g. "/js/app.js".
+func (ve *StaticViewEngine) buildBundles(fsys fs.FS, app *App, pattern string) {
+	for name, files := range ve.bundles {
+		if slices.Contains(files, pattern) {
+			ve.buildBundle(fsys, app, name)
+		}
+	}
+}
+
+// buildBundle concatenates and minifies the files of a bundle, and serves it on its name and
+// on its fingerprinted url. A missing file is logged and left out.
+func (ve *StaticViewEngine) buildBundle(fsys fs.FS, app *App, name string) {
+	js := path.Ext(name) != ".css"
+
+	var buf bytes.Buffer
+	for _, it := range ve.bundles[name] {
+		data, err := fs.ReadFile(fsys, "public"+it)
+		if err != nil {
+			app.logger.Error("xun: build bundle", slog.String("bundle", name), slog.Any("err", err))
+			continue
+		}
+
+		buf.Write(data)
+		// a script that doesn't end with a semicolon or a line break is still a statement
+		if js {
+			buf.WriteString(";\n")
+		} else {
+			buf.WriteString("\n")
+		}
+	}
+
+	content := minifyAsset(name, buf.Bytes())
+	pattern := name[1:]
+
+	// the content on the name is changed by a rebuild, so the viewer is replaced
+	if v, ok := app.viewers[pattern]; ok {
+		_, _, pat := splitFile(pattern)
+		app.removeViewer(pat, pattern, v)
+	}
+
+	app.HandleFile(pattern, newContentViewer(pattern, content, ComputeETag(bytes.NewReader(content)), ""))
+
+	ve.handleBuiltAsset(app, pattern, content)
+}
+
+// getAssetTag returns the <link> element of a css file, or the <script> element of any other
+// file, with its fingerprinted url and its Subresource Integrity hash if the asset pipeline
+// computed one:
+//
+//	{{ assetTag "/assets/app.js" }}
+//	<script src="/assets/app-1a2b3c4d.js" integrity="sha384-..." crossorigin="anonymous"></script>
+func (app *App) getAssetTag(pattern string) template.HTML {
+	// lock-free, because AssetURLs and AssetIntegrity are initialized in New() in production
+	unlock := app.rlock()
+	src, ok := app.AssetURLs[pattern]
+	sri := app.AssetIntegrity[pattern]
+	unlock()
+
+	if !ok {
+		src = pattern
+	}
+
+	attrs := ""
+	if sri != "" {
+		attrs = ` integrity="` + sri + `" crossorigin="anonymous"`
+	}
+
+	src = template.HTMLEscapeString(src)
+
+	switch strings.ToLower(path.Ext(pattern)) {
+	case ".css":
+		return template.HTML(`<link rel="stylesheet" href="` + src + `"` + attrs + `>`) // nolint: gosec
+	case ".mjs":
+		return template.HTML(`<script type="module" src="` + src + `"` + attrs + `></script>`) // nolint: gosec
+	default:
+		return template.HTML(`<script src="` + src + `"` + attrs + `></script>`) // nolint: gosec
+	}
+}
@@ -0,0 +1,266 @@
+package xun
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// minifyAsset returns the minified content of a css or js file, and the content of any other
+// file as it is.
+func minifyAsset(name string, buf []byte) []byte {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".css":
+		return minifyCSS(buf)
+	case ".js", ".mjs":
+		return minifyJS(buf)
+	default:
+		return buf
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// lastByte returns the last byte of the output, or 0 if it is empty.
+func lastByte(out *bytes.Buffer) byte {
+	if out.Len() == 0 {
+		return 0
+	}
+	return out.Bytes()[out.Len()-1]
+}
+
+// copyQuoted copies the string that starts at buf[i] to out, and returns the index after it.
+// A string ends at its quote that isn't escaped, or at a line break if it isn't closed.
+func copyQuoted(out *bytes.Buffer, buf []byte, i int) int {
+	quote := buf[i]
+	j := i + 1
+	for j < len(buf) {
+		c := buf[j]
+		if c == '\\' {
+			j += 2
+			continue
+		}
+		j++
+		if c == quote || (c == '\n' && quote != '`') {
+			break
+		}
+	}
+	j = min(j, len(buf))
+	out.Write(buf[i:j])
+	return j
+}
+
+// copyComment copies a /*! ... */ comment, which keeps a license by convention, and skips
+// the others. It returns the index after the comment and whether it has a line break.
+func copyComment(out *bytes.Buffer, buf []byte, i int) (int, bool) {
+	end := bytes.Index(buf[i+2:], []byte("*/"))
+	if end < 0 {
+		end = len(buf)
+	} else {
+		end += i + 4
+	}
+
+	if i+2 < len(buf) && buf[i+2] == '!' {
+		out.Write(buf[i:end])
+	}
+
+	return end, bytes.IndexByte(buf[i:end], '\n') >= 0
+}
+
+// minifyCSS removes the comments and the whitespace that doesn't change the meaning of a
+// stylesheet. The strings are kept as they are, and so is a space before a colon, because
+// "a :hover" and "a:hover" are different selectors. A slash is a delimiter, e.g. in
+// "grid-area: 1 / 2", so the space after it is removed.
+func minifyCSS(buf []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(buf))
+
+	const tight = "{};,>"
+
+	space := false
+	for i := 0; i < len(buf); {
+		c := buf[i]
+
+		switch {
+		case c == '/' && i+1 < len(buf) && buf[i+1] == '*':
+			i, _ = copyComment(&out, buf, i)
+			space = space || (out.Len() > 0 && !isSpace(lastByte(&out)))
+			continue
+		case isSpace(c):
+			space = out.Len() > 0
+			i++
+			continue
+		}
+
+		if space {
+			if prev := lastByte(&out); !strings.ContainsRune(tight+":/", rune(prev)) && !strings.ContainsRune(tight, rune(c)) {
+				out.WriteByte(' ')
+			}
+			space = false
+		}
+
+		// the last declaration of a block doesn't need its semicolon
+		if c == '}' && lastByte(&out) == ';' {
+			out.Truncate(out.Len() - 1)
+		}
+
+		if c == '"' || c == '\'' {
+			i = copyQuoted(&out, buf, i)
+			continue
+		}
+
+		out.WriteByte(c)
+		i++
+	}
+
+	return out.Bytes()
+}
+
+// regexKeywords are the keywords after which a slash starts a regular expression.
+var regexKeywords = []string{"return", "typeof", "instanceof", "in", "of", "new", "delete", "void", "throw", "case", "do", "else", "yield", "await"}
+
+// regexAllowed returns true if a slash after the output starts a regular expression rather
+// than a division.
+func regexAllowed(out *bytes.Buffer) bool {
+	prev := lastByte(out)
+	switch {
+	case prev == 0:
+		return true
+	case prev == ')' || prev == ']':
+		return false
+	case isIdent(prev):
+		b := out.Bytes()
+		i := len(b)
+		for i > 0 && isIdent(b[i-1]) {
+			i--
+		}
+		word := string(b[i:])
+		for _, it := range regexKeywords {
+			if word == it {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// copyRegex copies the regular expression that starts at buf[i] with its flags, and returns
+// the index after it.
+func copyRegex(out *bytes.Buffer, buf []byte, i int) int {
+	j := i + 1
+	class := false
+	for j < len(buf) {
+		c := buf[j]
+		if c == '\\' {
+			j += 2
+			continue
+		}
+		if c == '\n' {
+			break
+		}
+		j++
+		if c == '[' {
+			class = true
+		} else if c == ']' {
+			class = false
+		} else if c == '/' && !class {
+			for j < len(buf) && isIdent(buf[j]) {
+				j++
+			}
+			break
+		}
+	}
+	j = min(j, len(buf))
+	out.Write(buf[i:j])
+	return j
+}
+
+// copyLine copies the rest of the line that starts at buf[i] as it is, with the strings that
+// go on to the next lines, and returns the index of its line break.
+func copyLine(out *bytes.Buffer, buf []byte, i int) int {
+	for i < len(buf) && buf[i] != '\n' {
+		if c := buf[i]; c == '"' || c == '\'' || c == '`' {
+			i = copyQuoted(out, buf, i)
+			continue
+		}
+		out.WriteByte(buf[i])
+		i++
+	}
+	return i
+}
+
+// minifyJS removes the comments and the whitespace that doesn't change the meaning of a
+// script. It keeps a line break where one was, unless the previous character makes it
+// redundant, so automatic semicolon insertion works as before. The strings, the template
+// literals and the regular expressions are kept as they are. A slash after ) or } can be a
+// division, e.g. (a + b) / 2, or a regular expression, e.g. if (a) /b/.test(s), so the rest
+// of its line is kept as it is too.
+func minifyJS(buf []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(buf))
+
+	space, newline := false, false
+	for i := 0; i < len(buf); {
+		c := buf[i]
+
+		if c == '/' && i+1 < len(buf) && buf[i+1] == '*' {
+			var nl bool
+			i, nl = copyComment(&out, buf, i)
+			space, newline = true, newline || nl
+			continue
+		}
+
+		if c == '/' && i+1 < len(buf) && buf[i+1] == '/' {
+			end := bytes.IndexByte(buf[i:], '\n')
+			if end < 0 {
+				i = len(buf)
+			} else {
+				i += end
+			}
+			continue
+		}
+
+		if isSpace(c) {
+			space = true
+			newline = newline || c == '\n'
+			i++
+			continue
+		}
+
+		if space && out.Len() > 0 {
+			prev := lastByte(&out)
+			switch {
+			case newline && !strings.ContainsRune("{;,([", rune(prev)) && !strings.ContainsRune("})];", rune(c)):
+				out.WriteByte('\n')
+			case isIdent(prev) && isIdent(c), (prev == '+' || prev == '-') && prev == c, prev == '/' && c == '/',
+				prev >= '0' && prev <= '9' && c == '.':
+				// a b, a + +b, a / /b/ and 1 .toString() keep their space
+				out.WriteByte(' ')
+			}
+		}
+		space, newline = false, false
+
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			i = copyQuoted(&out, buf, i)
+		case c == '/' && (lastByte(&out) == ')' || lastByte(&out) == '}'):
+			i = copyLine(&out, buf, i)
+		case c == '/' && regexAllowed(&out):
+			i = copyRegex(&out, buf, i)
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
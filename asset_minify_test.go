@@ -0,0 +1,112 @@
+package xun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		name     string
+		css      string
+		expected string
+	}{
+		{
+			name:     "whitespace",
+			css:      "body {\n  color: red;\n  margin: 0 auto;\n}\n\nh1,\nh2 > a {\n  font-weight: bold;\n}\n",
+			expected: "body{color:red;margin:0 auto}h1,h2>a{font-weight:bold}",
+		},
+		{
+			name:     "comments",
+			css:      "/* reset */\np { margin: 0 } /* end */",
+			expected: "p{margin:0}",
+		},
+		{
+			name:     "license",
+			css:      "/*! MIT */\np { margin: 0 }",
+			expected: "/*! MIT */p{margin:0}",
+		},
+		{
+			name:     "strings",
+			css:      `a::before { content: "a  /* b */  c"; font-family: 'Helvetica Neue', sans-serif }`,
+			expected: `a::before{content:"a  /* b */  c";font-family:'Helvetica Neue',sans-serif}`,
+		},
+		{
+			name:     "descendant_pseudo",
+			css:      "nav :hover { color: red } a:hover { color: blue }",
+			expected: "nav :hover{color:red}a:hover{color:blue}",
+		},
+		{
+			name:     "media_and_calc",
+			css:      "@media screen and (max-width: 600px) {\n  .col { width: calc(100% - 2rem) !important; }\n}",
+			expected: "@media screen and (max-width:600px){.col{width:calc(100% - 2rem) !important}}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, string(minifyCSS([]byte(test.css))))
+		})
+	}
+}
+
+func TestMinifyJS(t *testing.T) {
+	tests := []struct {
+		name     string
+		js       string
+		expected string
+	}{
+		{
+			name:     "whitespace",
+			js:       "function add(a, b) {\n    return a + b;\n}\n\nconst x = add(1, 2)\nconsole.log(x)\n",
+			expected: "function add(a,b){return a+b;}\nconst x=add(1,2)\nconsole.log(x)",
+		},
+		{
+			name:     "comments",
+			js:       "// line\nlet a = 1; /* block */ let b = 2;\n/*\n * doc\n */\nlet c = 3",
+			expected: "let a=1;let b=2;let c=3",
+		},
+		{
+			name:     "license",
+			js:       "/*! MIT */\nlet a = 1",
+			expected: "/*! MIT */\nlet a=1",
+		},
+		{
+			name:     "asi",
+			js:       "let a = b\n++c\nreturn\nx",
+			expected: "let a=b\n++c\nreturn\nx",
+		},
+		{
+			name:     "operators",
+			js:       "a = b + +c - -d; e = 1 .toString()",
+			expected: "a=b+ +c- -d;e=1 .toString()",
+		},
+		{
+			name:     "strings",
+			js:       "const s = \"// not a comment\" + 'it\\'s /* */' + `line\n  ${ a // b\n}`",
+			expected: "const s=\"// not a comment\"+'it\\'s /* */'+`line\n  ${ a // b\n}`",
+		},
+		{
+			name:     "regex",
+			js:       "const re = /\\/\\/ [/*] x/g; if (re.test(s)) return /a b/.source\nx = a / b / c",
+			expected: "const re=/\\/\\/ [/*] x/g;if(re.test(s))return/a b/.source\nx=a/b/c",
+		},
+		{
+			name:     "division_and_regex",
+			js:       "x = a / /re/.exec(s).length",
+			expected: "x=a/ /re/.exec(s).length",
+		},
+		{
+			name:     "ambiguous_slash",
+			js:       "if(a) /\\/\\//.test(s) && f( b ) // c\nx = (a + b) / 2  /* half */\ny = {} / 1",
+			expected: "if(a)/\\/\\//.test(s) && f( b ) // c\nx=(a+b)/ 2  /* half */\ny={}/ 1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, string(minifyJS([]byte(test.js))))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package xun
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestAssetPipeline(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public/css/site.css":   {Data: []byte("body {\n  color: red;\n}\n")},
+		"public/js/a.js":        {Data: []byte("// a\nvar a = 1\n")},
+		"public/js/b.js":        {Data: []byte("var b = a + 1 /* b */\n")},
+		"public/css/reset.css":  {Data: []byte("* { margin: 0; }")},
+		"public/logo.svg":       {Data: []byte(`<svg>  </svg>`)},
+		"bundles.json":          {Data: []byte(`{"/assets/app.js": ["/js/a.js", "js/b.js"], "assets/app.css": ["/css/reset.css", "/css/site.css", "/css/missing.css"]}`)},
+		"pages/index.html":      {Data: []byte(`{{ assetTag "/assets/app.css" }}{{ assetTag "/assets/app.js" }}{{ assetTag "/css/site.css" }}{{ assetTag "/js/none.mjs" }}`)},
+		"pages/plain.html":      {Data: []byte(`<img src="{{ asset "/logo.svg" }}">`)},
+		"public/js/not-site.js": {Data: []byte("let  x = 1")},
+	}
+
+	mux := http.NewServeMux()
+	app := New(WithMux(mux), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))), WithAssetPipeline(),
+		WithBuildAssetURL(func(s string) bool { return strings.HasPrefix(s, "/css/") || s == "/logo.svg" }))
+	defer app.Close()
+
+	sri := func(s string) string {
+		sum := sha512.Sum384([]byte(s))
+		return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	t.Run("minify", func(t *testing.T) {
+		css := app.AssetURLs["/css/site.css"]
+		require.Regexp(t, `^/css/site-[0-9a-f]+\.css$`, css)
+
+		rw := serve(mux, http.MethodGet, css)
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "body{color:red}", rw.Body.String())
+		require.Equal(t, cacheControl, rw.Header().Get("Cache-Control"))
+		require.Equal(t, "text/css; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Equal(t, sri(rw.Body.String()), app.AssetIntegrity["/css/site.css"])
+
+		// the file keeps its content on its own url
+		rw = serve(mux, http.MethodGet, "/css/site.css")
+		require.Equal(t, "body {\n  color: red;\n}\n", rw.Body.String())
+
+		// the other files are fingerprinted as they are
+		rw = serve(mux, http.MethodGet, app.AssetURLs["/logo.svg"])
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, `<svg>  </svg>`, rw.Body.String())
+		require.Equal(t, sri(rw.Body.String()), app.AssetIntegrity["/logo.svg"])
+
+		_, ok := app.AssetURLs["/js/not-site.js"]
+		require.False(t, ok)
+	})
+
+	t.Run("bundles", func(t *testing.T) {
+		js := app.AssetURLs["/assets/app.js"]
+		require.Regexp(t, `^/assets/app-[0-9a-f]+\.js$`, js)
+
+		rw := serve(mux, http.MethodGet, js)
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "var a=1;var b=a+1;", rw.Body.String())
+		require.Equal(t, cacheControl, rw.Header().Get("Cache-Control"))
+		require.Equal(t, sri(rw.Body.String()), app.AssetIntegrity["/assets/app.js"])
+
+		// the bundle is served on its name too, but it isn't immutable there
+		rw = serve(mux, http.MethodGet, "/assets/app.js")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "var a=1;var b=a+1;", rw.Body.String())
+		require.Empty(t, rw.Header().Get("Cache-Control"))
+		require.NotEmpty(t, rw.Header().Get("ETag"))
+
+		// a missing file is left out
+		rw = serve(mux, http.MethodGet, app.AssetURLs["/assets/app.css"])
+		require.Equal(t, "*{margin:0}body{color:red}", rw.Body.String())
+	})
+
+	t.Run("asset_tag", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "text/html")
+		mux.ServeHTTP(rw, req)
+
+		require.Equal(t, `<link rel="stylesheet" href="`+app.AssetURLs["/assets/app.css"]+`" integrity="`+app.AssetIntegrity["/assets/app.css"]+`" crossorigin="anonymous">`+
+			`<script src="`+app.AssetURLs["/assets/app.js"]+`" integrity="`+app.AssetIntegrity["/assets/app.js"]+`" crossorigin="anonymous"></script>`+
+			`<link rel="stylesheet" href="`+app.AssetURLs["/css/site.css"]+`" integrity="`+app.AssetIntegrity["/css/site.css"]+`" crossorigin="anonymous">`+
+			`<script type="module" src="/js/none.mjs"></script>`, rw.Body.String())
+	})
+
+	t.Run("without_pipeline", func(t *testing.T) {
+		app := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithBuildAssetURL(func(s string) bool { return strings.HasPrefix(s, "/css/") }))
+		defer app.Close()
+
+		require.Empty(t, app.AssetIntegrity)
+		_, ok := app.AssetURLs["/assets/app.js"]
+		require.False(t, ok)
+
+		require.Equal(t, `<link rel="stylesheet" href="`+app.AssetURLs["/css/site.css"]+`">`, string(app.getAssetTag("/css/site.css")))
+		require.Equal(t, `<script src="/js/a.js"></script>`, string(app.getAssetTag("/js/a.js")))
+	})
+}
+
+func TestAssetPipelineOnWatch(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public/js/a.js":   {Data: []byte("var a = 1\n")},
+		"public/js/b.js":   {Data: []byte("var b = 2\n")},
+		"public/css/x.css": {Data: []byte("p { margin: 0 }")},
+		"bundles.json":     {Data: []byte(`{"/assets/app.js": ["/js/a.js", "/js/b.js"]}`)},
+	}
+
+	mux := http.NewServeMux()
+	app := New(WithMux(mux), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))), WithAssetPipeline())
+	defer app.Close()
+
+	old := app.AssetURLs["/assets/app.js"]
+
+	t.Run("file", func(t *testing.T) {
+		fsys["public/js/b.js"] = &fstest.MapFile{Data: []byte("var b = 3\n")}
+		app.fileChanged(fsnotify.Event{Name: "public/js/b.js", Op: fsnotify.Write})
+
+		js := app.AssetURLs["/assets/app.js"]
+		require.NotEqual(t, old, js)
+
+		rw := serve(mux, http.MethodGet, js)
+		require.Equal(t, "var a=1;var b=3;", rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/assets/app.js")
+		require.Equal(t, "var a=1;var b=3;", rw.Body.String())
+
+		// the old url keeps its content for the pages that are cached
+		rw = serve(mux, http.MethodGet, old)
+		require.Equal(t, "var a=1;var b=2;", rw.Body.String())
+
+		delete(fsys, "public/js/a.js")
+		app.fileChanged(fsnotify.Event{Name: "public/js/a.js", Op: fsnotify.Remove})
+
+		rw = serve(mux, http.MethodGet, app.AssetURLs["/assets/app.js"])
+		require.Equal(t, "var b=3;", rw.Body.String())
+	})
+
+	t.Run("bundles", func(t *testing.T) {
+		fsys["bundles.json"] = &fstest.MapFile{Data: []byte(`{"/assets/site.css": ["/css/x.css"]}`)}
+		app.fileChanged(fsnotify.Event{Name: "bundles.json", Op: fsnotify.Write})
+
+		_, ok := app.AssetURLs["/assets/app.js"]
+		require.False(t, ok)
+		_, ok = app.AssetIntegrity["/assets/app.js"]
+		require.False(t, ok)
+
+		rw := serve(mux, http.MethodGet, "/assets/app.js")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		rw = serve(mux, http.MethodGet, app.AssetURLs["/assets/site.css"])
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "p{margin:0}", rw.Body.String())
+
+		// a bundle is built again when it is declared again
+		fsys["bundles.json"] = &fstest.MapFile{Data: []byte(`{"/assets/app.js": ["/js/b.js"]}`)}
+		app.fileChanged(fsnotify.Event{Name: "bundles.json", Op: fsnotify.Write})
+
+		rw = serve(mux, http.MethodGet, "/assets/app.js")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "var b=3;", rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/assets/site.css")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		delete(fsys, "bundles.json")
+		app.fileChanged(fsnotify.Event{Name: "bundles.json", Op: fsnotify.Remove})
+		require.Empty(t, app.AssetURLs)
+		require.Empty(t, app.AssetIntegrity)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var logs strings.Builder
+		app.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+		fsys["bundles.json"] = &fstest.MapFile{Data: []byte(`{"/assets/app.js": `)}
+		app.fileChanged(fsnotify.Event{Name: "bundles.json", Op: fsnotify.Write})
+
+		require.Contains(t, logs.String(), `msg="xun: load bundles"`)
+		require.Empty(t, app.AssetURLs)
+	})
+}
@@ -0,0 +1,162 @@
+// Command xun is the command line tool of xun applications.
+//
+// Usage:
+//
+//	xun check [-funcs name,...] [dir]
+//	xun export [-o out] [-urls path,...] [-assets prefix,...] [dir]
+//
+// check parses every template of the application in dir, which is the current directory by
+// default, prints the problems as "file:line: message", and exits with status 1 if there is
+// any, so CI can keep a broken template from shipping. The functions that the application
+// adds with xun.WithTemplateFunc are unknown to the tool, list them with -funcs.
+//
+// export renders the pages and the public files of the application in dir to the out
+// directory, "dist" by default, as a static site. The pages that no other page links to, e.g.
+// the pages of a wildcard route, are listed with -urls, and the public files whose URL starts
+// with one of the -assets prefixes, e.g. /css/, are fingerprinted.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yaitoo/xun"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	switch args[0] {
+	case "check":
+		return check(args[1:], stdout, stderr)
+	case "export":
+		return export(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "xun: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: xun check [-funcs name,...] [dir]")
+	fmt.Fprintln(w, "       xun export [-o out] [-urls path,...] [-assets prefix,...] [dir]")
+}
+
+// split returns the items of a comma-separated list.
+func split(list string) []string {
+	var items []string
+	for _, it := range strings.Split(list, ",") {
+		if it = strings.TrimSpace(it); it != "" {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// appDir returns the directory of the application, which is the current directory by default.
+func appDir(flags *flag.FlagSet, stderr io.Writer) (string, bool) {
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		fmt.Fprintf(stderr, "xun: %s is not a directory\n", dir)
+		return "", false
+	}
+
+	return dir, true
+}
+
+func check(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	funcs := flags.String("funcs", "", "comma-separated names of the template functions that the app adds")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	dir, ok := appDir(flags, stderr)
+	if !ok {
+		return 2
+	}
+
+	// the load errors are reported by CheckTemplates with their lines
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	opts := []xun.Option{
+		xun.WithMux(http.NewServeMux()),
+		xun.WithFsys(os.DirFS(dir)),
+		xun.WithLogger(logger),
+	}
+
+	for _, name := range split(*funcs) {
+		opts = append(opts, xun.WithTemplateFunc(name, func(...any) any { return nil }))
+	}
+
+	problems := xun.New(opts...).CheckTemplates()
+	for _, p := range problems {
+		fmt.Fprintln(stdout, p)
+	}
+
+	if len(problems) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func export(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("o", "dist", "the directory that the site is written to")
+	urls := flags.String("urls", "", "comma-separated paths to export besides the linked pages, e.g. /posts/hello")
+	assets := flags.String("assets", "", "comma-separated URL prefixes of the public files to fingerprint, e.g. /css/,/js/")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	dir, ok := appDir(flags, stderr)
+	if !ok {
+		return 2
+	}
+
+	prefixes := split(*assets)
+
+	app := xun.New(
+		xun.WithMux(http.NewServeMux()),
+		xun.WithFsys(os.DirFS(dir)),
+		xun.WithLogger(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))),
+		xun.WithBuildAssetURL(func(s string) bool {
+			return slices.ContainsFunc(prefixes, func(p string) bool { return strings.HasPrefix(s, p) })
+		}),
+	)
+	defer app.Close()
+
+	files, err := app.Export(*out, xun.WithExportURLs(split(*urls)...))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	for _, it := range files {
+		fmt.Fprintln(stdout, filepath.Join(*out, it))
+	}
+
+	return 0
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pages"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages", "index.html"), []byte("<p>\n{{greet .Data}}</p>"), 0600))
+
+	var stdout, stderr bytes.Buffer
+
+	t.Run("problems", func(t *testing.T) {
+		logger := slog.Default()
+
+		stdout.Reset()
+		require.Equal(t, 1, run([]string{"check", dir}, &stdout, &stderr))
+		require.Equal(t, "pages/index.html:2: function \"greet\" is not defined\n", stdout.String())
+
+		// the default logger of the process is left as it is
+		require.Same(t, logger, slog.Default())
+	})
+
+	t.Run("funcs", func(t *testing.T) {
+		stdout.Reset()
+		require.Equal(t, 0, run([]string{"check", "-funcs", "greet, other", dir}, &stdout, &stderr))
+		require.Empty(t, stdout.String())
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		stderr.Reset()
+		require.Equal(t, 2, run(nil, &stdout, &stderr))
+		require.Contains(t, stderr.String(), "usage: xun check")
+
+		stderr.Reset()
+		require.Equal(t, 2, run([]string{"lint"}, &stdout, &stderr))
+		require.Contains(t, stderr.String(), `unknown command "lint"`)
+
+		stderr.Reset()
+		require.Equal(t, 2, run([]string{"check", filepath.Join(dir, "missing")}, &stdout, &stderr))
+		require.Contains(t, stderr.String(), "is not a directory")
+	})
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pages", "posts"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "public", "css"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages", "index.html"), []byte(`<link href="{{ asset "/css/site.css" }}"><a href="/posts/hello">hello</a>`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages", "posts", "hello.html"), []byte(`<p>hello</p>`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages", "posts", "draft.html"), []byte(`<p>draft</p>`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "public", "css", "site.css"), []byte(`p{}`), 0600))
+
+	var stdout, stderr bytes.Buffer
+
+	t.Run("export", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "dist")
+
+		stdout.Reset()
+		require.Equal(t, 0, run([]string{"export", "-o", out, "-assets", "/css/", dir}, &stdout, &stderr))
+
+		files := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		require.Len(t, files, 5)
+		require.Contains(t, files, filepath.Join(out, "index.html"))
+		require.Contains(t, files, filepath.Join(out, "posts", "hello", "index.html"))
+		require.Contains(t, files, filepath.Join(out, "posts", "draft", "index.html"))
+		require.Contains(t, files, filepath.Join(out, "css", "site.css"))
+
+		buf, err := os.ReadFile(filepath.Join(out, "index.html"))
+		require.NoError(t, err)
+		require.Regexp(t, `<link href="/css/site-[0-9a-f]+\.css">`, string(buf))
+	})
+
+	t.Run("urls", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "dist")
+
+		stderr.Reset()
+		require.Equal(t, 1, run([]string{"export", "-o", out, "-urls", "/posts/missing", dir}, &stdout, &stderr))
+		require.Contains(t, stderr.String(), "xun: export: /posts/missing: 404 Not Found")
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		stderr.Reset()
+		require.Equal(t, 2, run([]string{"export", filepath.Join(dir, "missing")}, &stdout, &stderr))
+		require.Contains(t, stderr.String(), "is not a directory")
+
+		stderr.Reset()
+		require.Equal(t, 2, run([]string{"export", "-x"}, &stdout, &stderr))
+		require.Contains(t, stderr.String(), "flag provided but not defined: -x")
+	})
+}
@@ -0,0 +1,473 @@
+package xun
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template/parse"
+)
+
+// ComponentData is the data of a component that the component function renders, e.g.
+// components/button.html:
+//
+//	<!--props: href:url! variant:string=primary-->
+//	<a class="btn btn-{{ .Props.variant }}" href="{{ .Props.href }}">{{ .Slot }}</a>
+type ComponentData struct {
+	Props map[string]any
+	Slots map[string]template.HTML
+}
+
+// Slot returns the rendered content of the named slot, or of the default slot without a name.
+func (d ComponentData) Slot(name ...string) template.HTML {
+	if len(name) > 0 {
+		return d.Slots[name[0]]
+	}
+	return d.Slots[""]
+}
+
+// componentSlot is the content of a slot that the slot function renders for a component call.
+type componentSlot struct {
+	name string
+	html template.HTML
+}
+
+// componentProp is a prop that a component declares in <!--props: name:type! name:type=default-->.
+type componentProp struct {
+	name     string
+	kind     string
+	required bool
+	def      any
+}
+
+// componentFuncs returns the component and slot functions of the template set:
+//
+//	{{ define "card-body" }}<p>{{ .Data.Text }}</p>{{ end }}
+//	{{ component "components/card" "title" .Data.Title (slot "card-body" .) (slot "footer" "card-footer" .) }}
+//
+// component renders the component with the key and value pairs as its props, and slot renders a
+// template of the calling set with the given data into the default slot, or the named slot.
+func componentFuncs(nt *template.Template, templates map[string]*HtmlTemplate, reload *sync.RWMutex) template.FuncMap {
+	return template.FuncMap{
+		"component": func(name string, args ...any) (template.HTML, error) {
+			unlock := rlock(reload)
+			c, ok := templates[name]
+			unlock()
+			if !ok {
+				return "", fmt.Errorf("%w: %q", ErrComponentNotFound, name)
+			}
+
+			data := ComponentData{Props: make(map[string]any), Slots: make(map[string]template.HTML)}
+			for i := 0; i < len(args); i++ {
+				if s, ok := args[i].(componentSlot); ok {
+					data.Slots[s.name] = s.html
+					continue
+				}
+
+				key, ok := args[i].(string)
+				if !ok || i+1 == len(args) {
+					return "", fmt.Errorf("%w: %s: props are key and value pairs", ErrInvalidProps, name)
+				}
+
+				data.Props[key] = args[i+1]
+				i++
+			}
+
+			if err := c.bindProps(data.Props); err != nil {
+				return "", err
+			}
+
+			buf := BufPool.Get()
+			defer BufPool.Put(buf)
+
+			if err := c.Execute(buf, data); err != nil {
+				return "", err
+			}
+			return template.HTML(buf.String()), nil // nolint: gosec
+		},
+		"slot": func(args ...any) (componentSlot, error) {
+			var s componentSlot
+			var tn string
+			var ok bool
+			switch len(args) {
+			case 2:
+				tn, ok = args[0].(string)
+			case 3:
+				if s.name, ok = args[0].(string); ok {
+					tn, ok = args[1].(string)
+				}
+			}
+
+			if !ok {
+				return s, errors.New("slot: a template name and its data, or a slot name, a template name and its data are required")
+			}
+
+			buf := BufPool.Get()
+			defer BufPool.Put(buf)
+
+			if err := nt.ExecuteTemplate(buf, tn, args[len(args)-1]); err != nil {
+				return s, err
+			}
+
+			s.html = template.HTML(buf.String()) // nolint: gosec
+			return s, nil
+		},
+	}
+}
+
+// propsOf parses the <!--props: ...--> declaration at the top of a component.
+func propsOf(buf []byte) ([]componentProp, error) {
+	s := strings.TrimLeft(string(buf), " \t\r\n")
+	if !strings.HasPrefix(s, "<!--props:") {
+		return nil, nil
+	}
+
+	end := strings.Index(s, "-->")
+	if end < 0 {
+		return nil, fmt.Errorf("%w: <!--props: is not closed", ErrInvalidProps)
+	}
+
+	props := []componentProp{}
+	for _, field := range splitProps(s[10:end]) {
+		p := componentProp{kind: "any"}
+
+		decl, def, hasDef := strings.Cut(field, "=")
+		if p.required = strings.HasSuffix(decl, "!"); p.required {
+			decl = decl[:len(decl)-1]
+		}
+
+		p.name, p.kind, _ = strings.Cut(decl, ":")
+		if p.kind == "" {
+			p.kind = "any"
+		}
+
+		if !propKinds[p.kind] {
+			return nil, fmt.Errorf("%w: %s: unknown type %q", ErrInvalidProps, p.name, p.kind)
+		}
+
+		if hasDef {
+			v, err := parsePropDefault(p.kind, def)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidProps, p.name, err)
+			}
+			p.def = v
+		}
+
+		props = append(props, p)
+	}
+
+	return props, nil
+}
+
+// hideProps replaces the <!--props: ...--> declaration with a template comment that trims the
+// white space after it, so the component doesn't render an empty line. The lines of the
+// declaration are kept, so the errors of the template have the lines of the file.
+func hideProps(buf []byte) []byte {
+	s := string(buf)
+	start := strings.Index(s, "<!--props:")
+	end := strings.Index(s, "-->")
+	if start < 0 || end < start || strings.TrimSpace(s[:start]) != "" {
+		return buf
+	}
+
+	return []byte("{{/*" + s[start+4:end] + "*/ -}}" + s[end+3:])
+}
+
+// splitProps splits the declarations by spaces, except in a quoted default value.
+func splitProps(s string) []string {
+	var fields []string
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(s):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(s[i])
+		case c == '"':
+			quoted = !quoted
+			b.WriteByte(c)
+		case !quoted && (c == ' ' || c == '\t' || c == '\r' || c == '\n'):
+			if b.Len() > 0 {
+				fields = append(fields, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if b.Len() > 0 {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+var propKinds = map[string]bool{"any": true, "string": true, "int": true, "float": true, "bool": true, "url": true, "html": true}
+
+func parsePropDefault(kind, s string) (any, error) {
+	if strings.HasPrefix(s, `"`) {
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, err
+		}
+		s = v
+	}
+
+	switch kind {
+	case "int":
+		return strconv.Atoi(s)
+	case "float":
+		return strconv.ParseFloat(s, 64)
+	case "bool":
+		return strconv.ParseBool(s)
+	default:
+		return s, nil
+	}
+}
+
+// bindProps checks the props of a call against the declaration of the component, and sets the
+// default values of the props that the call doesn't pass. A component without a declaration
+// takes any props.
+func (t *HtmlTemplate) bindProps(props map[string]any) error {
+	declared := t.current().props
+	if declared == nil {
+		return nil
+	}
+
+	for name := range props {
+		if prop(declared, name) == nil {
+			return fmt.Errorf("%w: %s: unknown prop %q", ErrInvalidProps, t.name, name)
+		}
+	}
+
+	for _, p := range declared {
+		v, ok := props[p.name]
+		if !ok {
+			if p.required {
+				return fmt.Errorf("%w: %s: missing required prop %q", ErrInvalidProps, t.name, p.name)
+			}
+			props[p.name] = p.def
+			continue
+		}
+
+		if !p.accepts(v) {
+			return fmt.Errorf("%w: %s: prop %q is %T, not %s", ErrInvalidProps, t.name, p.name, v, p.kind)
+		}
+	}
+
+	return nil
+}
+
+func prop(props []componentProp, name string) *componentProp {
+	for i := range props {
+		if props[i].name == name {
+			return &props[i]
+		}
+	}
+	return nil
+}
+
+// accepts reports whether the value has the type of the prop.
+func (p *componentProp) accepts(v any) bool {
+	switch p.kind {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "int":
+		_, ok := toInt(v)
+		return ok
+	case "float":
+		_, ok := toFloat(v)
+		return ok
+	case "bool":
+		_, ok := v.(bool)
+		return ok
+	case "url":
+		switch v.(type) {
+		case string, template.URL:
+			return true
+		}
+		return false
+	case "html":
+		switch v.(type) {
+		case string, template.HTML:
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// acceptsNode reports whether a literal argument has the type of the prop. Other arguments are
+// checked when they are rendered.
+func (p *componentProp) acceptsNode(n parse.Node) bool {
+	switch n := n.(type) {
+	case *parse.StringNode:
+		return p.kind == "any" || p.kind == "string" || p.kind == "url" || p.kind == "html"
+	case *parse.NumberNode:
+		return p.kind == "any" || p.kind == "float" || (p.kind == "int" && n.IsInt)
+	case *parse.BoolNode:
+		return p.kind == "any" || p.kind == "bool"
+	default:
+		return true
+	}
+}
+
+// componentCall is a {{ component "name" ... }} call with a literal name.
+type componentCall struct {
+	tree *parse.Tree
+	node *parse.CommandNode
+	name string
+}
+
+// componentCalls returns the component calls of the tree.
+func componentCalls(tree *parse.Tree) []componentCall {
+	var calls []componentCall
+	walkCommands(tree.Root, func(cmd *parse.CommandNode) {
+		if funcName(cmd) != "component" || len(cmd.Args) < 2 {
+			return
+		}
+
+		if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+			calls = append(calls, componentCall{tree: tree, node: cmd, name: s.Text})
+		}
+	})
+	return calls
+}
+
+// slotTemplates calls fn with the literal template names of the slot calls of the tree.
+func slotTemplates(tree *parse.Tree, fn func(cmd *parse.CommandNode, name string)) {
+	walkCommands(tree.Root, func(cmd *parse.CommandNode) {
+		if funcName(cmd) != "slot" || (len(cmd.Args) != 3 && len(cmd.Args) != 4) {
+			return
+		}
+
+		if s, ok := cmd.Args[len(cmd.Args)-2].(*parse.StringNode); ok {
+			fn(cmd, s.Text)
+		}
+	})
+}
+
+func funcName(cmd *parse.CommandNode) string {
+	if len(cmd.Args) == 0 {
+		return ""
+	}
+
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		return id.Ident
+	}
+	return ""
+}
+
+// walkCommands calls fn for the commands under n, including the commands in the parenthesized
+// pipelines of their arguments.
+func walkCommands(n parse.Node, fn func(*parse.CommandNode)) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, it := range n.Nodes {
+			walkCommands(it, fn)
+		}
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, it := range n.Cmds {
+			walkCommands(it, fn)
+		}
+	case *parse.CommandNode:
+		fn(n)
+		for _, it := range n.Args {
+			walkCommands(it, fn)
+		}
+	case *parse.ActionNode:
+		walkCommands(n.Pipe, fn)
+	case *parse.ChainNode:
+		walkCommands(n.Node, fn)
+	case *parse.IfNode:
+		walkCommandBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkCommandBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkCommandBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkCommands(n.Pipe, fn)
+	}
+}
+
+func walkCommandBranch(n *parse.BranchNode, fn func(*parse.CommandNode)) {
+	walkCommands(n.Pipe, fn)
+	walkCommands(n.List, fn)
+	walkCommands(n.ElseList, fn)
+}
+
+// checkComponentCalls checks the literal props of the component calls in the template against
+// the declarations of the components that are loaded, and returns the first problem as a
+// "template: file:line: ..." error, like the errors of the template parser.
+func (t *HtmlTemplate) checkComponentCalls(trees []*parse.Tree, templates map[string]*HtmlTemplate) error {
+	for _, tree := range trees {
+		for _, call := range componentCalls(tree) {
+			c, ok := templates[call.name]
+			if !ok {
+				continue
+			}
+
+			c.dependents[t.name] = t
+
+			if c.current().props == nil {
+				continue
+			}
+
+			if err := c.checkCall(call); err != nil {
+				return fmt.Errorf("template: %s:%d: %w", t.path, lineOf(tree, call.node), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkCall checks the props of a call whose keys are literal strings.
+func (t *HtmlTemplate) checkCall(call componentCall) error {
+	declared := t.current().props
+	passed := make(map[string]bool)
+	args := call.node.Args[2:]
+	for i := 0; i < len(args); i++ {
+		if _, ok := args[i].(*parse.PipeNode); ok {
+			// (slot ...) or a computed value
+			continue
+		}
+
+		key, ok := args[i].(*parse.StringNode)
+		if !ok || i+1 == len(args) {
+			return nil
+		}
+
+		p := prop(declared, key.Text)
+		if p == nil {
+			return fmt.Errorf("%w: %s: unknown prop %q", ErrInvalidProps, t.name, key.Text)
+		}
+
+		if !p.acceptsNode(args[i+1]) {
+			return fmt.Errorf("%w: %s: prop %q is not %s", ErrInvalidProps, t.name, key.Text, p.kind)
+		}
+
+		passed[key.Text] = true
+		i++
+	}
+
+	for _, p := range declared {
+		if p.required && !passed[p.name] {
+			return fmt.Errorf("%w: %s: missing required prop %q", ErrInvalidProps, t.name, p.name)
+		}
+	}
+
+	return nil
+}
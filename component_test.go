@@ -0,0 +1,146 @@
+package xun
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestComponent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"components/button.html": {Data: []byte("<!--props: href:url! variant:string=primary disabled:bool label=\"Click me\"-->\n" +
+			`<a class="btn btn-{{ .Props.variant }}" href="{{ .Props.href }}"{{ if .Props.disabled }} aria-disabled="true"{{ end }}>{{ or .Slot .Props.label }}</a>`)},
+		"components/card.html": {Data: []byte(`<div class="card"><h2>{{ .Props.title }}</h2>{{ .Slot }}<footer>{{ .Slot "footer" }}{{ component "components/button" "href" "/more" "variant" "link" }}</footer></div>`)},
+		"layouts/main.html":    {Data: []byte(`<main>{{ block "content" . }}{{ end }}</main>`)},
+		"pages/index.html": {Data: []byte(`<!--layout:main-->{{ define "content" }}` +
+			`{{ component "components/button" "href" .Data.URL "disabled" true }}` +
+			`{{ component "components/card" "title" "<Hi>" (slot "card-body" .) (slot "footer" "card-footer" .Data) }}{{ end }}` +
+			`{{ define "card-body" }}<p>{{ .Data.Text }}</p>{{ end }}{{ define "card-footer" }}<small>{{ .Text }}</small>{{ end }}`)},
+		"pages/missing.html":  {Data: []byte("<p>\n{{ component \"components/button\" \"variant\" \"ghost\" }}</p>")},
+		"pages/unknown.html":  {Data: []byte(`{{ component "components/button" "href" "/" "size" "xl" }}`)},
+		"pages/typed.html":    {Data: []byte(`{{ component "components/button" "href" "/" "disabled" "yes" }}`)},
+		"pages/runtime.html":  {Data: []byte(`{{ component "components/button" "href" .Data }}`)},
+		"pages/notfound.html": {Data: []byte(`{{ component "components/nope" }}`)},
+		"pages/slot.html":     {Data: []byte(`{{ component "components/card" "title" "x" (slot "nobody" .) }}`)},
+	}
+
+	mux := http.NewServeMux()
+
+	var logs bytes.Buffer
+	app := New(WithMux(mux), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	app.Get("/{$}", func(c *Context) error {
+		return c.View(map[string]string{"URL": "/go?a=1&b=2", "Text": "body <b>"})
+	})
+	app.Get("/runtime", func(c *Context) error {
+		return c.View(42)
+	})
+	app.Start()
+	defer app.Close()
+
+	t.Run("render", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, `<main>`+
+			`<a class="btn btn-primary" href="/go?a=1&amp;b=2" aria-disabled="true">Click me</a>`+
+			`<div class="card"><h2>&lt;Hi&gt;</h2><p>body &lt;b&gt;</p><footer><small>body &lt;b&gt;</small>`+
+			`<a class="btn btn-link" href="/more">Click me</a></footer></div>`+
+			`</main>`, rw.Body.String())
+	})
+
+	t.Run("load_errors", func(t *testing.T) {
+		require.Contains(t, logs.String(), `template: pages/missing.html:2: xun: invalid_props: components/button: missing required prop \"href\"`)
+		require.Contains(t, logs.String(), `components/button: unknown prop \"size\"`)
+		require.Contains(t, logs.String(), `components/button: prop \"disabled\" is not bool`)
+
+		for _, path := range []string{"/missing", "/unknown", "/typed"} {
+			rw := serve(mux, http.MethodGet, path, "Accept", "text/html")
+			require.Equal(t, http.StatusNotFound, rw.Code, path)
+		}
+	})
+
+	t.Run("runtime_errors", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/runtime", "Accept", "text/html")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, logs.String(), `components/button: prop \"href\" is int, not url`)
+
+		rw = serve(mux, http.MethodGet, "/notfound", "Accept", "text/html")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, logs.String(), `xun: component_not_found: \"components/nope\"`)
+	})
+
+	t.Run("check", func(t *testing.T) {
+		var problems []string
+		for _, p := range app.CheckTemplates() {
+			problems = append(problems, p.String())
+		}
+
+		require.Equal(t, []string{
+			`pages/missing.html:2: xun: invalid_props: components/button: missing required prop "href"`,
+			`pages/notfound.html:1: component "components/nope" is not found`,
+			`pages/slot.html:1: template "nobody" is not defined`,
+			`pages/typed.html:1: xun: invalid_props: components/button: prop "disabled" is not bool`,
+			`pages/unknown.html:1: xun: invalid_props: components/button: unknown prop "size"`,
+		}, problems)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		ve := app.engines[1].(*HtmlViewEngine)
+
+		// the broken pages are dropped from the dependents of the button when they are gone
+		delete(fsys, "pages/missing.html")
+		delete(fsys, "pages/unknown.html")
+		delete(fsys, "pages/typed.html")
+
+		// a new required prop breaks the pages that don't pass it
+		fsys["components/button.html"] = &fstest.MapFile{Data: []byte("<!--props: href:url! variant disabled:bool icon!-->\n<a>{{ .Slot }}</a>")}
+		err := ve.FileChanged(fsys, app, fsnotify.Event{Name: "components/button.html", Op: fsnotify.Write})
+		require.ErrorIs(t, err, ErrInvalidProps)
+		require.ErrorContains(t, err, `missing required prop "icon"`)
+	})
+}
+
+func TestComponentProps(t *testing.T) {
+	props, err := propsOf([]byte("\n<!--props:\n  href:url!\n  title=\"Hello world\"\n  count:int=3 ratio:float=0.5 on:bool=true\n-->"))
+	require.NoError(t, err)
+	require.Equal(t, []componentProp{
+		{name: "href", kind: "url", required: true},
+		{name: "title", kind: "any", def: "Hello world"},
+		{name: "count", kind: "int", def: 3},
+		{name: "ratio", kind: "float", def: 0.5},
+		{name: "on", kind: "bool", def: true},
+	}, props)
+
+	none, err := propsOf([]byte(`<p>no props</p>`))
+	require.NoError(t, err)
+	require.Nil(t, none)
+
+	for _, decl := range []string{
+		`<!--props: a:date-->`,
+		`<!--props: a:int=x-->`,
+		`<!--props: a:bool=maybe-->`,
+		`<!--props: a="x-->`,
+		`<!--props: a`,
+	} {
+		_, err := propsOf([]byte(decl))
+		require.ErrorIs(t, err, ErrInvalidProps, decl)
+	}
+
+	c := &HtmlTemplate{name: "components/x"}
+	c.parsed.Store(&htmlParsed{props: props})
+	values := map[string]any{"href": "/", "count": int64(2)}
+	require.NoError(t, c.bindProps(values))
+	require.Equal(t, map[string]any{"href": "/", "title": "Hello world", "count": int64(2), "ratio": 0.5, "on": true}, values)
+
+	require.ErrorIs(t, c.bindProps(map[string]any{"href": "/", "ratio": "x"}), ErrInvalidProps)
+	require.ErrorIs(t, c.bindProps(map[string]any{}), ErrInvalidProps)
+	require.ErrorIs(t, c.bindProps(map[string]any{"href": "/", "x": 1}), ErrInvalidProps)
+
+	// no declaration, no validation
+	require.NoError(t, (&HtmlTemplate{}).bindProps(map[string]any{"x": 1}))
+}
@@ -52,8 +52,50 @@ func (c *Context) View(data any, options ...string) error {
 		name = options[0]
 	}
 
+	v := c.negotiate(name)
+	if v == nil {
+		return ErrViewNotFound
+	}
+
+	return v.Render(c, data)
+}
+
+// ViewBlock renders only the named block of the html template of the named viewer, e.g.
+// c.ViewBlock(todos, "pages/todos", "list"), so a page can serve its own fragments.
+//
+// It returns ErrViewNotFound if there is no such html viewer, and an error that wraps
+// ErrBlockNotFound if the template has no such block.
+func (c *Context) ViewBlock(data any, name, block string) error {
+	it, _ := c.App.viewer(name)
+	v, ok := it.(*HtmlViewer)
+	if !ok {
+		// pages are registered without the "pages/" prefix
+		it, _ = c.App.viewer(strings.TrimPrefix(name, "pages/"))
+		v, ok = it.(*HtmlViewer)
+		if !ok {
+			return ErrViewNotFound
+		}
+	}
+
+	return v.RenderBlock(c, data, block)
+}
+
+// negotiate returns the viewer that View uses for the named viewer and the current request,
+// or nil if the route has no viewer.
+func (c *Context) negotiate(name string) Viewer {
 	v, ok := c.getViewer(name)
 
+	// ?format= is explicit, so a route viewer of that format wins over the named viewer
+	if ok {
+		if format, has := c.format(); has && !v.MimeType().Match(format) {
+			for _, viewer := range c.Routing.Viewers {
+				if viewer.MimeType().Match(format) {
+					return viewer
+				}
+			}
+		}
+	}
+
 	if !ok {
 		for _, accept := range c.Accept() {
 			for _, viewer := range c.Routing.Viewers {
@@ -73,13 +115,29 @@ func (c *Context) View(data any, options ...string) error {
 	if !ok {
 		if v == nil {
 			if len(c.Routing.Viewers) == 0 {
-				return ErrViewNotFound
+				return nil
 			}
 			v = c.Routing.Viewers[0] // use the first viewer as a fallback when no viewer is matched or specified by name
 		}
 	}
 
-	return v.Render(c, data)
+	return v
+}
+
+// IsHead reports whether the request is a HEAD request. GET routes also serve HEAD requests,
+// so a handler can skip work that only the body needs. The viewers still render buffered
+// content to set the Content-Length and ETag headers, but never send it.
+func (c *Context) IsHead() bool {
+	return c.Request.Method == http.MethodHead
+}
+
+// etagEnabled reports whether the rendered content gets an ETag, see WithETag and WithRouteETag.
+func (c *Context) etagEnabled() bool {
+	if c.App != nil && c.App.etag {
+		return true
+	}
+
+	return c.Routing.Options != nil && c.Routing.Options.Get(RouteETag) == true
 }
 
 // getViewer get viewer by name
@@ -87,7 +145,7 @@ func (c *Context) getViewer(name string) (Viewer, bool) {
 	if name == "" {
 		return nil, false
 	}
-	v, ok := c.App.viewers[name]
+	v, ok := c.App.viewer(name)
 	if ok {
 		mime := v.MimeType()
 		for _, accept := range c.Accept() {
@@ -137,10 +195,56 @@ func (c *Context) AcceptLanguage() (languages []string) {
 	return
 }
 
+// Locales returns a slice of strings representing the locales that the client
+// prefers, in order of preference.
+//
+// The locale selected by the `lang` query parameter comes first, followed by the
+// locale cookie (see WithLocaleCookie) and the languages in the Accept-Language header.
+// Underscores are normalized to hyphens, so zh_CN is returned as zh-CN.
+func (c *Context) Locales() (locales []string) {
+	if c.Request.URL != nil {
+		if v := c.Request.URL.Query().Get(LocaleParam); v != "" {
+			locales = append(locales, strings.ReplaceAll(v, "_", "-"))
+		}
+	}
+
+	name := DefaultLocaleCookie
+	if c.App != nil && c.App.localeCookie != "" {
+		name = c.App.localeCookie
+	}
+
+	if ck, err := c.Request.Cookie(name); err == nil && ck.Value != "" {
+		locales = append(locales, strings.ReplaceAll(ck.Value, "_", "-"))
+	}
+
+	for _, v := range c.AcceptLanguage() {
+		if v != "" && v != "*" {
+			locales = append(locales, strings.ReplaceAll(v, "_", "-"))
+		}
+	}
+
+	return
+}
+
+// defaultLocale returns the locale of the default templates that WithDefaultLocale sets.
+func (c *Context) defaultLocale() string {
+	if c.App == nil {
+		return ""
+	}
+	return c.App.defaultLocale
+}
+
 // Accept returns a slice of strings representing the media types
 // that the client accepts, in order of preference.
 // The media types are normalized to lowercase and whitespace is trimmed.
+//
+// A ?format= query parameter (e.g. ?format=csv) takes precedence over the
+// Accept header, so a link can ask for a representation without headers.
 func (c *Context) Accept() (types []MimeType) {
+	if format, ok := c.format(); ok {
+		types = append(types, format)
+	}
+
 	accepted := c.Request.Header.Get("Accept")
 	if accepted == "" {
 		return
@@ -148,20 +252,36 @@ func (c *Context) Accept() (types []MimeType) {
 
 	// text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7
 
-	options := strings.Split(accepted, ",")
-	l := len(options)
-	types = make([]MimeType, l)
-
-	for i := 0; i < l; i++ {
-		if n := strings.IndexByte(options[i], ';'); n >= 0 {
-			types[i] = NewMimeType(strings.TrimSpace(options[i][:n]))
+	for _, option := range strings.Split(accepted, ",") {
+		if n := strings.IndexByte(option, ';'); n >= 0 {
+			types = append(types, NewMimeType(strings.TrimSpace(option[:n])))
 		} else {
-			types[i] = NewMimeType(strings.TrimSpace(options[i]))
+			types = append(types, NewMimeType(strings.TrimSpace(option)))
 		}
 	}
 	return
 }
 
+// format returns the MIME type of the ?format= query parameter. The formats of the app
+// (see WithFormat) are looked up before Formats.
+func (c *Context) format() (MimeType, bool) {
+	format := c.Request.URL.Query().Get(FormatParam)
+	if format == "" {
+		return MimeType{}, false
+	}
+
+	if c.App != nil {
+		if mt, ok := c.App.formats[strings.ToLower(format)]; ok {
+			return NewMimeType(mt), true
+		}
+	}
+
+	if mt := FormatMimeType(format); mt != "" {
+		return NewMimeType(mt), true
+	}
+	return MimeType{}, false
+}
+
 // RequestReferer returns the referer of the request.
 func (c *Context) RequestReferer() string {
 	var v string
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"testing/fstest"
 
@@ -266,3 +267,114 @@ func TestDeleteHeader(t *testing.T) {
 	v = ctx.Response.Header().Get("test")
 	require.Empty(t, v)
 }
+
+func TestHead(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/about.html":  {Data: []byte(`<p>About</p>`)},
+		"views/report.html": {Data: []byte(`<p>{{.Data.Wait}}</p>`)},
+	}
+
+	mux := http.NewServeMux()
+	app := New(WithMux(mux), WithFsys(fsys), WithETag(), WithCompressor(&GzipCompressor{}))
+
+	var heads []bool
+	app.Get("/users", func(c *Context) error {
+		heads = append(heads, c.IsHead())
+		return c.View([]string{"Ada", "Grace"})
+	})
+
+	// the viewers must not range the stream or render the page on HEAD, or they block
+	app.Get("/events", func(c *Context) error {
+		return c.View(make(chan int))
+	}, WithViewer(&JsonViewer{Stream: true}))
+
+	app.Get("/rows", func(c *Context) error {
+		return c.View(make(chan []string))
+	}, WithViewer(&CsvViewer{}))
+
+	app.Get("/report", func(c *Context) error {
+		return c.View(&streamPage{next: make(chan struct{})}, "views/report")
+	}, WithStreaming())
+
+	app.Get("/problem", func(c *Context) error {
+		return NewProblem(http.StatusConflict, "conflict")
+	})
+
+	app.Get("/csv", func(c *Context) error {
+		return c.View([][]string{{"a", "b"}, {"c", "d"}})
+	}, WithViewer(&CsvViewer{}))
+
+	app.Group("/admin").Get("/users", func(c *Context) error {
+		return c.View("ok")
+	}, WithViewer(&StringViewer{}))
+
+	app.Start()
+	defer app.Close()
+
+	t.Run("handler", func(t *testing.T) {
+		get := serve(mux, http.MethodGet, "/users")
+		require.Equal(t, http.StatusOK, get.Code)
+
+		head := serve(mux, http.MethodHead, "/users")
+		require.Equal(t, http.StatusOK, head.Code)
+		require.Zero(t, head.Body.Len())
+		require.Equal(t, "application/json", head.Header().Get("Content-Type"))
+		require.Equal(t, strconv.Itoa(get.Body.Len()), head.Header().Get("Content-Length"))
+		require.Equal(t, get.Header().Get("ETag"), head.Header().Get("ETag"))
+
+		require.Equal(t, []bool{false, true}, heads)
+
+		head = serve(mux, http.MethodHead, "/users", "If-None-Match", get.Header().Get("ETag"))
+		require.Equal(t, http.StatusNotModified, head.Code)
+	})
+
+	t.Run("page", func(t *testing.T) {
+		head := serve(mux, http.MethodHead, "/about")
+		require.Equal(t, http.StatusOK, head.Code)
+		require.Zero(t, head.Body.Len())
+		require.Equal(t, strconv.Itoa(len(`<p>About</p>`)), head.Header().Get("Content-Length"))
+		require.NotEmpty(t, head.Header().Get("ETag"))
+	})
+
+	t.Run("group", func(t *testing.T) {
+		head := serve(mux, http.MethodHead, "/admin/users")
+		require.Equal(t, http.StatusOK, head.Code)
+		require.Zero(t, head.Body.Len())
+		require.Equal(t, "2", head.Header().Get("Content-Length"))
+	})
+
+	t.Run("headers_should_be_same_as_get", func(t *testing.T) {
+		for _, path := range []string{"/users", "/about", "/admin/users", "/problem", "/csv"} {
+			for _, encoding := range []string{"gzip", "identity"} {
+				get := serve(mux, http.MethodGet, path, "Accept", "*/*", "Accept-Encoding", encoding)
+				head := serve(mux, http.MethodHead, path, "Accept", "*/*", "Accept-Encoding", encoding)
+				require.Zero(t, head.Body.Len(), path)
+				require.NotZero(t, get.Body.Len(), path)
+				require.Equal(t, get.Code, head.Code, path)
+
+				if encoding == "identity" {
+					require.Equal(t, strconv.Itoa(get.Body.Len()), head.Header().Get("Content-Length"), path)
+				} else {
+					require.Equal(t, "gzip", head.Header().Get("Content-Encoding"), path)
+					// the compressed length is only known when the body is compressed
+					require.Empty(t, head.Header().Get("Content-Length"), path)
+				}
+
+				for _, h := range []http.Header{get.Header(), head.Header()} {
+					h.Del("Content-Length")
+					h.Del("X-Log-Id")
+				}
+				require.Equal(t, get.Header(), head.Header(), path)
+			}
+		}
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		for _, path := range []string{"/events", "/rows", "/report"} {
+			head := serve(mux, http.MethodHead, path, "Accept", "*/*")
+			require.Equal(t, http.StatusOK, head.Code, path)
+			require.Zero(t, head.Body.Len(), path)
+			require.Empty(t, head.Header().Get("ETag"), path)
+		}
+	})
+}
@@ -3,6 +3,11 @@ package xun
 import "errors"
 
 var (
-	ErrCancelled    = errors.New("xun: request_cancelled")
-	ErrViewNotFound = errors.New("xun: view_not_found")
+	ErrCancelled     = errors.New("xun: request_cancelled")
+	ErrViewNotFound  = errors.New("xun: view_not_found")
+	ErrBlockNotFound = errors.New("xun: block_not_found")
+	ErrLayoutCycle   = errors.New("xun: layout_cycle")
+
+	ErrComponentNotFound = errors.New("xun: component_not_found")
+	ErrInvalidProps      = errors.New("xun: invalid_props")
 )
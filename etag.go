@@ -1,6 +1,7 @@
 package xun
 
 import (
+	"bytes"
 	// skipcq: GSC-G401, GSC-G501, GO-S1023
 	"encoding/hex"
 	"hash"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/textproto"
+	"strconv"
 	"strings"
 )
 
@@ -30,6 +32,8 @@ func ComputeETagWith(r io.Reader, h hash.Hash) string {
 	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
 }
 
+// WriteIfNoneMatch writes 304 Not Modified and returns true if the If-None-Match header of a
+// GET or HEAD request matches the ETag header of the response.
 func WriteIfNoneMatch(w http.ResponseWriter, r *http.Request) bool {
 	if r.Method == "GET" || r.Method == "HEAD" {
 		if checkIfNoneMatch(w, r) {
@@ -105,6 +109,40 @@ func etagWeakMatch(a, b string) bool {
 	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
 }
 
+// writeBuffer writes the rendered buf to the response. If WithETag or WithRouteETag is
+// enabled, it sets an ETag computed from buf, and writes 304 Not Modified instead when the
+// client has the same content.
+//
+// The ETag is weak on a compressed response, because it is computed from the uncompressed
+// content, so it doesn't identify the bytes that are sent.
+//
+// A HEAD request gets the headers of GET, but not buf itself. Its Content-Length is the
+// length of buf unless the response is compressed, because the compressed length is only
+// known when buf is compressed.
+func writeBuffer(ctx *Context, buf *bytes.Buffer) error {
+	if ctx.etagEnabled() && ctx.Response.StatusCode() == http.StatusOK {
+		etag := ComputeETag(bytes.NewReader(buf.Bytes()))
+		if ctx.Response.Header().Get("Content-Encoding") != "" {
+			etag = "W/" + etag
+		}
+
+		ctx.Response.Header().Set("ETag", etag)
+		if WriteIfNoneMatch(ctx.Response, ctx.Request) {
+			return nil
+		}
+	}
+
+	if ctx.IsHead() {
+		if ctx.Response.Header().Get("Content-Encoding") == "" {
+			ctx.Response.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		}
+		return nil
+	}
+
+	_, err := buf.WriteTo(ctx.Response)
+	return err
+}
+
 func writeNotModified(w http.ResponseWriter) {
 	// RFC 7232 section 4.1:
 	// a sender SHOULD NOT generate representation metadata other than the
@@ -3,7 +3,9 @@ package xun
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -86,3 +88,147 @@ func TestETag(t *testing.T) {
 		require.False(t, WriteIfNoneMatch(w, req))
 	})
 }
+
+func TestWithETag(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/user.html":   {Data: []byte(`<p>{{.Data.Name}}</p>`)},
+		"text/user.txt":     {Data: []byte(`{{.Data.Name}}`)},
+		"pages/status.html": {Data: []byte(`<p>ok</p>`)},
+	}
+
+	mux := http.NewServeMux()
+	app := New(WithMux(mux), WithFsys(fsys), WithETag(), WithCompressor(&GzipCompressor{}))
+
+	type user struct {
+		Name string
+	}
+
+	name := "Ada"
+	app.Get("/user", func(c *Context) error {
+		return c.View(user{Name: name})
+	}, WithViewer(&JsonViewer{}, &XmlViewer{}))
+
+	app.Get("/user.html", func(c *Context) error {
+		return c.View(map[string]string{"Name": name}, "views/user")
+	})
+
+	app.Get("/user.txt", func(c *Context) error {
+		return c.View(map[string]string{"Name": name}, "text/user.txt")
+	})
+
+	app.Post("/user", func(c *Context) error {
+		c.WriteStatus(http.StatusCreated)
+		return c.View(map[string]string{"Name": name})
+	})
+
+	app.Start()
+	defer app.Close()
+
+	t.Run("json", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/user")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "{\"Name\":\"Ada\"}\n", rw.Body.String())
+
+		etag := rw.Header().Get("ETag")
+		require.Equal(t, ComputeETag(strings.NewReader(rw.Body.String())), etag)
+
+		rw = serve(mux, http.MethodGet, "/user", "If-None-Match", etag)
+		require.Equal(t, http.StatusNotModified, rw.Code)
+		require.Empty(t, rw.Body.String())
+		require.Empty(t, rw.Header().Get("Content-Type"))
+		require.Equal(t, etag, rw.Header().Get("ETag"))
+
+		name = "Grace"
+		defer func() { name = "Ada" }()
+
+		rw = serve(mux, http.MethodGet, "/user", "If-None-Match", etag)
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "{\"Name\":\"Grace\"}\n", rw.Body.String())
+		require.NotEqual(t, etag, rw.Header().Get("ETag"))
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/user")
+		strong := rw.Header().Get("ETag")
+
+		rw = serve(mux, http.MethodGet, "/user", "Accept-Encoding", "gzip")
+		require.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+
+		weak := rw.Header().Get("ETag")
+		require.Equal(t, "W/"+strong, weak)
+
+		rw = serve(mux, http.MethodGet, "/user", "Accept-Encoding", "gzip", "If-None-Match", weak)
+		require.Equal(t, http.StatusNotModified, rw.Code)
+		require.Empty(t, rw.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("viewers", func(t *testing.T) {
+		for _, it := range []struct {
+			path   string
+			accept string
+		}{
+			{"/user", "text/xml"},
+			{"/user.html", "text/html"},
+			{"/user.txt", "text/plain"},
+			{"/status", "text/html"},
+		} {
+			rw := serve(mux, http.MethodGet, it.path, "Accept", it.accept)
+			require.Equal(t, http.StatusOK, rw.Code, it.path)
+
+			etag := rw.Header().Get("ETag")
+			require.NotEmpty(t, etag, it.path)
+
+			rw = serve(mux, http.MethodGet, it.path, "Accept", it.accept, "If-None-Match", etag)
+			require.Equal(t, http.StatusNotModified, rw.Code, it.path)
+		}
+	})
+
+	t.Run("not_ok", func(t *testing.T) {
+		rw := serve(mux, http.MethodPost, "/user")
+		require.Equal(t, http.StatusCreated, rw.Code)
+		require.Empty(t, rw.Header().Get("ETag"))
+		require.Equal(t, "{\"Name\":\"Ada\"}\n", rw.Body.String())
+	})
+}
+
+func TestWithRouteETag(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	app := New(WithMux(mux))
+
+	app.Get("/stats", func(c *Context) error {
+		return c.View(map[string]int{"Users": 1})
+	}, WithRouteETag())
+
+	app.Get("/now", func(c *Context) error {
+		return c.View(map[string]int{"Users": 1})
+	})
+
+	app.Start()
+	defer app.Close()
+
+	resp, err := http.Get(srv.URL + "/stats")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	require.False(t, strings.HasPrefix(etag, "W/"))
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/stats", nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/now")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("ETag"))
+}
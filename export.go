@@ -0,0 +1,272 @@
+package xun
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ExportOptions holds the options of App.Export.
+type ExportOptions struct {
+	urls []string
+}
+
+// ExportOption is a function that takes a pointer to ExportOptions and modifies it.
+type ExportOption func(*ExportOptions)
+
+// WithExportURLs adds the paths to export besides the routes, e.g. the posts of a
+// "GET /posts/{slug}" route that isn't linked from any page.
+func WithExportURLs(urls ...string) ExportOption {
+	return func(o *ExportOptions) {
+		o.urls = append(o.urls, urls...)
+	}
+}
+
+// ErrExport is returned by App.Export when a path can't be exported.
+var ErrExport = errors.New("xun: export")
+
+// linkAttr matches the links of a html document.
+var linkAttr = regexp.MustCompile(`(?i)\s(?:href|src)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// Export renders the app to dir as a static site, which can be uploaded to any static host:
+//
+//	files, err := app.Export("dist", xun.WithExportURLs("/posts/hello"))
+//
+// It requests every GET route without wildcards, the asset URLs and the urls of the options
+// through the handlers and middlewares of the app, and then the links to local paths that it
+// finds in the html documents. A html document is written as path/index.html, so /about is
+// served from about/index.html, and the other responses are written as their paths, e.g.
+// the public files and the fingerprinted assets.
+//
+// A redirect is followed instead of written. It returns an error that wraps ErrExport if a
+// route or a url of the options doesn't respond 200 OK; a broken link is logged and skipped.
+// It returns the written files relative to dir.
+func (app *App) Export(dir string, opts ...ExportOption) ([]string, error) {
+	o := &ExportOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var seeds []string
+
+	app.mu.RLock()
+	for pattern, r := range app.routes {
+		if p, ok := exportPath(pattern); ok && !r.removed {
+			seeds = append(seeds, p)
+		}
+	}
+	for _, it := range app.AssetURLs {
+		seeds = append(seeds, it)
+	}
+	app.mu.RUnlock()
+
+	slices.Sort(seeds)
+
+	for _, it := range o.urls {
+		u, err := url.Parse(it)
+		if err != nil || u.Path == "" {
+			return nil, fmt.Errorf("%w: invalid url %q", ErrExport, it)
+		}
+		seeds = append(seeds, cleanPath(u.Path))
+	}
+
+	e := &exporter{app: app, dir: dir, visited: make(map[string]bool)}
+
+	for _, p := range seeds {
+		if err := e.export(p, true); err != nil {
+			return nil, err
+		}
+	}
+
+	// the links are found while the queue grows
+	for i := 0; i < len(e.links); i++ {
+		if err := e.export(e.links[i], false); err != nil {
+			return nil, err
+		}
+	}
+
+	// e.g. /about and /about/ are both about/index.html
+	slices.Sort(e.files)
+
+	return slices.Compact(e.files), nil
+}
+
+// exportPath returns the path of a GET route without wildcards, e.g. "/admin/" for "GET /admin/{$}".
+func exportPath(pattern string) (string, bool) {
+	if pattern == liveReloadPattern {
+		return "", false
+	}
+
+	method, host, p := splitPattern(pattern)
+	if (method != "" && method != http.MethodGet) || host != "" {
+		return "", false
+	}
+
+	p = "/" + strings.TrimSuffix(p, "{$}")
+	if strings.Contains(p, "{") {
+		return "", false
+	}
+
+	return p, true
+}
+
+// cleanPath returns the clean absolute path, which keeps its trailing slash.
+func cleanPath(p string) string {
+	it := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && it != "/" {
+		it += "/"
+	}
+	return it
+}
+
+type exporter struct {
+	app     *App
+	dir     string
+	visited map[string]bool
+	links   []string
+	files   []string
+}
+
+// export requests the path and writes the response. A path that must be exported is a route
+// or a url of the options, and the others are links.
+func (e *exporter) export(p string, must bool) error {
+	if e.visited[p] {
+		return nil
+	}
+	e.visited[p] = true
+
+	req, err := http.NewRequest(http.MethodGet, p, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrExport, p, err)
+	}
+	req.Host = "localhost"
+	req.Header.Set("Accept", "text/html,*/*;q=0.8")
+
+	w := &exportWriter{header: make(http.Header)}
+
+	// a path that only the catch-all route of pages/index.html matches is not a page
+	if _, pattern := e.app.mux.Handler(req); p != "/" && (pattern == "/" || pattern == "GET /") {
+		w.status = http.StatusNotFound
+	} else if e.serve(w, req) {
+		return fmt.Errorf("%w: %s: %w", ErrExport, p, http.ErrAbortHandler)
+	}
+
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if status >= 300 && status < 400 {
+		if loc := w.header.Get("Location"); loc != "" {
+			e.follow(p, loc)
+			return nil
+		}
+	}
+
+	if status != http.StatusOK {
+		if must {
+			return fmt.Errorf("%w: %s: %d %s", ErrExport, p, status, http.StatusText(status))
+		}
+		e.app.logger.Warn("xun: export broken link", slog.String("path", p), slog.Int("status", status))
+		return nil
+	}
+
+	html := strings.HasPrefix(w.header.Get("Content-Type"), "text/html")
+
+	name := p
+	if strings.HasSuffix(name, "/") {
+		name += "index.html"
+	} else if html && path.Ext(name) == "" {
+		name += "/index.html"
+	}
+	name = strings.TrimPrefix(name, "/")
+
+	file := filepath.Join(e.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrExport, p, err)
+	}
+
+	if err := os.WriteFile(file, w.buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrExport, p, err)
+	}
+
+	e.files = append(e.files, name)
+
+	if html {
+		for _, m := range linkAttr.FindAllSubmatch(w.buf.Bytes(), -1) {
+			e.follow(p, string(m[1])+string(m[2]))
+		}
+	}
+
+	return nil
+}
+
+// serve serves the request, and reports whether the handler aborted the response, e.g. a
+// stream that fails.
+func (e *exporter) serve(w http.ResponseWriter, req *http.Request) (aborted bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			if p != http.ErrAbortHandler { // nolint: errorlint
+				panic(p)
+			}
+			aborted = true
+		}
+	}()
+
+	e.app.mux.ServeHTTP(w, req)
+	return false
+}
+
+// follow queues a link of the page at base if it is a local path.
+func (e *exporter) follow(base, link string) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return
+	}
+
+	p := u.Path
+	if !strings.HasPrefix(p, "/") {
+		p = path.Dir(base+"x") + "/" + p
+	}
+
+	p = cleanPath(p)
+	if !e.visited[p] {
+		e.links = append(e.links, p)
+	}
+}
+
+// exportWriter keeps the response of a request in memory.
+type exportWriter struct {
+	header http.Header
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *exportWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *exportWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(p)
+}
+
+// Flush is a no-op, so the streaming pages are exported as a whole.
+func (w *exportWriter) Flush() {}
@@ -0,0 +1,155 @@
+package xun
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public/css/site.css":    {Data: []byte(`body { color: red; }`)},
+		"public/robots.txt":      {Data: []byte(`User-agent: *`)},
+		"public/docs/index.html": {Data: []byte(`<a href="../">home</a>`)},
+		"layouts/main.html": {
+			Data: []byte(`<html><head><link rel="stylesheet" href="{{ asset "/css/site.css" }}"></head><body>{{ .TempData.site }}: {{ block "content" . }}{{ end }}</body></html>`),
+		},
+		"pages/index.html": {
+			Data: []byte(`<!--layout:main-->{{ define "content" }}<a href="/about">About</a> <a href='blog/'>Blog</a> <a href="https://example.com/x">x</a> <a href="#top">top</a> <a href="mailto:a@example.com">mail</a>{{ end }}`),
+		},
+		"pages/about.html":      {Data: []byte(`<!--layout:main-->{{ define "content" }}<a href="/old">old</a> <a href="/missing">missing</a>{{ end }}`)},
+		"pages/blog/index.html": {Data: []byte(`<!--layout:main-->{{ define "content" }}<a href="hello?ref=blog">Hello</a>{{ end }}`)},
+		"pages/contact.html":    {Data: []byte("---\nmethods: [POST]\n---\n<p>sent</p>")},
+		"views/post.html":       {Data: []byte(`<p>{{ .Data.Slug }}</p>`)},
+	}
+
+	var logs bytes.Buffer
+	app := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithBuildAssetURL(func(s string) bool { return strings.HasPrefix(s, "/css/") }))
+	defer app.Close()
+
+	// the export runs through the middlewares
+	app.Use(func(next HandleFunc) HandleFunc {
+		return func(c *Context) error {
+			c.TempData["site"] = "Xun"
+			return next(c)
+		}
+	})
+
+	app.Get("/blog/{slug}", func(c *Context) error {
+		return c.View(map[string]string{"Slug": c.Request.PathValue("slug")}, "views/post")
+	}, WithViewer(&HtmlViewer{}))
+
+	app.Get("/api/status", func(c *Context) error {
+		return c.View(map[string]string{"status": "ok"})
+	})
+
+	app.Get("/old", func(c *Context) error {
+		c.Redirect("/blog/moved")
+		return nil
+	})
+
+	app.Post("/api/login", func(c *Context) error {
+		return nil
+	})
+
+	css := app.AssetURLs["/css/site.css"]
+	require.NotEmpty(t, css)
+
+	dir := t.TempDir()
+	files, err := app.Export(dir, WithExportURLs("/blog/world"))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"about/index.html",
+		"api/status",
+		"blog/hello/index.html",
+		"blog/index.html",
+		"blog/moved/index.html",
+		"blog/world/index.html",
+		strings.TrimPrefix(css, "/"),
+		"css/site.css",
+		"docs/index.html",
+		"index.html",
+		"robots.txt",
+	}, files)
+
+	read := func(name string) string {
+		buf, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(buf)
+	}
+
+	require.Contains(t, read("index.html"), `<link rel="stylesheet" href="`+css+`">`)
+	require.Contains(t, read("index.html"), `Xun: <a href="/about">About</a>`)
+	require.Contains(t, read("about/index.html"), `Xun: <a href="/old">old</a>`)
+	require.Equal(t, `<p>hello</p>`, read("blog/hello/index.html"))
+	require.Equal(t, `<p>world</p>`, read("blog/world/index.html"))
+	require.Equal(t, `<p>moved</p>`, read("blog/moved/index.html"))
+	require.Equal(t, `body { color: red; }`, read(css))
+	require.Equal(t, `body { color: red; }`, read("css/site.css"))
+	require.Equal(t, `User-agent: *`, read("robots.txt"))
+	require.JSONEq(t, `{"status":"ok"}`, read("api/status"))
+
+	_, err = os.Stat(filepath.Join(dir, "contact"))
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	require.Contains(t, logs.String(), `msg="xun: export broken link" path=/missing status=404`)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := app.Export(t.TempDir(), WithExportURLs("/nothing/here"))
+		require.ErrorIs(t, err, ErrExport)
+		require.ErrorContains(t, err, "xun: export: /nothing/here: 404 Not Found")
+
+		_, err = app.Export(t.TempDir(), WithExportURLs("%zz"))
+		require.ErrorIs(t, err, ErrExport)
+
+		app.Get("/fail", func(c *Context) error {
+			return errors.New("failed")
+		})
+
+		_, err = app.Export(t.TempDir())
+		require.ErrorIs(t, err, ErrExport)
+		require.ErrorContains(t, err, "xun: export: /fail: 500 Internal Server Error")
+
+		// a file is in the way of the directory
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o644))
+		_, err = app.Export(file)
+		require.ErrorIs(t, err, ErrExport)
+	})
+}
+
+func TestExportPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		ok      bool
+	}{
+		{"GET /", "/", true},
+		{"GET /about", "/about", true},
+		{"GET /admin/{$}", "/admin/", true},
+		{"/feed.xml", "/feed.xml", true},
+		{"GET /posts/{slug}", "", false},
+		{"GET /files/{path...}", "", false},
+		{"POST /login", "", false},
+		{"GET example.com/about", "", false},
+		{liveReloadPattern, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			p, ok := exportPath(test.pattern)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.path, p)
+		})
+	}
+}
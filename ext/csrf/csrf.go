@@ -31,7 +31,7 @@ func New(secretKey []byte, opts ...Option) xun.Middleware {
 
 			token, _ := c.Request.Cookie(o.CookieName) // nolint: errcheck
 
-			if c.Request.Method == "GET" || c.Request.Method == "HEAD" || c.Request.Method == "OPTIONS" {
+			if c.Request.Method == http.MethodGet || c.IsHead() || c.Request.Method == http.MethodOptions {
 				if token == nil { // csrf_token doesn't exists
 					setTokenCookie(c, o)
 				}
@@ -0,0 +1,164 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/yaitoo/xun"
+)
+
+// AtomViewer is a viewer that writes a Feed as an Atom (RFC 4287) document.
+//
+// It sets the Content-Type header to "application/atom+xml; charset=utf-8".
+type AtomViewer struct {
+}
+
+var atomViewerMime = &xun.MimeType{Type: "application", SubType: "atom+xml"}
+
+// MimeType returns the MIME type of the Atom content.
+//
+// It returns "application/atom+xml".
+func (*AtomViewer) MimeType() *xun.MimeType {
+	return atomViewerMime
+}
+
+// Render renders the feed as Atom to the http.ResponseWriter.
+func (*AtomViewer) Render(c *xun.Context, data any) error { // skipcq: RVV-B0012
+	f, err := feedOf(data)
+	if err != nil {
+		return err
+	}
+
+	r := newResolver(c.Request)
+	updated := f.updated()
+
+	doc := atomFeed{
+		Xmlns:    "http://www.w3.org/2005/Atom",
+		Lang:     f.Language,
+		Base:     r.root(),
+		ID:       f.ID,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  atomTime(updated),
+		Author:   atomPersonOf(f.Author, r),
+		Links: []atomLink{
+			{Href: r.resolve(c.Request.URL.RequestURI()), Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	if f.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: r.resolve(f.Link), Rel: "alternate", Type: "text/html"})
+	}
+
+	if doc.ID == "" {
+		doc.ID = r.resolve(f.Link)
+	}
+
+	if doc.ID == "" {
+		doc.ID = doc.Links[0].Href
+	}
+
+	for _, it := range f.Items {
+		e := atomEntry{
+			ID:        it.ID,
+			Title:     it.Title,
+			Published: atomTime(it.Published),
+			Updated:   atomTime(it.updated()),
+			Author:    atomPersonOf(it.Author, r),
+		}
+
+		if e.ID == "" {
+			e.ID = r.resolve(it.Link)
+		}
+
+		if it.Link != "" {
+			e.Link = &atomLink{Href: r.resolve(it.Link), Rel: "alternate", Type: "text/html"}
+		}
+
+		if it.Summary != "" {
+			e.Summary = &atomText{Type: "text", Body: it.Summary}
+		}
+
+		if it.Content != "" {
+			e.Content = &atomText{Type: "html", Body: it.Content}
+		}
+
+		for _, term := range it.Categories {
+			e.Categories = append(e.Categories, atomCategory{Term: term})
+		}
+
+		doc.Entries = append(doc.Entries, e)
+	}
+
+	buf := xun.BufPool.Get()
+	defer xun.BufPool.Put(buf)
+
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(buf).Encode(doc); err != nil {
+		return err
+	}
+
+	return write(c, "application/atom+xml; charset=utf-8", updated, buf)
+}
+
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func atomPersonOf(p *Person, r resolver) *atomPerson {
+	if p == nil {
+		return nil
+	}
+
+	return &atomPerson{Name: p.Name, Email: p.Email, URI: r.resolve(p.URI)}
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	Base     string      `xml:"xml:base,attr,omitempty"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   *atomPerson `xml:"author,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       *atomLink      `xml:"link,omitempty"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+	URI   string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
@@ -0,0 +1,216 @@
+// Package feed provides Atom and RSS viewers for xun web applications.
+//
+// Register them with the html viewer of a listing route, and a feed reader that asks for
+// "application/atom+xml" or "application/rss+xml" gets the feed instead of the page:
+//
+//	app.Get("/blog", func(c *xun.Context) error {
+//		return c.View(&feed.Feed{Title: "Blog", Link: "/blog", Items: items})
+//	}, xun.WithViewer(&feed.AtomViewer{}, &feed.RssViewer{}))
+//
+// Pass WithFormats to xun.New to select a feed by ?format=atom or ?format=rss too.
+//
+// Relative links are resolved against the scheme and host of the request, and the feed
+// supports conditional GET through ETag and Last-Modified.
+package feed
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/yaitoo/xun"
+)
+
+// ErrNotFeed is returned when the data of a feed viewer is not a feed.
+var ErrNotFeed = errors.New("feed: data is not a feed")
+
+// WithFormats registers the "atom" and "rss" ?format= values on the app, so a feed can be
+// requested by ?format=atom or ?format=rss as well as by the Accept header.
+func WithFormats() xun.Option {
+	return func(app *xun.App) {
+		xun.WithFormat("atom", atomViewerMime.String())(app)
+		xun.WithFormat("rss", rssViewerMime.String())(app)
+	}
+}
+
+// Feed is the channel of a feed.
+type Feed struct {
+	// ID is the permanent id of the feed. It is the absolute Link if it is empty.
+	ID          string
+	Title       string
+	Description string
+	// Link is the html page of the feed.
+	Link     string
+	Language string
+	Author   *Person
+	// Updated is the last time the feed changed. It is the latest time of the items if it is zero.
+	Updated time.Time
+	Items   []Item
+}
+
+// Item is an entry of a feed.
+type Item struct {
+	// ID is the permanent id of the item. It is the absolute Link if it is empty.
+	ID    string
+	Title string
+	Link  string
+	// Summary is plain text.
+	Summary string
+	// Content is html, it is escaped in the feed document.
+	Content    string
+	Author     *Person
+	Categories []string
+	Published  time.Time
+	// Updated is the Published time if it is zero.
+	Updated time.Time
+}
+
+// Person is the author of a feed or an item.
+type Person struct {
+	Name  string
+	Email string
+	URI   string
+}
+
+// Source is implemented by data that is not a feed itself, e.g. the model of a listing
+// page, so one value can be rendered by both the html viewer and the feed viewers.
+type Source interface {
+	Feed() *Feed
+}
+
+// feedOf returns the feed of the data of a viewer.
+func feedOf(data any) (*Feed, error) {
+	switch it := data.(type) {
+	case *Feed:
+		if it != nil {
+			return it, nil
+		}
+	case Feed:
+		return &it, nil
+	case Source:
+		if f := it.Feed(); f != nil {
+			return f, nil
+		}
+	}
+
+	return nil, ErrNotFeed
+}
+
+// updated returns the Updated time of the feed, or the latest time of its items.
+func (f *Feed) updated() time.Time {
+	if !f.Updated.IsZero() {
+		return f.Updated
+	}
+
+	var t time.Time
+	for _, it := range f.Items {
+		if u := it.updated(); u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
+
+func (it *Item) updated() time.Time {
+	if !it.Updated.IsZero() {
+		return it.Updated
+	}
+	return it.Published
+}
+
+// resolver resolves relative links against the scheme and host of the request.
+type resolver struct {
+	base *url.URL
+}
+
+func newResolver(r *http.Request) resolver {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+
+	return resolver{base: &url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path}}
+}
+
+func (r resolver) resolve(link string) string {
+	if link == "" {
+		return ""
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	return r.base.ResolveReference(u).String()
+}
+
+// root returns the absolute url of the site, that is the base of links in html content.
+func (r resolver) root() string {
+	return r.resolve("/")
+}
+
+// write sends a rendered feed with its ETag and Last-Modified headers, or 304 Not Modified
+// if the client has the same feed.
+func write(c *xun.Context, contentType string, updated time.Time, buf *bytes.Buffer) error {
+	h := c.Response.Header()
+	h.Set("Content-Type", contentType)
+
+	// a compressed response is only semantically equivalent to the feed, as with xun.WithETag
+	etag := xun.ComputeETag(bytes.NewReader(buf.Bytes()))
+	if h.Get("Content-Encoding") != "" {
+		etag = "W/" + etag
+	}
+	h.Set("ETag", etag)
+
+	if !updated.IsZero() {
+		h.Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	}
+
+	if xun.WriteIfNoneMatch(c.Response, c.Request) {
+		return nil
+	}
+
+	if notModifiedSince(c.Request, updated) {
+		h.Del("Content-Type")
+		c.WriteStatus(http.StatusNotModified)
+		return nil
+	}
+
+	if c.IsHead() {
+		// the length of a compressed body isn't known without compressing it
+		if h.Get("Content-Encoding") == "" {
+			h.Set("Content-Length", strconv.Itoa(buf.Len()))
+		}
+		return nil
+	}
+
+	_, err := buf.WriteTo(c.Response)
+	return err
+}
+
+// notModifiedSince reports whether the feed has not changed since If-Modified-Since. It is
+// ignored when the request has If-None-Match (RFC 9110 13.1.3).
+func notModifiedSince(r *http.Request, updated time.Time) bool {
+	if updated.IsZero() || r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	return !updated.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,306 @@
+package feed
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun"
+)
+
+type blog struct {
+	posts []Item
+}
+
+func (b *blog) Feed() *Feed {
+	return &Feed{
+		Title:       "Blog",
+		Description: "News & notes",
+		Link:        "/blog",
+		Language:    "en",
+		Author:      &Person{Name: "xun", URI: "/about"},
+		Items:       b.posts,
+	}
+}
+
+// serve serves a request on h with the given header pairs, and returns the recorded response.
+func serve(h http.Handler, method, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	return rw
+}
+
+func TestFeed(t *testing.T) {
+	published := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	updated := time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC)
+
+	posts := []Item{
+		{
+			Title:      "Hello <World>",
+			Link:       "/blog/hello",
+			Summary:    "a < b",
+			Content:    `<p>See <a href="/docs">docs</a> &amp; more</p>`,
+			Author:     &Person{Name: "Ada", Email: "ada@example.com"},
+			Categories: []string{"go"},
+			Published:  published,
+			Updated:    updated,
+		},
+		{
+			ID:        "urn:post:2",
+			Title:     "Second",
+			Link:      "https://cdn.example.com/second",
+			Summary:   "plain",
+			Published: published,
+		},
+	}
+
+	mux := http.NewServeMux()
+
+	fsys := fstest.MapFS{
+		"views/blog.html": {Data: []byte(`<ul>{{range .Data.Feed.Items}}<li>{{.Title}}</li>{{end}}</ul>`)},
+	}
+
+	app := xun.New(xun.WithMux(mux), xun.WithFsys(fsys), WithFormats())
+
+	app.Get("/blog", func(c *xun.Context) error {
+		return c.View(&blog{posts: posts}, "views/blog")
+	}, xun.WithViewer(&AtomViewer{}, &RssViewer{}))
+
+	app.Get("/empty", func(c *xun.Context) error {
+		return c.View(Feed{Title: "Empty"})
+	}, xun.WithViewer(&AtomViewer{}))
+
+	app.Get("/invalid", func(c *xun.Context) error {
+		return c.View("not a feed")
+	}, xun.WithViewer(&RssViewer{}))
+
+	app.Start()
+	defer app.Close()
+
+	t.Run("html", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blog", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<ul><li>Hello &lt;World&gt;</li><li>Second</li></ul>", rw.Body.String())
+	})
+
+	t.Run("atom", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blog", "Accept", "application/atom+xml")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "application/atom+xml; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Equal(t, "Fri, 03 May 2024 09:30:00 GMT", rw.Header().Get("Last-Modified"))
+		require.NotEmpty(t, rw.Header().Get("ETag"))
+
+		require.True(t, strings.HasPrefix(rw.Body.String(), xml.Header))
+
+		var doc struct {
+			Base    string `xml:"base,attr"`
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Links   []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+			Author struct {
+				URI string `xml:"uri"`
+			} `xml:"author"`
+			Entries []struct {
+				ID      string `xml:"id"`
+				Title   string `xml:"title"`
+				Updated string `xml:"updated"`
+				Link    struct {
+					Href string `xml:"href,attr"`
+				} `xml:"link"`
+				Summary string `xml:"summary"`
+				Content struct {
+					Type string `xml:"type,attr"`
+					Body string `xml:",chardata"`
+				} `xml:"content"`
+			} `xml:"entry"`
+		}
+		require.NoError(t, xml.Unmarshal(rw.Body.Bytes(), &doc))
+
+		require.Equal(t, "http://example.com/", doc.Base)
+		require.Equal(t, "http://example.com/blog", doc.ID)
+		require.Equal(t, "2024-05-03T09:30:00Z", doc.Updated)
+		require.Len(t, doc.Links, 2)
+		require.Equal(t, "self", doc.Links[0].Rel)
+		require.Equal(t, "http://example.com/blog", doc.Links[0].Href)
+		require.Equal(t, "alternate", doc.Links[1].Rel)
+		require.Equal(t, "http://example.com/about", doc.Author.URI)
+
+		require.Len(t, doc.Entries, 2)
+		require.Equal(t, "http://example.com/blog/hello", doc.Entries[0].ID)
+		require.Equal(t, "http://example.com/blog/hello", doc.Entries[0].Link.Href)
+		require.Equal(t, "Hello <World>", doc.Entries[0].Title)
+		require.Equal(t, "2024-05-03T09:30:00Z", doc.Entries[0].Updated)
+		require.Equal(t, "a < b", doc.Entries[0].Summary)
+		require.Equal(t, "html", doc.Entries[0].Content.Type)
+		require.Equal(t, posts[0].Content, doc.Entries[0].Content.Body)
+
+		require.Equal(t, "urn:post:2", doc.Entries[1].ID)
+		require.Equal(t, "https://cdn.example.com/second", doc.Entries[1].Link.Href)
+		require.Equal(t, "2024-05-01T08:00:00Z", doc.Entries[1].Updated)
+
+		// the html content is escaped, not embedded as markup
+		require.Contains(t, rw.Body.String(), "&lt;p&gt;See &lt;a href=&#34;/docs&#34;&gt;docs&lt;/a&gt; &amp;amp; more&lt;/p&gt;")
+	})
+
+	t.Run("rss", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blog", "Accept", "application/rss+xml")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "application/rss+xml; charset=utf-8", rw.Header().Get("Content-Type"))
+
+		var doc struct {
+			Version string `xml:"version,attr"`
+			Channel struct {
+				Title         string `xml:"title"`
+				Description   string `xml:"description"`
+				LastBuildDate string `xml:"lastBuildDate"`
+				Items         []struct {
+					Link        string `xml:"link"`
+					Description string `xml:"description"`
+					Author      string `xml:"author"`
+					PubDate     string `xml:"pubDate"`
+					GUID        struct {
+						ID          string `xml:",chardata"`
+						IsPermaLink string `xml:"isPermaLink,attr"`
+					} `xml:"guid"`
+				} `xml:"item"`
+			} `xml:"channel"`
+		}
+		require.NoError(t, xml.Unmarshal(rw.Body.Bytes(), &doc))
+
+		require.Equal(t, "2.0", doc.Version)
+		require.Equal(t, "News & notes", doc.Channel.Description)
+		require.Contains(t, rw.Body.String(), "<link>http://example.com/blog</link>")
+		require.Contains(t, rw.Body.String(), `<atom:link href="http://example.com/blog" rel="self" type="application/rss+xml"></atom:link>`)
+		require.Equal(t, "Fri, 03 May 2024 09:30:00 +0000", doc.Channel.LastBuildDate)
+
+		require.Len(t, doc.Channel.Items, 2)
+		require.Equal(t, posts[0].Content, doc.Channel.Items[0].Description)
+		require.Equal(t, "ada@example.com (Ada)", doc.Channel.Items[0].Author)
+		require.Equal(t, "Wed, 01 May 2024 08:00:00 +0000", doc.Channel.Items[0].PubDate)
+		require.Equal(t, "http://example.com/blog/hello", doc.Channel.Items[0].GUID.ID)
+		require.Equal(t, "true", doc.Channel.Items[0].GUID.IsPermaLink)
+
+		require.Equal(t, "plain", doc.Channel.Items[1].Description)
+		require.Equal(t, "urn:post:2", doc.Channel.Items[1].GUID.ID)
+		require.Equal(t, "false", doc.Channel.Items[1].GUID.IsPermaLink)
+	})
+
+	t.Run("format", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blog?format=rss", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "application/rss+xml; charset=utf-8", rw.Header().Get("Content-Type"))
+
+		rw = serve(mux, http.MethodGet, "/blog?format=ATOM", "Accept", "text/html")
+		require.Equal(t, "application/atom+xml; charset=utf-8", rw.Header().Get("Content-Type"))
+
+		// the formats are registered on the app, not on xun.Formats
+		_, ok := xun.Formats["rss"]
+		require.False(t, ok)
+	})
+
+	t.Run("forwarded_proto", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blog", "Accept", "application/atom+xml", "X-Forwarded-Proto", "https")
+		require.Contains(t, rw.Body.String(), `xml:base="https://example.com/"`)
+	})
+
+	t.Run("if_none_match", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blog", "Accept", "application/atom+xml")
+		etag := rw.Header().Get("ETag")
+
+		rw = serve(mux, http.MethodGet, "/blog", "Accept", "application/atom+xml", "If-None-Match", etag)
+		require.Equal(t, http.StatusNotModified, rw.Code)
+		require.Empty(t, rw.Body.String())
+
+		// the rss document has its own etag
+		rw = serve(mux, http.MethodGet, "/blog", "Accept", "application/rss+xml", "If-None-Match", etag)
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.NotEmpty(t, rw.Body.String())
+	})
+
+	t.Run("if_modified_since", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blog", "Accept", "application/rss+xml", "If-Modified-Since", "Fri, 03 May 2024 09:30:00 GMT")
+		require.Equal(t, http.StatusNotModified, rw.Code)
+		require.Empty(t, rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/blog", "Accept", "application/rss+xml", "If-Modified-Since", "Fri, 03 May 2024 09:29:59 GMT")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.NotEmpty(t, rw.Body.String())
+
+		// If-None-Match takes precedence over If-Modified-Since
+		rw = serve(mux, http.MethodGet, "/blog", "Accept", "application/rss+xml", "If-None-Match", `"stale"`, "If-Modified-Since", "Fri, 03 May 2024 09:30:00 GMT")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.NotEmpty(t, rw.Body.String())
+	})
+
+	t.Run("head", func(t *testing.T) {
+		rw := serve(mux, http.MethodHead, "/blog", "Accept", "application/atom+xml")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Empty(t, rw.Body.String())
+
+		// HEAD has the headers of GET
+		get := serve(mux, http.MethodGet, "/blog", "Accept", "application/atom+xml")
+		require.Equal(t, get.Header().Get("ETag"), rw.Header().Get("ETag"))
+		require.Equal(t, strconv.Itoa(get.Body.Len()), rw.Header().Get("Content-Length"))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/empty", "Accept", "application/atom+xml")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Empty(t, rw.Header().Get("Last-Modified"))
+
+		require.Contains(t, rw.Body.String(), "<id>http://example.com/empty</id>")
+		require.NotContains(t, rw.Body.String(), "<entry>")
+	})
+
+	t.Run("not_feed", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/invalid", "Accept", "application/rss+xml")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+	})
+}
+
+func TestFeedCompressed(t *testing.T) {
+	mux := http.NewServeMux()
+	app := xun.New(xun.WithMux(mux), xun.WithCompressor(&xun.GzipCompressor{}))
+
+	app.Get("/blog", func(c *xun.Context) error {
+		return c.View(Feed{Title: "Blog", Items: []Item{{Title: "Hello", Link: "/blog/hello"}}})
+	}, xun.WithViewer(&AtomViewer{}))
+
+	app.Start()
+	defer app.Close()
+
+	rw := serve(mux, http.MethodGet, "/blog", "Accept", "application/atom+xml", "Accept-Encoding", "gzip")
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+
+	// the compressed feed has a weak etag, which still matches If-None-Match
+	etag := rw.Header().Get("ETag")
+	require.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+	rw = serve(mux, http.MethodGet, "/blog", "Accept", "application/atom+xml", "Accept-Encoding", "gzip", "If-None-Match", etag)
+
+	require.Equal(t, http.StatusNotModified, rw.Code)
+
+	// HEAD has the encoding and the etag of GET, but not the length of the uncompressed feed
+	rw = serve(mux, http.MethodHead, "/blog", "Accept", "application/atom+xml", "Accept-Encoding", "gzip")
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+	require.Equal(t, etag, rw.Header().Get("ETag"))
+	require.Empty(t, rw.Header().Get("Content-Length"))
+	require.Zero(t, rw.Body.Len())
+}
@@ -0,0 +1,155 @@
+package feed
+
+import (
+	"encoding/xml"
+	"html"
+	"time"
+
+	"github.com/yaitoo/xun"
+)
+
+// RssViewer is a viewer that writes a Feed as an RSS 2.0 document.
+//
+// It sets the Content-Type header to "application/rss+xml; charset=utf-8".
+type RssViewer struct {
+}
+
+var rssViewerMime = &xun.MimeType{Type: "application", SubType: "rss+xml"}
+
+// MimeType returns the MIME type of the RSS content.
+//
+// It returns "application/rss+xml".
+func (*RssViewer) MimeType() *xun.MimeType {
+	return rssViewerMime
+}
+
+// Render renders the feed as RSS to the http.ResponseWriter.
+func (*RssViewer) Render(c *xun.Context, data any) error { // skipcq: RVV-B0012
+	f, err := feedOf(data)
+	if err != nil {
+		return err
+	}
+
+	r := newResolver(c.Request)
+	updated := f.updated()
+
+	ch := rssChannel{
+		Title:         f.Title,
+		Link:          r.resolve(f.Link),
+		Description:   f.Description,
+		Language:      f.Language,
+		LastBuildDate: rssTime(updated),
+		AtomLink: rssAtomLink{
+			Href: r.resolve(c.Request.URL.RequestURI()),
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+	}
+
+	if ch.Link == "" {
+		ch.Link = r.root()
+	}
+
+	for _, it := range f.Items {
+		item := rssItem{
+			Title:      it.Title,
+			Link:       r.resolve(it.Link),
+			Author:     rssAuthor(it.Author),
+			Categories: it.Categories,
+			PubDate:    rssTime(it.Published),
+		}
+
+		if item.PubDate == "" {
+			item.PubDate = rssTime(it.updated())
+		}
+
+		// RSS has a single description, which is html
+		if it.Content != "" {
+			item.Description = it.Content
+		} else {
+			item.Description = html.EscapeString(it.Summary)
+		}
+
+		if it.ID != "" {
+			item.GUID = &rssGUID{ID: it.ID, IsPermaLink: "false"}
+		} else if item.Link != "" {
+			item.GUID = &rssGUID{ID: item.Link, IsPermaLink: "true"}
+		}
+
+		ch.Items = append(ch.Items, item)
+	}
+
+	doc := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: ch,
+	}
+
+	buf := xun.BufPool.Get()
+	defer xun.BufPool.Put(buf)
+
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(buf).Encode(doc); err != nil {
+		return err
+	}
+
+	return write(c, "application/rss+xml; charset=utf-8", updated, buf)
+}
+
+func rssTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC1123Z)
+}
+
+// rssAuthor returns the author of an item, which is an email address with an optional name.
+func rssAuthor(p *Person) string {
+	if p == nil || p.Email == "" {
+		return ""
+	}
+
+	if p.Name == "" {
+		return p.Email
+	}
+
+	return p.Email + " (" + p.Name + ")"
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	Language      string      `xml:"language,omitempty"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	AtomLink      rssAtomLink `xml:"atom:link"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title,omitempty"`
+	Link        string   `xml:"link,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Author      string   `xml:"author,omitempty"`
+	Categories  []string `xml:"category"`
+	GUID        *rssGUID `xml:"guid,omitempty"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	ID          string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
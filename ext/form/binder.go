@@ -115,3 +115,15 @@ func (t *TEntity[T]) Error() string {
 	}
 	return strings.Join(errs, "\n")
 }
+
+// Problem converts the validation errors to a 400 Bad Request problem with an "errors"
+// extension that maps field names to messages.
+func (t *TEntity[T]) Problem() *xun.Problem {
+	return xun.NewProblem(http.StatusBadRequest, "validation failed").With("errors", t.Errors)
+}
+
+// Unwrap returns the validation errors as a problem, so a handler that returns the entity
+// as an error gets a problem details response.
+func (t *TEntity[T]) Unwrap() error {
+	return t.Problem()
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 
 	"net/http"
 	"net/http/httptest"
@@ -216,3 +217,48 @@ func TestError(t *testing.T) {
 	require.Equal(t, "key: value", e.Error())
 
 }
+
+func TestProblem(t *testing.T) {
+	type Login struct {
+		Email string `form:"email" json:"email" validate:"required,email"`
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	app := xun.New(xun.WithMux(mux))
+	app.Post("/login", func(c *xun.Context) error {
+		it, err := BindJson[Login](c.Request)
+		if err != nil {
+			return err
+		}
+
+		if !it.Validate(c.AcceptLanguage()...) {
+			return it
+		}
+
+		return c.View(it.Data)
+	})
+	app.Start()
+	defer app.Close()
+
+	resp, err := http.Post(srv.URL+"/login", "application/json", strings.NewReader(`{"email":"xun"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	var p xun.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	require.Equal(t, http.StatusBadRequest, p.Status)
+	require.Equal(t, "Bad Request", p.Title)
+	require.Equal(t, resp.Header.Get("X-Log-Id"), p.Instance)
+	require.Equal(t, map[string]any{"Email": "Email must be a valid email address"}, p.Extensions["errors"])
+
+	it := &TEntity[Login]{Errors: map[string]string{"email": "required"}}
+	var problem *xun.Problem
+	require.True(t, errors.As(it, &problem))
+	require.Equal(t, map[string]string{"email": "required"}, problem.Extensions["errors"])
+}
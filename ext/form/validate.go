@@ -1,6 +1,8 @@
 package form
 
 import (
+	"strings"
+
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
@@ -43,11 +45,28 @@ func AddValidator(trans ut.Translator, register func(v *validator.Validate, tran
 	return v
 }
 
+// findValidator returns the validator of the first locale that has one. Each locale is
+// tried with its subtags removed one by one (zh-Hant-TW, zh-Hant, zh) before the next one,
+// and both zh-CN and zh_CN forms are accepted.
 func findValidator(locales ...string) *Validator {
 	for _, locale := range locales {
-		if v, ok := validators[locale]; ok {
-			return v
+		for tag := locale; tag != ""; tag = parentLocale(tag) {
+			if v, ok := validators[tag]; ok {
+				return v
+			}
+
+			if v, ok := validators[strings.ReplaceAll(tag, "-", "_")]; ok {
+				return v
+			}
 		}
 	}
 	return defaultValidator
 }
+
+func parentLocale(locale string) string {
+	i := strings.LastIndexAny(locale, "-_")
+	if i < 0 {
+		return ""
+	}
+	return locale[:i]
+}
@@ -0,0 +1,22 @@
+package form
+
+import (
+	"testing"
+
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	trans "github.com/go-playground/validator/v10/translations/zh"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindValidator(t *testing.T) {
+	v := AddValidator(ut.New(zh.New()).GetFallback(), trans.RegisterDefaultTranslations)
+
+	require.Equal(t, v, findValidator("zh"))
+	require.Equal(t, v, findValidator("zh-CN"))
+	require.Equal(t, v, findValidator("zh_Hant_TW"))
+	require.Equal(t, v, findValidator("fr", "zh-TW", "en"))
+	require.Equal(t, defaultValidator, findValidator("en-US", "zh"))
+	require.Equal(t, defaultValidator, findValidator("fr"))
+	require.Equal(t, defaultValidator, findValidator())
+}
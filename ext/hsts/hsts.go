@@ -45,7 +45,7 @@ func WriteHeader(opts ...Option) xun.Middleware {
 	return func(next xun.HandleFunc) xun.HandleFunc {
 		return func(c *xun.Context) error {
 			r := c.Request
-			if (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https") && (r.Method == "GET" || r.Method == "HEAD") {
+			if (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https") && (r.Method == http.MethodGet || c.IsHead()) {
 				v := "max-age=" + strconv.FormatInt(cfg.MaxAge, 10)
 				if cfg.IncludeSubDomains {
 					v += "; includeSubDomains"
@@ -65,7 +65,7 @@ func WriteHeader(opts ...Option) xun.Middleware {
 func Redirect(rules ...IgnoreRule) xun.Middleware {
 	return func(next xun.HandleFunc) xun.HandleFunc {
 		return func(c *xun.Context) error {
-			if c.Request.TLS == nil && (c.Request.Method == "GET" || c.Request.Method == "HEAD") {
+			if c.Request.TLS == nil && (c.Request.Method == http.MethodGet || c.IsHead()) {
 				for _, it := range rules {
 					if it(c.Request) {
 						return next(c)
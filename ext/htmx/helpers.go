@@ -139,3 +139,13 @@ func WriteReswap(c *xun.Context, strategy string) {
 func WriteReselect(c *xun.Context, selector string) {
 	WriteHeader(c, HxReselect, selector)
 }
+
+// WithTargetBlock is a routing option that renders only the block of the page named by the
+// "HX-Target" header, so an element can be swapped with the block of the same name:
+//
+//	<ul id="list" hx-get="/todos" hx-trigger="refresh">{{ block "list" . }}...{{ end }}</ul>
+//
+// A request without the header, or with an element id that is not a block, gets the whole page.
+func WithTargetBlock() xun.RoutingOption {
+	return xun.WithBlockHeader(HxTarget)
+}
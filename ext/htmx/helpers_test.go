@@ -2,8 +2,10 @@ package htmx
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 	"github.com/yaitoo/xun"
@@ -186,3 +188,44 @@ func TestWriteSwapOverrides(t *testing.T) {
 		require.Equal(t, "#content", w.Header().Get(HxReselect))
 	})
 }
+
+func TestWithTargetBlock(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html": {Data: []byte(`<html><body>{{block "content" .}}{{end}}</body></html>`)},
+		"pages/todos.html":  {Data: []byte(`<!--layout:main-->{{define "content"}}<ul id="list">{{block "list" .}}{{range .Data}}<li>{{.}}</li>{{end}}{{end}}</ul>{{end}}`)},
+	}
+
+	mux := http.NewServeMux()
+
+	app := xun.New(xun.WithMux(mux), xun.WithFsys(fsys))
+	app.Get("/todos", func(c *xun.Context) error {
+		return c.View([]string{"a", "b"})
+	}, WithTargetBlock())
+
+	app.Start()
+	defer app.Close()
+
+	page := `<html><body><ul id="list"><li>a</li><li>b</li></ul></body></html>`
+
+	for _, it := range []struct {
+		target string
+		want   string
+	}{
+		{"", page},
+		{"list", `<li>a</li><li>b</li>`},
+		{"body", page},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/todos", nil)
+		req.Header.Set("Accept", "text/html")
+		if it.target != "" {
+			req.Header.Set(HxRequest, "true")
+			req.Header.Set(HxTarget, it.target)
+		}
+		rw := httptest.NewRecorder()
+		mux.ServeHTTP(rw, req)
+
+		require.Equal(t, http.StatusOK, rw.Code, it.target)
+		require.Equal(t, HxTarget, rw.Header().Get("Vary"), it.target)
+		require.Equal(t, it.want, rw.Body.String(), it.target)
+	}
+}
@@ -0,0 +1,261 @@
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedFormat = errors.New("i18n: unsupported catalog format")
+
+// Message is a translated message. A plural message has a text for each CLDR plural category.
+type Message struct {
+	Text   string
+	Plural map[string]string
+}
+
+// Catalog holds all messages of a locale keyed by message key.
+type Catalog map[string]Message
+
+// ParseCatalog parses a JSON or TOML catalog. The format is selected by the extension of name.
+//
+// Nested objects (JSON) and tables (TOML) are flattened into dotted keys, and an object whose
+// keys are all plural categories becomes a plural message:
+//
+//	{
+//	  "nav": { "home": "Home" },
+//	  "apples": { "one": "{count} apple", "other": "{count} apples" }
+//	}
+func ParseCatalog(name string, buf []byte) (Catalog, error) {
+	var (
+		items map[string]any
+		err   error
+	)
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		err = json.Unmarshal(buf, &items)
+	case ".toml":
+		items, err = parseToml(buf)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("i18n: %s: %w", name, err)
+	}
+
+	c := make(Catalog)
+	c.flatten("", items)
+
+	return c, nil
+}
+
+func (c Catalog) flatten(prefix string, items map[string]any) {
+	for k, v := range items {
+		key := prefix + k
+
+		switch it := v.(type) {
+		case string:
+			c[key] = Message{Text: it}
+		case map[string]any:
+			if plural, ok := toPlural(it); ok {
+				c[key] = Message{Plural: plural}
+				continue
+			}
+			c.flatten(key+".", it)
+		default:
+			c[key] = Message{Text: fmt.Sprint(it)}
+		}
+	}
+}
+
+func toPlural(items map[string]any) (map[string]string, bool) {
+	if len(items) == 0 {
+		return nil, false
+	}
+
+	plural := make(map[string]string, len(items))
+	for k, v := range items {
+		switch k {
+		case Zero, One, Two, Few, Many, Other:
+		default:
+			return nil, false
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		plural[k] = s
+	}
+
+	return plural, true
+}
+
+// parseToml parses the subset of TOML that catalogs need: comments, [table] headers with
+// dotted names, and `key = "value"` pairs with bare, quoted or dotted keys. Values are basic
+// ("...") or literal ('...') strings, or bare numbers and booleans.
+func parseToml(buf []byte) (map[string]any, error) {
+	root := make(map[string]any)
+	table := root
+
+	for n, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: invalid table header", n+1)
+			}
+
+			keys, err := splitTomlKey(line[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+
+			table = root
+			for _, k := range keys {
+				table = tomlTable(table, k)
+			}
+			continue
+		}
+
+		eq := tomlKeyEnd(line)
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: missing '='", n+1)
+		}
+
+		keys, err := splitTomlKey(line[:eq])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		value, err := parseTomlValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		t := table
+		for _, k := range keys[:len(keys)-1] {
+			t = tomlTable(t, k)
+		}
+		t[keys[len(keys)-1]] = value
+	}
+
+	return root, nil
+}
+
+func tomlTable(parent map[string]any, key string) map[string]any {
+	if t, ok := parent[key].(map[string]any); ok {
+		return t
+	}
+
+	t := make(map[string]any)
+	parent[key] = t
+	return t
+}
+
+// tomlKeyEnd returns the index of the '=' that ends the key, skipping quoted key parts.
+func tomlKeyEnd(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+func splitTomlKey(s string) ([]string, error) {
+	var keys []string
+
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var key string
+		switch s[0] {
+		case '"', '\'':
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			key = s[1 : end+1]
+			s = strings.TrimSpace(s[end+2:])
+		default:
+			end := strings.IndexByte(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			key = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("empty key")
+		}
+		keys = append(keys, key)
+
+		if s != "" {
+			if s[0] != '.' {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			s = strings.TrimSpace(s[1:])
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+
+	return keys, nil
+}
+
+func parseTomlValue(s string) (any, error) {
+	if s == "" {
+		return nil, fmt.Errorf("missing value")
+	}
+
+	switch s[0] {
+	case '"':
+		end := 1
+		for ; end < len(s); end++ {
+			if s[end] == '\\' {
+				end++
+				continue
+			}
+			if s[end] == '"' {
+				break
+			}
+		}
+		if end >= len(s) {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return strconv.Unquote(s[:end+1])
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : end+1], nil
+	}
+
+	// bare numbers and booleans, with an optional trailing comment
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s, nil
+}
@@ -0,0 +1,83 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCatalog(t *testing.T) {
+	t.Run("json_should_work", func(t *testing.T) {
+		c, err := ParseCatalog("locales/en.json", []byte(`{
+	"hello": "Hello {name}",
+	"nav": { "home": "Home", "menu": { "about": "About" } },
+	"apples": { "one": "{count} apple", "other": "{count} apples" },
+	"numbers": { "one": "One", "total": "Total" },
+	"version": 2
+}`))
+		require.NoError(t, err)
+
+		require.Equal(t, Message{Text: "Hello {name}"}, c["hello"])
+		require.Equal(t, Message{Text: "Home"}, c["nav.home"])
+		require.Equal(t, Message{Text: "About"}, c["nav.menu.about"])
+		require.Equal(t, Message{Plural: map[string]string{One: "{count} apple", Other: "{count} apples"}}, c["apples"])
+		require.Equal(t, Message{Text: "One"}, c["numbers.one"])
+		require.Equal(t, Message{Text: "Total"}, c["numbers.total"])
+		require.Equal(t, Message{Text: "2"}, c["version"])
+	})
+
+	t.Run("toml_should_work", func(t *testing.T) {
+		c, err := ParseCatalog("locales/fr.toml", []byte(`
+# comment
+hello = "Bonjour {name}"
+path = 'C:\\temp\\{name}'
+escaped = "line\n\"quoted\""
+"quoted.key" = "quoted"
+site.title = "Titre" # trailing comment
+
+[nav]
+home = "Accueil"
+
+[nav.menu]
+about = "À propos"
+
+[apples]
+one = "{count} pomme"
+many = "{count} de pommes"
+other = "{count} pommes"
+`))
+		require.NoError(t, err)
+
+		require.Equal(t, Message{Text: "Bonjour {name}"}, c["hello"])
+		require.Equal(t, Message{Text: `C:\\temp\\{name}`}, c["path"])
+		require.Equal(t, Message{Text: "line\n\"quoted\""}, c["escaped"])
+		require.Equal(t, Message{Text: "quoted"}, c["quoted.key"])
+		require.Equal(t, Message{Text: "Titre"}, c["site.title"])
+		require.Equal(t, Message{Text: "Accueil"}, c["nav.home"])
+		require.Equal(t, Message{Text: "À propos"}, c["nav.menu.about"])
+		require.Equal(t, Message{Plural: map[string]string{One: "{count} pomme", Many: "{count} de pommes", Other: "{count} pommes"}}, c["apples"])
+	})
+
+	t.Run("invalid_should_fail", func(t *testing.T) {
+		_, err := ParseCatalog("locales/en.yaml", []byte(`hello: world`))
+		require.ErrorIs(t, err, ErrUnsupportedFormat)
+
+		_, err = ParseCatalog("locales/en.json", []byte(`{`))
+		require.Error(t, err)
+
+		_, err = ParseCatalog("locales/en.toml", []byte(`[nav`))
+		require.Error(t, err)
+
+		_, err = ParseCatalog("locales/en.toml", []byte(`hello "world"`))
+		require.Error(t, err)
+
+		_, err = ParseCatalog("locales/en.toml", []byte(`hello = "world`))
+		require.Error(t, err)
+
+		_, err = ParseCatalog("locales/en.toml", []byte(`hello =`))
+		require.Error(t, err)
+
+		_, err = ParseCatalog("locales/en.toml", []byte(`a..b = "c"`))
+		require.Error(t, err)
+	})
+}
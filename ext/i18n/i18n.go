@@ -0,0 +1,327 @@
+// Package i18n provides key-based message translation for xun applications.
+//
+// Message catalogs are loaded from `locales/*.json` or `locales/*.toml` in the App's fsys,
+// one file per locale (e.g. `locales/en.json`, `locales/zh-CN.toml`). The Translator is a
+// xun.ViewEngine, so catalogs are hot reloaded by the App's watcher when WithWatch is enabled.
+//
+// Example usage:
+//
+//	tr := i18n.New()
+//	app := xun.New(
+//		xun.WithFsys(fsys),
+//		xun.WithTemplateFuncMap(tr.FuncMap()),
+//		xun.WithViewEngines(&xun.StaticViewEngine{}, &xun.HtmlViewEngine{}, &xun.TextViewEngine{}, tr),
+//	)
+//	app.Use(tr.Middleware())
+//
+// Templates translate messages with the `t` and `tn` functions. The first argument is the
+// locale, or the view model (`$`) whose TempData holds the locale resolved by the middleware:
+//
+//	<h1>{{ t $ "welcome" "name" .Data.Name }}</h1>
+//	<p>{{ tn $ "cart.items" .Data.Count }}</p>
+package i18n
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/yaitoo/xun"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+// LocaleKey is the TempData key that stores the locale resolved by the middleware.
+const LocaleKey = "locale"
+
+// Translator loads message catalogs and translates messages.
+type Translator struct {
+	mu       sync.RWMutex
+	catalogs map[string]Catalog
+	options  *Options
+}
+
+// New creates a Translator with the given options.
+func New(opts ...Option) *Translator {
+	o := &Options{
+		Dir:           DefaultDir,
+		DefaultLocale: DefaultLocale,
+		Logger:        slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Translator{
+		catalogs: make(map[string]Catalog),
+		options:  o,
+	}
+}
+
+// Load loads all catalogs in the catalog directory of the given file system.
+func (tr *Translator) Load(fsys fs.FS, _ *xun.App) {
+	fs.WalkDir(fsys, tr.options.Dir, func(path string, d fs.DirEntry, err error) error { // nolint: errcheck
+		if d != nil && !d.IsDir() {
+			if err := tr.loadCatalog(fsys, path); err != nil {
+				tr.options.Logger.Error("i18n: load catalog", slog.String("path", path), slog.Any("err", err))
+			}
+		}
+		return nil
+	})
+}
+
+// FileChanged reloads a catalog when it is created or updated, and drops it when it is removed.
+func (tr *Translator) FileChanged(fsys fs.FS, _ *xun.App, event fsnotify.Event) error {
+	locale, ok := tr.localeOf(event.Name)
+	if !ok {
+		return nil
+	}
+
+	if event.Has(fsnotify.Remove) {
+		tr.mu.Lock()
+		delete(tr.catalogs, locale)
+		tr.mu.Unlock()
+		return nil
+	}
+
+	return tr.loadCatalog(fsys, event.Name)
+}
+
+// localeOf returns the locale of a catalog file, e.g. locales/zh-CN.json => zh-CN.
+func (tr *Translator) localeOf(name string) (string, bool) {
+	dir, file := path.Split(name)
+	if path.Clean(dir) != path.Clean(tr.options.Dir) {
+		return "", false
+	}
+
+	ext := strings.ToLower(path.Ext(file))
+	if ext != ".json" && ext != ".toml" {
+		return "", false
+	}
+
+	return file[:len(file)-len(ext)], true
+}
+
+func (tr *Translator) loadCatalog(fsys fs.FS, name string) error {
+	locale, ok := tr.localeOf(name)
+	if !ok {
+		return nil
+	}
+
+	buf, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	c, err := ParseCatalog(name, buf)
+	if err != nil {
+		return err
+	}
+
+	tr.mu.Lock()
+	tr.catalogs[locale] = c
+	tr.mu.Unlock()
+
+	return nil
+}
+
+// Locales returns the locales that have a catalog.
+func (tr *Translator) Locales() []string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	locales := make([]string, 0, len(tr.catalogs))
+	for locale := range tr.catalogs {
+		locales = append(locales, locale)
+	}
+
+	return locales
+}
+
+// Match returns the first preferred locale that has a catalog. Each preferred locale is tried
+// with its subtags removed one by one (zh-TW, then zh) before the next one is tried.
+// It returns the default locale if none of them has a catalog.
+func (tr *Translator) Match(preferred ...string) string {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	for _, p := range preferred {
+		for tag := p; tag != ""; tag = parent(tag) {
+			for locale := range tr.catalogs {
+				if strings.EqualFold(locale, tag) {
+					return locale
+				}
+			}
+		}
+	}
+
+	return tr.options.DefaultLocale
+}
+
+// Locale returns the locale of the request. It is the locale resolved by the middleware, or
+// the best match of c.Locales() (the `lang` query parameter, the locale cookie and Accept-Language).
+func (tr *Translator) Locale(c *xun.Context) string {
+	if locale, ok := c.Get(LocaleKey).(string); ok && locale != "" {
+		return locale
+	}
+
+	return tr.Match(c.Locales()...)
+}
+
+// Languages returns the resolved locale of the request followed by all locales the client
+// prefers. It is designed to be passed to form validation, so validation messages share the
+// locale of the page:
+//
+//	if !it.Validate(i18n.Languages(c)...) { ... }
+func Languages(c *xun.Context) []string {
+	locales := c.Locales()
+	if locale, ok := c.Get(LocaleKey).(string); ok && locale != "" {
+		return append([]string{locale}, locales...)
+	}
+	return locales
+}
+
+// Middleware returns a middleware that resolves the locale of the request and stores it in
+// TempData with LocaleKey, so templates, handlers and form validation share the same locale.
+func (tr *Translator) Middleware() xun.Middleware {
+	return func(next xun.HandleFunc) xun.HandleFunc {
+		return func(c *xun.Context) error {
+			c.Set(LocaleKey, tr.Match(c.Locales()...))
+			return next(c)
+		}
+	}
+}
+
+// T translates the message of key in the given locale and interpolates the placeholders.
+//
+// Placeholders such as `{name}` are replaced by args given as name/value pairs, or by a
+// map[string]any. If the key is missing in the locale, its parent locales and the default
+// locale are tried in order; the key itself is returned when no catalog has it.
+func (tr *Translator) T(locale, key string, args ...any) string {
+	msg, _, ok := tr.find(locale, key)
+	if !ok {
+		return key
+	}
+
+	text := msg.Text
+	if text == "" && msg.Plural != nil {
+		text = msg.Plural[Other]
+	}
+
+	return interpolate(text, args)
+}
+
+// N translates the plural message of key for the number n in the given locale.
+//
+// The plural category of n is selected by the CLDR rule of the catalog's language, falling
+// back to "other". The `{count}` placeholder is replaced by n.
+func (tr *Translator) N(locale, key string, n any, args ...any) string {
+	msg, found, ok := tr.find(locale, key)
+	if !ok {
+		return key
+	}
+
+	text := msg.Text
+	if msg.Plural != nil {
+		text, ok = msg.Plural[Plural(found, n)]
+		if !ok {
+			text = msg.Plural[Other]
+		}
+	}
+
+	return interpolate(text, append([]any{"count", n}, args...))
+}
+
+// find returns the message of key and the locale of the catalog that it was found in.
+func (tr *Translator) find(locale, key string) (Message, string, bool) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	for _, l := range []string{locale, tr.options.DefaultLocale} {
+		for tag := l; tag != ""; tag = parent(tag) {
+			for name, c := range tr.catalogs {
+				if !strings.EqualFold(name, tag) {
+					continue
+				}
+				if msg, ok := c[key]; ok {
+					return msg, name, true
+				}
+			}
+		}
+	}
+
+	return Message{}, "", false
+}
+
+// FuncMap returns the `t` and `tn` template functions. Register them with xun.WithTemplateFuncMap.
+func (tr *Translator) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"t": func(locale any, key string, args ...any) string {
+			return tr.T(tr.localeFrom(locale), key, args...)
+		},
+		"tn": func(locale any, key string, n any, args ...any) string {
+			return tr.N(tr.localeFrom(locale), key, n, args...)
+		},
+	}
+}
+
+// localeFrom returns the locale from a template argument: a locale string, the view model
+// or its TempData.
+func (tr *Translator) localeFrom(v any) string {
+	var td map[string]any
+
+	switch it := v.(type) {
+	case string:
+		return it
+	case xun.ViewModel:
+		td = it.TempData
+	case *xun.ViewModel:
+		td = it.TempData
+	case xun.TempData:
+		td = it
+	case map[string]any:
+		td = it
+	}
+
+	if locale, ok := td[LocaleKey].(string); ok && locale != "" {
+		return locale
+	}
+
+	return tr.options.DefaultLocale
+}
+
+func parent(locale string) string {
+	i := strings.LastIndexByte(locale, '-')
+	if i < 0 {
+		return ""
+	}
+	return locale[:i]
+}
+
+// interpolate replaces `{name}` placeholders in text with args given as name/value pairs or maps.
+func interpolate(text string, args []any) string {
+	if len(args) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	var pairs []string
+	for i := 0; i < len(args); i++ {
+		if m, ok := args[i].(map[string]any); ok {
+			for k, v := range m {
+				pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+			}
+			continue
+		}
+
+		if i+1 < len(args) {
+			pairs = append(pairs, "{"+fmt.Sprint(args[i])+"}", fmt.Sprint(args[i+1]))
+			i++
+		}
+	}
+
+	return strings.NewReplacer(pairs...).Replace(text)
+}
@@ -0,0 +1,187 @@
+package i18n
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestTranslator(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/en.json": {Data: []byte(`{
+	"welcome": "Welcome, {name}!",
+	"apples": { "one": "{count} apple", "other": "{count} apples" },
+	"only_en": "English only"
+}`)},
+		"locales/zh.toml": {Data: []byte(`
+welcome = "欢迎，{name}！"
+[apples]
+other = "{count} 个苹果"
+`)},
+		"locales/ru.json": {Data: []byte(`{
+	"apples": { "one": "{count} яблоко", "few": "{count} яблока", "many": "{count} яблок", "other": "{count} яблока" }
+}`)},
+		"locales/readme.md": {Data: []byte(`ignored`)},
+	}
+
+	tr := New()
+	tr.Load(fsys, nil)
+
+	require.ElementsMatch(t, []string{"en", "zh", "ru"}, tr.Locales())
+
+	t.Run("match_should_work", func(t *testing.T) {
+		require.Equal(t, "zh", tr.Match("zh-TW", "en"))
+		require.Equal(t, "ru", tr.Match("de", "ru-RU"))
+		require.Equal(t, "en", tr.Match("de"))
+		require.Equal(t, "en", tr.Match())
+	})
+
+	t.Run("t_should_work", func(t *testing.T) {
+		require.Equal(t, "Welcome, xun!", tr.T("en", "welcome", "name", "xun"))
+		require.Equal(t, "欢迎，xun！", tr.T("zh-CN", "welcome", map[string]any{"name": "xun"}))
+		require.Equal(t, "English only", tr.T("zh", "only_en"))
+		require.Equal(t, "missing", tr.T("zh", "missing"))
+		require.Equal(t, "{count} apples", tr.T("en", "apples"))
+	})
+
+	t.Run("n_should_work", func(t *testing.T) {
+		require.Equal(t, "1 apple", tr.N("en", "apples", 1))
+		require.Equal(t, "2 apples", tr.N("en-US", "apples", 2))
+		require.Equal(t, "1 个苹果", tr.N("zh", "apples", 1))
+		require.Equal(t, "21 яблоко", tr.N("ru", "apples", 21))
+		require.Equal(t, "3 яблока", tr.N("ru", "apples", 3))
+		require.Equal(t, "11 яблок", tr.N("ru", "apples", 11))
+		require.Equal(t, "1.5 яблока", tr.N("ru", "apples", 1.5))
+		require.Equal(t, "Welcome, xun!", tr.N("en", "welcome", 1, "name", "xun"))
+		require.Equal(t, "missing", tr.N("en", "missing", 1))
+	})
+
+	t.Run("hot_reload_should_work", func(t *testing.T) {
+		fsys["locales/fr.json"] = &fstest.MapFile{Data: []byte(`{"welcome": "Bienvenue, {name} !"}`)}
+		require.NoError(t, tr.FileChanged(fsys, nil, fsnotify.Event{Name: "locales/fr.json", Op: fsnotify.Create}))
+		require.Equal(t, "Bienvenue, xun !", tr.T("fr", "welcome", "name", "xun"))
+
+		fsys["locales/fr.json"] = &fstest.MapFile{Data: []byte(`{"welcome": "Salut, {name} !"}`)}
+		require.NoError(t, tr.FileChanged(fsys, nil, fsnotify.Event{Name: "locales/fr.json", Op: fsnotify.Write}))
+		require.Equal(t, "Salut, xun !", tr.T("fr", "welcome", "name", "xun"))
+
+		fsys["locales/fr.json"] = &fstest.MapFile{Data: []byte(`{`)}
+		require.Error(t, tr.FileChanged(fsys, nil, fsnotify.Event{Name: "locales/fr.json", Op: fsnotify.Write}))
+		require.Equal(t, "Salut, xun !", tr.T("fr", "welcome", "name", "xun"))
+
+		delete(fsys, "locales/fr.json")
+		require.NoError(t, tr.FileChanged(fsys, nil, fsnotify.Event{Name: "locales/fr.json", Op: fsnotify.Remove}))
+		require.Equal(t, "Welcome, xun!", tr.T("fr", "welcome", "name", "xun"))
+
+		require.NoError(t, tr.FileChanged(fsys, nil, fsnotify.Event{Name: "pages/index.html", Op: fsnotify.Write}))
+	})
+
+	t.Run("load_error_should_be_logged", func(t *testing.T) {
+		var logs strings.Builder
+		tr := New(WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+		tr.Load(fstest.MapFS{"locales/en.json": {Data: []byte(`{`)}}, nil)
+
+		require.Empty(t, tr.Locales())
+		require.Contains(t, logs.String(), `msg="i18n: load catalog" path=locales/en.json`)
+	})
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"locales/en.json": {Data: []byte(`{
+	"welcome": "Welcome, {name}!",
+	"items": { "one": "{count} item", "other": "{count} items" }
+}`)},
+		"locales/zh-CN.json": {Data: []byte(`{
+	"welcome": "欢迎，{name}！",
+	"items": { "other": "{count} 件商品" }
+}`)},
+		"pages/index.html": {Data: []byte(`{{ t $ "welcome" "name" "<xun>" }}|{{ tn $ "items" 1 }}|{{ tn . "items" 2 }}|{{ t "zh-CN" "welcome" "name" "xun" }}`)},
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tr := New(WithDefaultLocale("en"))
+
+	app := xun.New(xun.WithMux(mux), xun.WithFsys(fsys),
+		xun.WithTemplateFuncMap(tr.FuncMap()),
+		xun.WithViewEngines(&xun.HtmlViewEngine{}, tr))
+
+	app.Use(tr.Middleware())
+
+	app.Get("/languages", func(c *xun.Context) error {
+		return c.View(Languages(c))
+	})
+
+	app.Start()
+	defer app.Close()
+
+	tests := []struct {
+		name   string
+		setup  func(req *http.Request)
+		expect string
+	}{
+		{
+			name:   "default_locale",
+			expect: "Welcome, &lt;xun&gt;!|1 item|2 items|欢迎，xun！",
+		},
+		{
+			name: "accept_language",
+			setup: func(req *http.Request) {
+				req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+			},
+			expect: "欢迎，&lt;xun&gt;！|1 件商品|2 件商品|欢迎，xun！",
+		},
+		{
+			name: "cookie",
+			setup: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: xun.DefaultLocaleCookie, Value: "zh-CN"})
+				req.Header.Set("Accept-Language", "en")
+			},
+			expect: "欢迎，&lt;xun&gt;！|1 件商品|2 件商品|欢迎，xun！",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", srv.URL+"/", nil)
+			require.NoError(t, err)
+			if test.setup != nil {
+				test.setup(req)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			buf, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			require.Equal(t, test.expect, string(buf))
+		})
+	}
+
+	t.Run("languages_should_start_with_resolved_locale", func(t *testing.T) {
+		req, err := http.NewRequest("GET", srv.URL+"/languages?lang=zh-CN-x", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", "fr")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		var languages []string
+		require.NoError(t, xun.Json.NewDecoder(resp.Body).Decode(&languages))
+		resp.Body.Close()
+
+		require.Equal(t, []string{"zh-CN", "zh-CN-x", "fr"}, languages)
+	})
+}
@@ -0,0 +1,51 @@
+package i18n
+
+import "log/slog"
+
+const (
+	// DefaultDir is the directory in the App's fsys that message catalogs are loaded from.
+	DefaultDir = "locales"
+
+	// DefaultLocale is the locale used when none of the requested locales has a catalog.
+	DefaultLocale = "en"
+)
+
+// Options represents the configuration for the Translator.
+type Options struct {
+	Dir           string
+	DefaultLocale string
+	Logger        *slog.Logger
+}
+
+// Option is a function type that takes a pointer to Options and modifies it.
+// It is used to customize the behavior of the Translator.
+type Option func(o *Options)
+
+// WithDir sets the directory that catalogs are loaded from. Defaults to "locales".
+func WithDir(dir string) Option {
+	return func(o *Options) {
+		if dir != "" {
+			o.Dir = dir
+		}
+	}
+}
+
+// WithDefaultLocale sets the locale that is used when no requested locale has a catalog.
+// Defaults to "en".
+func WithDefaultLocale(locale string) Option {
+	return func(o *Options) {
+		if locale != "" {
+			o.DefaultLocale = locale
+		}
+	}
+}
+
+// WithLogger sets the logger that reports the catalogs that fail to load. If not set,
+// slog.Default() will be used.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *Options) {
+		if l != nil {
+			o.Logger = l
+		}
+	}
+}
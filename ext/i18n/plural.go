@@ -0,0 +1,178 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Plural categories defined by CLDR.
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// PluralRule returns the plural category of a number.
+type PluralRule func(o Operands) string
+
+// Operands are the CLDR plural operands of a number.
+//
+// See https://unicode.org/reports/tr35/tr35-numbers.html#Operands
+type Operands struct {
+	N float64 // absolute value of the source number
+	I int64   // integer digits of n
+	V int     // number of visible fraction digits in n, with trailing zeros
+}
+
+// PluralRules maps a language to its plural rule. Languages without an entry use the English rule.
+// More rules can be added before any Translator is used.
+var PluralRules = map[string]PluralRule{
+	"en": pluralOneOther,
+	"de": pluralOneOther,
+	"nl": pluralOneOther,
+	"sv": pluralOneOther,
+	"it": pluralOneOther,
+	"zh": pluralOther,
+	"ja": pluralOther,
+	"ko": pluralOther,
+	"fr": pluralFrench,
+	"ru": pluralRussian,
+	"uk": pluralRussian,
+	"ar": pluralArabic,
+}
+
+// Plural returns the CLDR plural category of n in the given locale.
+// n can be any integer or floating-point number, or a numeric string such as "1.50".
+func Plural(locale string, n any) string {
+	lang, _, _ := strings.Cut(locale, "-")
+
+	rule, ok := PluralRules[strings.ToLower(lang)]
+	if !ok {
+		rule = pluralOneOther
+	}
+
+	return rule(NewOperands(n))
+}
+
+// NewOperands computes the plural operands of n.
+func NewOperands(n any) Operands {
+	var s string
+	switch v := n.(type) {
+	case int:
+		s = strconv.FormatInt(int64(v), 10)
+	case int8:
+		s = strconv.FormatInt(int64(v), 10)
+	case int16:
+		s = strconv.FormatInt(int64(v), 10)
+	case int32:
+		s = strconv.FormatInt(int64(v), 10)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	case float32:
+		s = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		s = v
+	}
+
+	s = strings.TrimPrefix(s, "-")
+
+	var o Operands
+	o.N, _ = strconv.ParseFloat(s, 64) // nolint: errcheck
+	o.N = math.Abs(o.N)
+
+	i, f, _ := strings.Cut(s, ".")
+	o.I, _ = strconv.ParseInt(i, 10, 64) // nolint: errcheck
+	o.V = len(f)
+
+	return o
+}
+
+// en: one → i = 1 and v = 0
+func pluralOneOther(o Operands) string {
+	if o.I == 1 && o.V == 0 {
+		return One
+	}
+	return Other
+}
+
+// zh, ja, ko: no plural forms
+func pluralOther(Operands) string {
+	return Other
+}
+
+// fr: one → i = 0,1; many → i != 0 and i % 1000000 = 0 and v = 0
+func pluralFrench(o Operands) string {
+	if o.I == 0 || o.I == 1 {
+		return One
+	}
+
+	if o.V == 0 && o.I%1000000 == 0 {
+		return Many
+	}
+
+	return Other
+}
+
+// ru: one → v = 0 and i % 10 = 1 and i % 100 != 11
+//
+//	few  → v = 0 and i % 10 = 2..4 and i % 100 != 12..14
+//	many → v = 0 and (i % 10 = 0 or i % 10 = 5..9 or i % 100 = 11..14)
+func pluralRussian(o Operands) string {
+	if o.V != 0 {
+		return Other
+	}
+
+	i10 := o.I % 10
+	i100 := o.I % 100
+
+	switch {
+	case i10 == 1 && i100 != 11:
+		return One
+	case i10 >= 2 && i10 <= 4 && (i100 < 12 || i100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+// ar: zero → n = 0; one → n = 1; two → n = 2
+//
+//	few  → n % 100 = 3..10
+//	many → n % 100 = 11..99
+func pluralArabic(o Operands) string {
+	if o.V != 0 && o.N != math.Trunc(o.N) {
+		return Other
+	}
+
+	n100 := o.I % 100
+	switch {
+	case o.I == 0:
+		return Zero
+	case o.I == 1:
+		return One
+	case o.I == 2:
+		return Two
+	case n100 >= 3 && n100 <= 10:
+		return Few
+	case n100 >= 11:
+		return Many
+	default:
+		return Other
+	}
+}
@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      any
+		want   string
+	}{
+		{"en", 0, Other},
+		{"en", 1, One},
+		{"en-US", 2, Other},
+		{"en", 1.5, Other},
+		{"en", "1.0", Other},
+		{"en", -1, One},
+
+		{"zh", 1, Other},
+		{"zh-CN", 100, Other},
+
+		{"fr", 0, One},
+		{"fr", 1, One},
+		{"fr", 1.5, One},
+		{"fr", 2, Other},
+		{"fr", 1000000, Many},
+		{"fr", 2000000, Many},
+		{"fr", 1000001, Other},
+
+		{"ru", 1, One},
+		{"ru", 21, One},
+		{"ru", 11, Many},
+		{"ru", 2, Few},
+		{"ru", 24, Few},
+		{"ru", 12, Many},
+		{"ru", 5, Many},
+		{"ru", 0, Many},
+		{"ru", 1.5, Other},
+
+		{"ar", 0, Zero},
+		{"ar", 1, One},
+		{"ar", 2, Two},
+		{"ar", 3, Few},
+		{"ar", 110, Few},
+		{"ar", 11, Many},
+		{"ar", 199, Many},
+		{"ar", 100, Other},
+		{"ar", 102, Other},
+		{"ar", 0.5, Other},
+
+		{"xx", 1, One},
+		{"xx", 3, Other},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.want, Plural(test.locale, test.n), "%s %v", test.locale, test.n)
+	}
+}
+
+func TestOperands(t *testing.T) {
+	o := NewOperands("1.50")
+	require.Equal(t, 1.5, o.N)
+	require.Equal(t, int64(1), o.I)
+	require.Equal(t, 2, o.V)
+
+	o = NewOperands(int64(-25))
+	require.Equal(t, 25.0, o.N)
+	require.Equal(t, int64(25), o.I)
+	require.Equal(t, 0, o.V)
+
+	o = NewOperands(uint8(3))
+	require.Equal(t, int64(3), o.I)
+
+	o = NewOperands(float32(2.5))
+	require.Equal(t, int64(2), o.I)
+	require.Equal(t, 1, o.V)
+}
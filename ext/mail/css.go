@@ -0,0 +1,418 @@
+package mail
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	styleElement = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+	cssComment   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	// simpleSelector is a type selector, an id and classes, e.g. p, .button, a.button or #footer.
+	simpleSelector = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*)?((?:[.#][a-zA-Z_-][a-zA-Z0-9_-]*)*)$`)
+)
+
+// cssRule is a rule of a simple selector.
+type cssRule struct {
+	tag     string
+	id      string
+	classes []string
+	// specificity is ids, classes and types, e.g. 0x010100 for #footer.link
+	specificity int
+	decls       []cssDecl
+}
+
+type cssDecl struct {
+	property  string
+	value     string
+	important bool
+}
+
+// inlineCSS moves the rules of the <style> elements of a html document into the style
+// attributes of the elements that they select, because many mail clients ignore <style>.
+//
+// Only the rules of simple selectors are moved, e.g. p, .button, a.button and #footer. The
+// others, like @media queries and a:hover, are kept in a <style> element where the first one
+// was. The declarations are applied in the order of the cascade, and the declarations of a
+// style attribute win over the rules unless they are !important.
+func inlineCSS(doc string) string {
+	var rules []cssRule
+	var kept strings.Builder
+
+	for _, m := range styleElement.FindAllStringSubmatch(doc, -1) {
+		rules = append(rules, parseCSS(m[1], &kept)...)
+	}
+
+	if len(rules) == 0 {
+		return doc
+	}
+
+	first := true
+	doc = styleElement.ReplaceAllStringFunc(doc, func(string) string {
+		if first && kept.Len() > 0 {
+			first = false
+			return "<style>" + kept.String() + "</style>"
+		}
+		return ""
+	})
+
+	return applyCSS(doc, rules)
+}
+
+// parseCSS returns the rules of simple selectors, and writes the other rules to kept.
+func parseCSS(css string, kept *strings.Builder) []cssRule {
+	css = cssComment.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	for {
+		css = strings.TrimSpace(css)
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			return rules
+		}
+
+		prelude := strings.TrimSpace(css[:open])
+
+		if strings.HasPrefix(prelude, "@") {
+			// an at-rule with a block of rules, e.g. @media
+			end := matchBrace(css, open)
+			kept.WriteString(css[:end])
+			css = css[end:]
+			continue
+		}
+
+		end := strings.IndexByte(css[open:], '}')
+		if end < 0 {
+			return rules
+		}
+		end += open
+
+		block := css[open+1 : end]
+		decls := parseDecls(block)
+		css = css[end+1:]
+
+		var others []string
+		for _, sel := range strings.Split(prelude, ",") {
+			sel = strings.TrimSpace(sel)
+			rule, ok := parseSelector(sel)
+			if !ok {
+				others = append(others, sel)
+				continue
+			}
+			rule.decls = decls
+			rules = append(rules, rule)
+		}
+
+		if len(others) > 0 {
+			kept.WriteString(strings.Join(others, ",") + "{" + strings.TrimSpace(block) + "}")
+		}
+	}
+}
+
+// matchBrace returns the end of the block that starts at open.
+func matchBrace(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(css)
+}
+
+func parseSelector(sel string) (cssRule, bool) {
+	m := simpleSelector.FindStringSubmatch(sel)
+	if m == nil || sel == "" {
+		return cssRule{}, false
+	}
+
+	rule := cssRule{tag: strings.ToLower(m[1])}
+	if rule.tag != "" {
+		rule.specificity++
+	}
+
+	rest := m[2]
+	for rest != "" {
+		next := strings.IndexAny(rest[1:], ".#") + 1
+		if next == 0 {
+			next = len(rest)
+		}
+
+		name := rest[1:next]
+		if rest[0] == '#' {
+			rule.id = name
+			rule.specificity += 0x010000
+		} else {
+			rule.classes = append(rule.classes, name)
+			rule.specificity += 0x000100
+		}
+		rest = rest[next:]
+	}
+
+	return rule, true
+}
+
+func parseDecls(block string) []cssDecl {
+	var decls []cssDecl
+	for _, it := range strings.Split(block, ";") {
+		property, value, ok := strings.Cut(it, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		if !ok || property == "" || value == "" {
+			continue
+		}
+
+		d := cssDecl{property: property, value: value}
+		if i := strings.LastIndex(value, "!"); i >= 0 && strings.EqualFold(strings.TrimSpace(value[i+1:]), "important") {
+			d.value = strings.TrimSpace(value[:i])
+			d.important = true
+		}
+		decls = append(decls, d)
+	}
+	return decls
+}
+
+func (r *cssRule) match(tag, id string, classes []string) bool {
+	if r.tag != "" && r.tag != tag {
+		return false
+	}
+
+	if r.id != "" && r.id != id {
+		return false
+	}
+
+	for _, c := range r.classes {
+		if !slices.Contains(classes, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyCSS writes the declarations of the rules into the start tags of the elements that
+// they select.
+func applyCSS(doc string, rules []cssRule) string {
+	var out strings.Builder
+	out.Grow(len(doc))
+
+	for {
+		i := strings.IndexByte(doc, '<')
+		if i < 0 || i+1 >= len(doc) {
+			out.WriteString(doc)
+			return out.String()
+		}
+
+		out.WriteString(doc[:i])
+		doc = doc[i:]
+
+		if strings.HasPrefix(doc, "<!--") {
+			end := strings.Index(doc, "-->")
+			if end < 0 {
+				out.WriteString(doc)
+				return out.String()
+			}
+			out.WriteString(doc[:end+3])
+			doc = doc[end+3:]
+			continue
+		}
+
+		if !isLetter(doc[1]) {
+			out.WriteByte('<')
+			doc = doc[1:]
+			continue
+		}
+
+		end := tagEnd(doc)
+		tag := doc[:end]
+		doc = doc[end:]
+
+		out.WriteString(styleTag(tag, rules))
+
+		// the content of a kept <style> isn't html
+		name, _ := tagName(tag)
+		if name == "style" || name == "script" {
+			close := strings.Index(strings.ToLower(doc), "</"+name)
+			if close < 0 {
+				close = len(doc)
+			}
+			out.WriteString(doc[:close])
+			doc = doc[close:]
+		}
+	}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// tagEnd returns the end of the start tag at the beginning of doc, skipping the > in quoted
+// attribute values.
+func tagEnd(doc string) int {
+	var quote byte
+	for i := 1; i < len(doc); i++ {
+		c := doc[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(doc)
+}
+
+func tagName(tag string) (string, int) {
+	i := 1
+	for i < len(tag) && (isLetter(tag[i]) || (tag[i] >= '0' && tag[i] <= '9') || tag[i] == '-') {
+		i++
+	}
+	return strings.ToLower(tag[1:i]), i
+}
+
+// tagAttr is an attribute of a start tag, where value is the raw text between start and end.
+type tagAttr struct {
+	name       string
+	value      string
+	start, end int
+}
+
+func tagAttrs(tag string, i int) []tagAttr {
+	var attrs []tagAttr
+	for i < len(tag) {
+		for i < len(tag) && strings.IndexByte(" \t\r\n/>", tag[i]) >= 0 {
+			i++
+		}
+
+		start := i
+		for i < len(tag) && strings.IndexByte(" \t\r\n/>=", tag[i]) < 0 {
+			i++
+		}
+		if i == start {
+			break
+		}
+
+		attr := tagAttr{name: strings.ToLower(tag[start:i]), start: i, end: i}
+		if i < len(tag) && tag[i] == '=' {
+			i++
+			if i < len(tag) && (tag[i] == '"' || tag[i] == '\'') {
+				q := tag[i]
+				end := strings.IndexByte(tag[i+1:], q)
+				if end < 0 {
+					end = len(tag) - i - 1
+				}
+				attr.start, attr.end = i+1, i+1+end
+				i += end + 2
+			} else {
+				attr.start = i
+				for i < len(tag) && strings.IndexByte(" \t\r\n>", tag[i]) < 0 {
+					i++
+				}
+				attr.end = i
+			}
+			attr.value = html.UnescapeString(tag[attr.start:min(attr.end, len(tag))])
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// styleTag returns the start tag with the declarations of the rules that select it.
+func styleTag(tag string, rules []cssRule) string {
+	name, i := tagName(tag)
+	attrs := tagAttrs(tag, i)
+
+	var id string
+	var classes []string
+	style := -1
+	for n, it := range attrs {
+		switch it.name {
+		case "id":
+			id = it.value
+		case "class":
+			classes = strings.Fields(it.value)
+		case "style":
+			style = n
+		}
+	}
+
+	type applied struct {
+		cssDecl
+		specificity, order int
+	}
+
+	var decls []applied
+	for _, r := range rules {
+		if r.match(name, id, classes) {
+			for _, d := range r.decls {
+				decls = append(decls, applied{d, r.specificity, len(decls)})
+			}
+		}
+	}
+
+	if len(decls) == 0 {
+		return tag
+	}
+
+	if style >= 0 {
+		// the style attribute wins over the rules
+		for _, d := range parseDecls(attrs[style].value) {
+			decls = append(decls, applied{d, 0x1000000, len(decls)})
+		}
+	}
+
+	slices.SortStableFunc(decls, func(a, b applied) int {
+		if a.important != b.important {
+			if a.important {
+				return 1
+			}
+			return -1
+		}
+		if a.specificity != b.specificity {
+			return a.specificity - b.specificity
+		}
+		return a.order - b.order
+	})
+
+	var properties []string
+	values := make(map[string]string)
+	for _, d := range decls {
+		if _, ok := values[d.property]; !ok {
+			properties = append(properties, d.property)
+		}
+		values[d.property] = d.value
+	}
+
+	items := make([]string, 0, len(properties))
+	for _, p := range properties {
+		items = append(items, p+": "+values[p])
+	}
+	value := html.EscapeString(strings.Join(items, "; "))
+
+	if style >= 0 {
+		a := attrs[style]
+		if a.end > a.start || tag[a.start-1] == '"' || tag[a.start-1] == '\'' {
+			return tag[:a.start] + value + tag[a.end:]
+		}
+		// a style attribute without a value
+		return tag[:a.start] + `="` + value + `"` + tag[a.end:]
+	}
+
+	end := len(tag) - 1
+	if strings.HasSuffix(tag, "/>") {
+		end--
+	}
+	return strings.TrimRight(tag[:end], " ") + ` style="` + value + `"` + tag[end:]
+}
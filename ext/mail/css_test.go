@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineCSS(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		expected string
+	}{
+		{
+			name:     "no_style",
+			doc:      `<p>Hi</p>`,
+			expected: `<p>Hi</p>`,
+		},
+		{
+			name:     "type_class_id",
+			doc:      `<style>p { color: red } .note { font-size: 12px } #footer { margin: 0 }</style><p class="note">Hi</p><div id="footer"></div>`,
+			expected: `<p class="note" style="color: red; font-size: 12px">Hi</p><div id="footer" style="margin: 0"></div>`,
+		},
+		{
+			name:     "specificity",
+			doc:      `<style>a.button { color: blue } .button { color: green } a { color: red }</style><a class="button">Go</a><a>Back</a>`,
+			expected: `<a class="button" style="color: blue">Go</a><a style="color: red">Back</a>`,
+		},
+		{
+			name:     "source_order",
+			doc:      `<style>.a { color: red } .b { color: blue }</style><p class="b a">Hi</p>`,
+			expected: `<p class="b a" style="color: blue">Hi</p>`,
+		},
+		{
+			name:     "style_attribute_wins",
+			doc:      `<style>p { color: red; margin: 0 }</style><p style="color: blue">Hi</p>`,
+			expected: `<p style="color: blue; margin: 0">Hi</p>`,
+		},
+		{
+			name:     "important",
+			doc:      `<style>p { color: red !important }</style><p style="color: blue">Hi</p>`,
+			expected: `<p style="color: red">Hi</p>`,
+		},
+		{
+			name:     "selector_list",
+			doc:      `<style>h1, h2 { margin: 0 }</style><h1>A</h1><h2>B</h2>`,
+			expected: `<h1 style="margin: 0">A</h1><h2 style="margin: 0">B</h2>`,
+		},
+		{
+			name:     "kept_rules",
+			doc:      `<head><style>/* mail */ p { color: red } a:hover, td p { color: blue } @media (max-width: 600px) { p { width: 100% } }</style></head><p>Hi</p>`,
+			expected: `<head><style>a:hover,td p{color: blue}@media (max-width: 600px) { p { width: 100% } }</style></head><p style="color: red">Hi</p>`,
+		},
+		{
+			name:     "many_style_elements",
+			doc:      `<style>p { color: red }</style><style>a:hover { color: blue }</style><p>Hi</p>`,
+			expected: `<style>a:hover{color: blue}</style><p style="color: red">Hi</p>`,
+		},
+		{
+			name:     "quoted_attributes",
+			doc:      `<style>img { border: 0 }</style><img alt="a > b" src='x.png'/><br>`,
+			expected: `<img alt="a > b" src='x.png' style="border: 0"/><br>`,
+		},
+		{
+			name:     "escaped_value",
+			doc:      `<style>p { font-family: "Helvetica Neue", Arial }</style><p>Hi</p>`,
+			expected: `<p style="font-family: &#34;Helvetica Neue&#34;, Arial">Hi</p>`,
+		},
+		{
+			name:     "comments_and_scripts",
+			doc:      `<style>p { color: red } a:hover { color: blue }</style><!-- <p> --><script>if (a <p) {}</script><p>Hi</p>`,
+			expected: `<style>a:hover{color: blue}</style><!-- <p> --><script>if (a <p) {}</script><p style="color: red">Hi</p>`,
+		},
+		{
+			name:     "text",
+			doc:      `<style>b { color: red }</style>1 < 2 <b>yes</b>`,
+			expected: `1 < 2 <b style="color: red">yes</b>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, inlineCSS(test.doc))
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Package mail sends transactional emails that are rendered from the templates of a xun app.
+//
+// A message is rendered from a pair of templates, the html view and the text template with
+// the same name, e.g. "mail/welcome" renders views/mail/welcome.html and text/mail/welcome.txt.
+// Either of them may be missing. The CSS of the html part is inlined for the mail clients that
+// ignore <style>, and the files of the message are attached from the file system of the mailer.
+//
+// Example usage:
+//
+//	mailer := mail.New(app, &mail.SMTPSender{Addr: "smtp.example.com:587", Username: "u", Password: "p"},
+//		mail.WithFrom("Xun <noreply@example.com>"), mail.WithFsys(fsys))
+//
+//	err := mailer.Send(ctx, &mail.Message{
+//		To:       []string{"Ada <ada@example.com>"},
+//		Template: "mail/welcome",
+//		Data:     user,
+//		Files:    []string{"docs/terms.pdf"},
+//	})
+//
+// The message goes through a Sender: SMTPSender delivers it to a SMTP server, Maildir writes it
+// to a directory for development, and Recorder keeps it in memory for tests.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/yaitoo/xun"
+)
+
+var (
+	// ErrNoSender is returned when a message has no sender and the mailer has no default one.
+	ErrNoSender = errors.New("mail: no sender")
+	// ErrNoRecipients is returned when a message has no recipients.
+	ErrNoRecipients = errors.New("mail: no recipients")
+	// ErrNoBody is returned when a message has neither a text nor a html part.
+	ErrNoBody = errors.New("mail: no text or html part")
+)
+
+// Sender delivers a message, which is encoded as RFC 5322, to the recipients of its envelope.
+// The recipients include the Cc and Bcc of the message.
+type Sender interface {
+	Send(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+// Mailer renders messages from the templates of an App and sends them through a Sender.
+type Mailer struct {
+	app     *xun.App
+	sender  Sender
+	options *Options
+}
+
+// New creates a Mailer that renders the templates of the app and sends through sender.
+func New(app *xun.App, sender Sender, opts ...Option) *Mailer {
+	o := &Options{
+		InlineCSS: true,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Mailer{
+		app:     app,
+		sender:  sender,
+		options: o,
+	}
+}
+
+// Send renders the message and sends it. It is safe to call from any goroutine.
+func (m *Mailer) Send(ctx context.Context, msg *Message) error {
+	from, to, buf, err := m.Compose(msg)
+	if err != nil {
+		return err
+	}
+
+	return m.sender.Send(ctx, from, to, buf)
+}
+
+// Compose renders the message, and returns the address of its sender, the addresses of all its
+// recipients and the message encoded as RFC 5322, without sending it.
+func (m *Mailer) Compose(msg *Message) (string, []string, []byte, error) {
+	// msg is not changed, so it can be sent again
+	it := *msg
+
+	if it.From == "" {
+		it.From = m.options.From
+	}
+
+	if it.Template != "" {
+		if err := m.render(&it); err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	if it.HTML != "" && m.options.InlineCSS {
+		it.HTML = inlineCSS(it.HTML)
+	}
+
+	if it.Subject == "" {
+		it.Subject = titleOf(it.HTML)
+	}
+
+	if len(it.Files) > 0 {
+		it.Attachments = append([]Attachment(nil), it.Attachments...)
+		for _, name := range it.Files {
+			a, err := m.attach(name)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			it.Attachments = append(it.Attachments, a)
+		}
+	}
+
+	return it.encode()
+}
+
+// render renders the parts of the message that it doesn't have from its templates.
+func (m *Mailer) render(msg *Message) error {
+	opts := []xun.RenderOption{xun.WithRenderLocale(msg.Locales...)}
+
+	if msg.HTML == "" {
+		s, err := m.app.RenderHTML("views/"+msg.Template, msg.Data, opts...)
+		if err != nil && !errors.Is(err, xun.ErrViewNotFound) {
+			return fmt.Errorf("mail: %s: %w", msg.Template, err)
+		}
+		msg.HTML = s
+	}
+
+	if msg.Text == "" {
+		s, err := m.app.RenderText("text/"+msg.Template+".txt", msg.Data, opts...)
+		if err != nil && !errors.Is(err, xun.ErrViewNotFound) {
+			return fmt.Errorf("mail: %s: %w", msg.Template, err)
+		}
+		msg.Text = s
+	}
+
+	if msg.HTML == "" && msg.Text == "" {
+		return fmt.Errorf("mail: %s: %w", msg.Template, xun.ErrViewNotFound)
+	}
+
+	return nil
+}
+
+// attach reads a file of the message from the file system of the mailer.
+func (m *Mailer) attach(name string) (Attachment, error) {
+	if m.options.Fsys == nil {
+		return Attachment{}, fmt.Errorf("mail: attach %s: %w", name, fs.ErrNotExist)
+	}
+
+	buf, err := fs.ReadFile(m.options.Fsys, name)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("mail: attach %s: %w", name, err)
+	}
+
+	return Attachment{
+		Filename:    path.Base(name),
+		ContentType: mime.TypeByExtension(path.Ext(name)),
+		Data:        buf,
+	}, nil
+}
+
+var titleTag = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// titleOf returns the text of the <title> element of a html document, which is the subject of
+// a message that doesn't have one.
+func titleOf(doc string) string {
+	m := titleTag.FindStringSubmatch(doc)
+	if m == nil {
+		return ""
+	}
+
+	return strings.Join(strings.Fields(html.UnescapeString(m[1])), " ")
+}
@@ -0,0 +1,283 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun"
+)
+
+func newApp(t *testing.T) *xun.App {
+	fsys := fstest.MapFS{
+		"layouts/mail.html": {Data: []byte(`<html><head><title>{{ block "title" . }}{{ end }}</title><style>p { color: #333; } .button { background: blue; } a:hover { color: red; }</style></head><body>{{ block "content" . }}{{ end }}</body></html>`)},
+		"views/mail/welcome.html": {
+			Data: []byte(`<!--layout:mail-->{{ define "title" }}Welcome, {{ .Data.Name }}{{ end }}{{ define "content" }}<p>Hi {{ .Data.Name }}</p><a class="button" href="https://example.com">Start</a>{{ end }}`),
+		},
+		"views/mail/welcome.fr.html": {
+			Data: []byte(`<!--layout:mail-->{{ define "title" }}Bienvenue, {{ .Data.Name }}{{ end }}{{ define "content" }}<p>Salut {{ .Data.Name }}</p>{{ end }}`),
+		},
+		"text/mail/welcome.txt":    {Data: []byte("Hi {{ .Data.Name }}\nhttps://example.com")},
+		"text/mail/welcome.fr.txt": {Data: []byte("Salut {{ .Data.Name }}")},
+		"views/mail/receipt.html":  {Data: []byte(`<p>Total: {{ .Data.Total }}</p>`)},
+		"text/mail/reset.txt":      {Data: []byte(`Your code is {{ .Data.Code }}`)},
+		"views/mail/broken.html":   {Data: []byte(`<p>{{ .Data.Name.First }}</p>`)},
+	}
+
+	app := xun.New(xun.WithMux(http.NewServeMux()), xun.WithFsys(fsys), xun.WithLocales("fr"), xun.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	t.Cleanup(app.Close)
+
+	return app
+}
+
+type user struct{ Name string }
+
+func TestSend(t *testing.T) {
+	app := newApp(t)
+
+	files := fstest.MapFS{
+		"docs/terms.pdf": {Data: []byte("%PDF-1.4")},
+	}
+
+	rec := &Recorder{}
+	mailer := New(app, rec, WithFrom("Xun <noreply@example.com>"), WithFsys(files))
+
+	t.Run("template", func(t *testing.T) {
+		rec.Reset()
+		err := mailer.Send(context.TODO(), &Message{
+			To:       []string{"Ada <ada@example.com>"},
+			Template: "mail/welcome",
+			Data:     user{Name: "Ada"},
+		})
+		require.NoError(t, err)
+
+		sent := rec.Last()
+		require.NotNil(t, sent)
+		require.Equal(t, "noreply@example.com", sent.From)
+		require.Equal(t, []string{"ada@example.com"}, sent.To)
+
+		msg := sent.Message
+		require.Equal(t, `"Xun" <noreply@example.com>`, msg.From)
+		require.Equal(t, []string{`"Ada" <ada@example.com>`}, msg.To)
+		require.Equal(t, "Welcome, Ada", msg.Subject)
+		require.Equal(t, "Hi Ada\nhttps://example.com", msg.Text)
+		require.Contains(t, msg.HTML, `<p style="color: #333">Hi Ada</p>`)
+		require.Contains(t, msg.HTML, `<a class="button" href="https://example.com" style="background: blue">Start</a>`)
+		// the rules that can't be inlined are kept
+		require.Contains(t, msg.HTML, `<style>a:hover{color: red;}</style>`)
+		require.Empty(t, msg.Attachments)
+		require.NotEmpty(t, msg.Header["Message-Id"])
+		require.NotEmpty(t, msg.Header["Date"])
+		require.Contains(t, string(sent.Raw), "Content-Type: multipart/alternative; boundary=")
+	})
+
+	t.Run("locale", func(t *testing.T) {
+		rec.Reset()
+		err := mailer.Send(context.TODO(), &Message{
+			To:       []string{"ada@example.com"},
+			Template: "mail/welcome",
+			Data:     user{Name: "Ada"},
+			Locales:  []string{"fr-CA", "en"},
+		})
+		require.NoError(t, err)
+
+		msg := rec.Last().Message
+		require.Equal(t, "Bienvenue, Ada", msg.Subject)
+		require.Equal(t, "Salut Ada", msg.Text)
+		require.Contains(t, msg.HTML, `<p style="color: #333">Salut Ada</p>`)
+	})
+
+	t.Run("one_part", func(t *testing.T) {
+		rec.Reset()
+		err := mailer.Send(context.TODO(), &Message{
+			To:       []string{"ada@example.com"},
+			Subject:  "Your receipt",
+			Template: "mail/receipt",
+			Data:     map[string]any{"Total": "€42"},
+		})
+		require.NoError(t, err)
+
+		sent := rec.Last()
+		require.Equal(t, "Your receipt", sent.Message.Subject)
+		require.Equal(t, "<p>Total: €42</p>", sent.Message.HTML)
+		require.Empty(t, sent.Message.Text)
+		require.Contains(t, string(sent.Raw), "Content-Type: text/html; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+		err = mailer.Send(context.TODO(), &Message{
+			To:       []string{"ada@example.com"},
+			Subject:  "Reset your password",
+			Template: "mail/reset",
+			Data:     map[string]any{"Code": "1234"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Your code is 1234", rec.Last().Message.Text)
+		require.Empty(t, rec.Last().Message.HTML)
+	})
+
+	t.Run("recipients", func(t *testing.T) {
+		rec.Reset()
+		err := mailer.Send(context.TODO(), &Message{
+			From:    "billing@example.com",
+			To:      []string{"ada@example.com", "bob@example.com"},
+			Cc:      []string{"Bob <bob@example.com>", "cc@example.com"},
+			Bcc:     []string{"audit@example.com"},
+			ReplyTo: "support@example.com",
+			Subject: "Héllo",
+			Text:    "Hi",
+			Header:  map[string]string{"list-unsubscribe": "<https://example.com/unsubscribe>"},
+		})
+		require.NoError(t, err)
+
+		sent := rec.Last()
+		require.Equal(t, "billing@example.com", sent.From)
+		require.Equal(t, []string{"ada@example.com", "bob@example.com", "cc@example.com", "audit@example.com"}, sent.To)
+		require.NotContains(t, string(sent.Raw), "audit@example.com")
+		require.Contains(t, string(sent.Raw), "Subject: =?utf-8?q?H=C3=A9llo?=\r\n")
+
+		msg := sent.Message
+		require.Equal(t, "<billing@example.com>", msg.From)
+		require.Equal(t, []string{"<ada@example.com>", "<bob@example.com>"}, msg.To)
+		require.Equal(t, []string{`"Bob" <bob@example.com>`, "<cc@example.com>"}, msg.Cc)
+		require.Empty(t, msg.Bcc)
+		require.Equal(t, "<support@example.com>", msg.ReplyTo)
+		require.Equal(t, "Héllo", msg.Subject)
+		require.Equal(t, "<https://example.com/unsubscribe>", msg.Header["List-Unsubscribe"])
+	})
+
+	t.Run("attachments", func(t *testing.T) {
+		rec.Reset()
+		err := mailer.Send(context.TODO(), &Message{
+			To:       []string{"ada@example.com"},
+			Template: "mail/welcome",
+			Data:     user{Name: "Ada"},
+			Files:    []string{"docs/terms.pdf"},
+			Attachments: []Attachment{
+				{Filename: "logo.png", Data: []byte("png"), ContentID: "logo"},
+				{Filename: "data.bin", Data: []byte(strings.Repeat("x", 100))},
+			},
+		})
+		require.NoError(t, err)
+
+		sent := rec.Last()
+		require.Contains(t, string(sent.Raw), "Content-Type: multipart/mixed; boundary=")
+		require.Contains(t, string(sent.Raw), "Content-Type: multipart/related; boundary=")
+		require.Contains(t, string(sent.Raw), "Content-Id: <logo>")
+
+		msg := sent.Message
+		require.Equal(t, "Hi Ada\nhttps://example.com", msg.Text)
+		require.Contains(t, msg.HTML, "Hi Ada")
+		require.Equal(t, []Attachment{
+			{Filename: "logo.png", ContentType: "image/png", Data: []byte("png"), ContentID: "logo"},
+			{Filename: "data.bin", ContentType: "application/octet-stream", Data: []byte(strings.Repeat("x", 100))},
+			{Filename: "terms.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")},
+		}, msg.Attachments)
+	})
+
+	t.Run("without_inline_css", func(t *testing.T) {
+		rec.Reset()
+		m := New(app, rec, WithFrom("noreply@example.com"), WithoutInlineCSS())
+		err := m.Send(context.TODO(), &Message{
+			To:       []string{"ada@example.com"},
+			Template: "mail/welcome",
+			Data:     user{Name: "Ada"},
+		})
+		require.NoError(t, err)
+
+		msg := rec.Last().Message
+		require.Contains(t, msg.HTML, `<style>p { color: #333; }`)
+		require.Contains(t, msg.HTML, `<p>Hi Ada</p>`)
+	})
+
+	t.Run("message_is_not_changed", func(t *testing.T) {
+		msg := &Message{
+			To:       []string{"ada@example.com"},
+			Template: "mail/welcome",
+			Data:     user{Name: "Ada"},
+			Files:    []string{"docs/terms.pdf"},
+		}
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				require.NoError(t, mailer.Send(context.TODO(), msg))
+			}()
+		}
+		wg.Wait()
+
+		require.Empty(t, msg.From)
+		require.Empty(t, msg.Subject)
+		require.Empty(t, msg.HTML)
+		require.Empty(t, msg.Attachments)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := New(app, rec).Send(context.TODO(), &Message{To: []string{"ada@example.com"}, Text: "Hi"})
+		require.ErrorIs(t, err, ErrNoSender)
+
+		err = mailer.Send(context.TODO(), &Message{Text: "Hi"})
+		require.ErrorIs(t, err, ErrNoRecipients)
+
+		err = mailer.Send(context.TODO(), &Message{To: []string{"ada@example.com"}})
+		require.ErrorIs(t, err, ErrNoBody)
+
+		err = mailer.Send(context.TODO(), &Message{To: []string{"ada"}, Text: "Hi"})
+		require.ErrorContains(t, err, "mail: to:")
+
+		err = mailer.Send(context.TODO(), &Message{From: "noreply", To: []string{"ada@example.com"}, Text: "Hi"})
+		require.ErrorContains(t, err, "mail: from:")
+
+		err = mailer.Send(context.TODO(), &Message{
+			To:     []string{"ada@example.com"},
+			Text:   "Hi",
+			Header: map[string]string{"X-Note": "a\r\nBcc: eve@example.com"},
+		})
+		require.ErrorContains(t, err, `mail: invalid header "X-Note"`)
+
+		err = mailer.Send(context.TODO(), &Message{To: []string{"ada@example.com"}, Template: "mail/missing"})
+		require.ErrorIs(t, err, xun.ErrViewNotFound)
+
+		err = mailer.Send(context.TODO(), &Message{To: []string{"ada@example.com"}, Template: "mail/broken", Data: user{Name: "Ada"}})
+		require.ErrorContains(t, err, "mail: mail/broken:")
+
+		err = mailer.Send(context.TODO(), &Message{To: []string{"ada@example.com"}, Text: "Hi", Files: []string{"docs/missing.pdf"}})
+		require.ErrorIs(t, err, fs.ErrNotExist)
+
+		err = New(app, rec, WithFrom("noreply@example.com")).Send(context.TODO(), &Message{To: []string{"ada@example.com"}, Text: "Hi", Files: []string{"docs/terms.pdf"}})
+		require.ErrorIs(t, err, fs.ErrNotExist)
+
+		failed := errors.New("failed")
+		err = New(app, senderFunc(func(context.Context, string, []string, []byte) error { return failed }), WithFrom("noreply@example.com")).
+			Send(context.TODO(), &Message{To: []string{"ada@example.com"}, Text: "Hi"})
+		require.ErrorIs(t, err, failed)
+	})
+}
+
+type senderFunc func(ctx context.Context, from string, to []string, msg []byte) error
+
+func (f senderFunc) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	return f(ctx, from, to, msg)
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte("not a message"))
+	require.ErrorContains(t, err, "mail: parse:")
+
+	msg, err := Parse([]byte("From: ada@example.com\r\nSubject: Hi\r\n\r\nHello\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, "<ada@example.com>", msg.From)
+	require.Equal(t, "Hi", msg.Subject)
+	require.Equal(t, "Hello\n", msg.Text)
+
+	_, err = Parse([]byte("From: ada@example.com\r\nContent-Type: multipart/mixed; boundary=x\r\n\r\n--x\r\nbroken"))
+	require.ErrorContains(t, err, "mail: parse:")
+}
@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var deliveries atomic.Int64
+
+// Maildir writes every message to a maildir for development instead of sending it, where a
+// mail client or a text editor can read it. A message is written to tmp/ and moved to new/, so
+// a reader never sees a partial file. The envelope is prepended as Return-Path and Delivered-To,
+// so the Bcc recipients can be checked too.
+type Maildir struct {
+	// Dir is the root of the maildir. The tmp, new and cur directories are created if they don't exist.
+	Dir string
+}
+
+// Send writes the message to a new file in the maildir.
+func (m *Maildir) Send(_ context.Context, from string, to []string, msg []byte) error {
+	for _, it := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(m.Dir, it), 0o755); err != nil {
+			return fmt.Errorf("mail: maildir: %w", err)
+		}
+	}
+
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+
+	// time.pid_counter.host is unique in a maildir
+	name := strconv.FormatInt(time.Now().Unix(), 10) + "." + strconv.Itoa(os.Getpid()) + "_" +
+		strconv.FormatInt(deliveries.Add(1), 10) + "." + host
+
+	var buf bytes.Buffer
+	buf.WriteString("Return-Path: <" + from + ">\r\n")
+	for _, it := range to {
+		buf.WriteString("Delivered-To: " + it + "\r\n")
+	}
+	buf.Write(msg)
+
+	tmp := filepath.Join(m.Dir, "tmp", name)
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("mail: maildir: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(m.Dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("mail: maildir: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaildir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := New(newApp(t), &Maildir{Dir: dir}, WithFrom("noreply@example.com"))
+
+	for range 2 {
+		err := mailer.Send(context.TODO(), &Message{
+			To:       []string{"ada@example.com"},
+			Bcc:      []string{"audit@example.com"},
+			Template: "mail/welcome",
+			Data:     user{Name: "Ada"},
+		})
+		require.NoError(t, err)
+	}
+
+	for _, it := range []string{"tmp", "cur"} {
+		files, err := os.ReadDir(filepath.Join(dir, it))
+		require.NoError(t, err)
+		require.Empty(t, files)
+	}
+
+	files, err := os.ReadDir(filepath.Join(dir, "new"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.NotEqual(t, files[0].Name(), files[1].Name())
+
+	buf, err := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	require.NoError(t, err)
+
+	msg, err := Parse(buf)
+	require.NoError(t, err)
+	require.Equal(t, "<noreply@example.com>", msg.Header["Return-Path"])
+	require.Equal(t, "ada@example.com", msg.Header["Delivered-To"])
+	require.Equal(t, "Welcome, Ada", msg.Subject)
+	require.Equal(t, "Hi Ada\nhttps://example.com", msg.Text)
+
+	// the Bcc recipients are in the envelope only
+	require.Contains(t, string(buf), "Delivered-To: audit@example.com\r\n")
+
+	// a file is in the way of the maildir
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	err = (&Maildir{Dir: file}).Send(context.TODO(), "noreply@example.com", []string{"ada@example.com"}, buf)
+	require.ErrorContains(t, err, "mail: maildir:")
+}
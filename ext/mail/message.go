@@ -0,0 +1,284 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Message is an email. The parts are rendered from Template unless they are set.
+type Message struct {
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
+	// Subject is the <title> of the html part if it is empty.
+	Subject string
+	// Header holds the extra header fields, e.g. List-Unsubscribe.
+	Header map[string]string
+
+	// Template is the name of the templates of the parts, e.g. "mail/welcome" renders
+	// views/mail/welcome.html and text/mail/welcome.txt with Data.
+	Template string
+	Data     any
+	// Locales picks the locale variants of the templates, e.g. welcome.fr.html.
+	Locales []string
+
+	Text string
+	HTML string
+
+	// Files are attached from the file system of the mailer.
+	Files       []string
+	Attachments []Attachment
+}
+
+// Attachment is a file of a message.
+type Attachment struct {
+	Filename string
+	// ContentType is detected from the extension of Filename if it is empty.
+	ContentType string
+	Data        []byte
+	// ContentID embeds the file in the html part, where it is referenced as "cid:" + ContentID.
+	ContentID string
+}
+
+// entity is a MIME entity, whose body is encoded already.
+type entity struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// encode returns the address of the sender, the addresses of all recipients and the message.
+func (msg *Message) encode() (string, []string, []byte, error) {
+	if msg.From == "" {
+		return "", nil, nil, ErrNoSender
+	}
+
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("mail: from: %w", err)
+	}
+
+	var rcpts []string
+	addresses := func(field string, list []string) (string, error) {
+		items := make([]string, 0, len(list))
+		for _, it := range list {
+			addr, err := mail.ParseAddress(it)
+			if err != nil {
+				return "", fmt.Errorf("mail: %s: %w", strings.ToLower(field), err)
+			}
+			items = append(items, addr.String())
+			if !slices.Contains(rcpts, addr.Address) {
+				rcpts = append(rcpts, addr.Address)
+			}
+		}
+		return strings.Join(items, ", "), nil
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		if value != "" {
+			buf.WriteString(key + ": " + value + "\r\n")
+		}
+	}
+
+	writeHeader("From", from.String())
+
+	for _, it := range []struct {
+		field string
+		list  []string
+	}{{"To", msg.To}, {"Cc", msg.Cc}, {"Bcc", msg.Bcc}} {
+		value, err := addresses(it.field, it.list)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		// the Bcc recipients are only in the envelope
+		if it.field != "Bcc" {
+			writeHeader(it.field, value)
+		}
+	}
+
+	if len(rcpts) == 0 {
+		return "", nil, nil, ErrNoRecipients
+	}
+
+	if msg.ReplyTo != "" {
+		addr, err := mail.ParseAddress(msg.ReplyTo)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("mail: reply-to: %w", err)
+		}
+		writeHeader("Reply-To", addr.String())
+	}
+
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID(from.Address))
+	writeHeader("MIME-Version", "1.0")
+
+	keys := make([]string, 0, len(msg.Header))
+	for k := range msg.Header {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		v := msg.Header[k]
+		if strings.ContainsAny(k, "\r\n: ") || strings.ContainsAny(v, "\r\n") {
+			return "", nil, nil, fmt.Errorf("mail: invalid header %q", k)
+		}
+		writeHeader(textproto.CanonicalMIMEHeaderKey(k), v)
+	}
+
+	body, err := msg.body()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// the root is a text part or a multipart entity, which have no other fields
+	writeHeader("Content-Type", body.header.Get("Content-Type"))
+	writeHeader("Content-Transfer-Encoding", body.header.Get("Content-Transfer-Encoding"))
+	buf.WriteString("\r\n")
+	buf.Write(body.body)
+
+	return from.Address, rcpts, buf.Bytes(), nil
+}
+
+// body returns the root entity of the message:
+//
+//	multipart/mixed
+//	├── multipart/alternative
+//	│   ├── text/plain
+//	│   └── multipart/related
+//	│       ├── text/html
+//	│       └── the embedded files
+//	└── the attached files
+//
+// where a multipart entity that has only one part is replaced by the part.
+func (msg *Message) body() (*entity, error) {
+	var alternative, related, mixed []*entity
+
+	if msg.Text != "" {
+		alternative = append(alternative, textEntity("text/plain", msg.Text))
+	}
+
+	if msg.HTML != "" {
+		related = append(related, textEntity("text/html", msg.HTML))
+	}
+
+	for _, a := range msg.Attachments {
+		if a.ContentID != "" && msg.HTML != "" {
+			related = append(related, a.entity())
+		} else {
+			mixed = append(mixed, a.entity())
+		}
+	}
+
+	if len(alternative) == 0 && len(related) == 0 {
+		return nil, ErrNoBody
+	}
+
+	if len(related) > 0 {
+		alternative = append(alternative, multipartEntity("related", related))
+	}
+
+	mixed = append([]*entity{multipartEntity("alternative", alternative)}, mixed...)
+
+	return multipartEntity("mixed", mixed), nil
+}
+
+func multipartEntity(subtype string, parts []*entity) *entity {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		pw, _ := w.CreatePart(p.header) // nolint: errcheck
+		pw.Write(p.body)                // nolint: errcheck
+	}
+	w.Close() // nolint: errcheck
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", "multipart/"+subtype+"; boundary="+w.Boundary())
+
+	return &entity{header: h, body: buf.Bytes()}
+}
+
+func textEntity(contentType, text string) *entity {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	w.Write([]byte(text)) // nolint: errcheck
+	w.Close()             // nolint: errcheck
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", contentType+"; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	return &entity{header: h, body: buf.Bytes()}
+}
+
+func (a Attachment) entity() *entity {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(a.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	if a.Filename != "" {
+		h.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"name": a.Filename}))
+	} else {
+		h.Set("Content-Type", contentType)
+	}
+	h.Set("Content-Transfer-Encoding", "base64")
+
+	disposition := "attachment"
+	if a.ContentID != "" {
+		disposition = "inline"
+		h.Set("Content-ID", "<"+a.ContentID+">")
+	}
+
+	if a.Filename != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	} else {
+		h.Set("Content-Disposition", disposition)
+	}
+
+	// base64 in lines of 76 characters
+	s := base64.StdEncoding.EncodeToString(a.Data)
+	var buf bytes.Buffer
+	for len(s) > 76 {
+		buf.WriteString(s[:76] + "\r\n")
+		s = s[76:]
+	}
+	buf.WriteString(s + "\r\n")
+
+	return &entity{header: h, body: buf.Bytes()}
+}
+
+// messageID returns a unique Message-ID in the domain of the sender.
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b) // nolint: errcheck
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,38 @@
+package mail
+
+import "io/fs"
+
+// Options represents the configuration for the Mailer.
+type Options struct {
+	// From is the sender of the messages that don't have one.
+	From string
+	// Fsys is the file system that Message.Files are attached from.
+	Fsys fs.FS
+	// InlineCSS moves the <style> rules of the html parts into style attributes. It is on by default.
+	InlineCSS bool
+}
+
+// Option is a function type that takes a pointer to Options and modifies it.
+// It is used to customize the behavior of the Mailer.
+type Option func(o *Options)
+
+// WithFrom sets the sender of the messages that don't have one, e.g. "Xun <noreply@example.com>".
+func WithFrom(from string) Option {
+	return func(o *Options) {
+		o.From = from
+	}
+}
+
+// WithFsys sets the file system that Message.Files are attached from, usually the fsys of the App.
+func WithFsys(fsys fs.FS) Option {
+	return func(o *Options) {
+		o.Fsys = fsys
+	}
+}
+
+// WithoutInlineCSS keeps the <style> elements of the html parts as they are.
+func WithoutInlineCSS() Option {
+	return func(o *Options) {
+		o.InlineCSS = false
+	}
+}
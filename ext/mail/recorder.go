@@ -0,0 +1,212 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"sync"
+)
+
+// Sent is a message that a Recorder has received.
+type Sent struct {
+	// From and To are the envelope, where To includes the Cc and Bcc recipients.
+	From string
+	To   []string
+	// Raw is the message as it is sent.
+	Raw []byte
+	// Message is the parsed Raw.
+	Message *Message
+}
+
+// Recorder keeps the messages in memory instead of sending them, for tests:
+//
+//	rec := &mail.Recorder{}
+//	mailer := mail.New(app, rec)
+//	...
+//	require.Equal(t, "Welcome", rec.Last().Message.Subject)
+type Recorder struct {
+	mu   sync.Mutex
+	sent []*Sent
+}
+
+// Send parses the message and keeps it. It returns the error of a message that can't be parsed.
+func (r *Recorder) Send(_ context.Context, from string, to []string, msg []byte) error {
+	m, err := Parse(msg)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sent = append(r.sent, &Sent{
+		From:    from,
+		To:      append([]string(nil), to...),
+		Raw:     append([]byte(nil), msg...),
+		Message: m,
+	})
+
+	return nil
+}
+
+// Messages returns the messages in the order they are sent.
+func (r *Recorder) Messages() []*Sent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*Sent(nil), r.sent...)
+}
+
+// Last returns the last message, or nil if there is none.
+func (r *Recorder) Last() *Sent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.sent) == 0 {
+		return nil
+	}
+	return r.sent[len(r.sent)-1]
+}
+
+// Reset drops all messages.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sent = nil
+}
+
+// Parse parses a message encoded as RFC 5322, e.g. a file of a Maildir. The text and html
+// parts are decoded with LF line breaks, and the other parts are the attachments. Header
+// holds the header fields that Message has no field for.
+func Parse(raw []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("mail: parse: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	msg := &Message{Header: make(map[string]string)}
+
+	for key, values := range m.Header {
+		switch key {
+		case "From", "Reply-To":
+			addr, err := m.Header.AddressList(key)
+			if err == nil && len(addr) > 0 {
+				if key == "From" {
+					msg.From = addr[0].String()
+				} else {
+					msg.ReplyTo = addr[0].String()
+				}
+			}
+		case "To", "Cc":
+			addr, _ := m.Header.AddressList(key) // nolint: errcheck
+			list := make([]string, 0, len(addr))
+			for _, it := range addr {
+				list = append(list, it.String())
+			}
+			if key == "To" {
+				msg.To = list
+			} else {
+				msg.Cc = list
+			}
+		case "Subject":
+			s, err := dec.DecodeHeader(values[0])
+			if err != nil {
+				s = values[0]
+			}
+			msg.Subject = s
+		case "Content-Type", "Content-Transfer-Encoding", "Mime-Version":
+		default:
+			msg.Header[key] = values[0]
+		}
+	}
+
+	if err := parsePart(msg, m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), "", "", m.Body); err != nil {
+		return nil, fmt.Errorf("mail: parse: %w", err)
+	}
+
+	return msg, nil
+}
+
+func parsePart(msg *Message, contentType, encoding, disposition, contentID string, body io.Reader) error {
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := r.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			// NextPart decodes quoted-printable and removes the header
+			enc := p.Header.Get("Content-Transfer-Encoding")
+			err = parsePart(msg, p.Header.Get("Content-Type"), enc, p.Header.Get("Content-Disposition"), p.Header.Get("Content-ID"), p)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	d, dparams, _ := mime.ParseMediaType(disposition) // nolint: errcheck
+	if d != "attachment" && contentID == "" {
+		// the line breaks are CRLF on the wire
+		text := strings.ReplaceAll(string(buf), "\r\n", "\n")
+		switch mediaType {
+		case "text/plain":
+			if msg.Text == "" {
+				msg.Text = text
+				return nil
+			}
+		case "text/html":
+			if msg.HTML == "" {
+				msg.HTML = text
+				return nil
+			}
+		}
+	}
+
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+
+	msg.Attachments = append(msg.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Data:        buf,
+		ContentID:   strings.Trim(contentID, "<>"),
+	})
+
+	return nil
+}
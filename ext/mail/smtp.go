@@ -0,0 +1,139 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// ErrNoTLS is returned by a SMTPSender that requires TLS when the server doesn't support STARTTLS.
+var ErrNoTLS = errors.New("mail: smtp server doesn't support STARTTLS")
+
+// SMTPSender delivers messages to a SMTP server, e.g. the submission port of a mail provider.
+//
+// The connection is upgraded with STARTTLS when the server supports it, and the sender logs in
+// with PLAIN authentication if it has a Username. net/smtp refuses to send the password over a
+// plain connection to any host but localhost.
+type SMTPSender struct {
+	// Addr is the host:port of the server, e.g. "smtp.example.com:587".
+	Addr     string
+	Username string
+	Password string
+
+	// TLSConfig configures STARTTLS. The ServerName is the host of Addr if it is nil.
+	TLSConfig *tls.Config
+	// RequireTLS refuses to send a message if the server doesn't support STARTTLS.
+	RequireTLS bool
+	// LocalName is the host name that is sent in EHLO, "localhost" by default.
+	LocalName string
+}
+
+// Send delivers a message in a new connection. The deadline of ctx is the deadline of the
+// whole delivery, and cancelling ctx closes the connection.
+func (s *SMTPSender) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("mail: smtp: %w", err)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("mail: smtp: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) // nolint: errcheck
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0)) // nolint: errcheck
+	})
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return smtpError(ctx, err)
+	}
+	defer c.Close()
+
+	if err := s.deliver(c, host, from, to, msg); err != nil {
+		return smtpError(ctx, err)
+	}
+
+	return nil
+}
+
+// smtpError returns the error of ctx instead of the timeout that it caused.
+func smtpError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("mail: smtp: %w", ctx.Err())
+	}
+
+	// the deadline of the connection may pass before ctx is done
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return fmt.Errorf("mail: smtp: %w", context.DeadlineExceeded)
+	}
+
+	return fmt.Errorf("mail: smtp: %w", err)
+}
+
+func (s *SMTPSender) deliver(c *smtp.Client, host, from string, to []string, msg []byte) error {
+	if s.LocalName != "" {
+		if err := c.Hello(s.LocalName); err != nil {
+			return err
+		}
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		cfg := &tls.Config{ServerName: host}
+		if s.TLSConfig != nil {
+			cfg = s.TLSConfig.Clone()
+			if cfg.ServerName == "" {
+				cfg.ServerName = host
+			}
+		}
+
+		if err := c.StartTLS(cfg); err != nil {
+			return err
+		}
+	} else if s.RequireTLS {
+		return ErrNoTLS
+	}
+
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,261 @@
+package mail
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer is a local SMTP stand-in that accepts the messages of a user.
+type smtpServer struct {
+	addr     string
+	tls      *tls.Config
+	username string
+	password string
+
+	mu       sync.Mutex
+	from     string
+	to       []string
+	data     []byte
+	upgraded bool
+}
+
+func newSMTPServer(t *testing.T, tlsConfig *tls.Config) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	s := &smtpServer{addr: l.Addr().String(), tls: tlsConfig, username: "ada", password: "secret"}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 localhost ESMTP") // nolint: errcheck
+
+	secure := false
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if s.tls != nil && !secure {
+				tc.PrintfLine("250-localhost\r\n250-AUTH PLAIN\r\n250 STARTTLS") // nolint: errcheck
+			} else {
+				tc.PrintfLine("250-localhost\r\n250 AUTH PLAIN") // nolint: errcheck
+			}
+		case "STARTTLS":
+			tc.PrintfLine("220 Ready to start TLS") // nolint: errcheck
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tc = textproto.NewConn(conn)
+			secure = true
+			s.mu.Lock()
+			s.upgraded = true
+			s.mu.Unlock()
+		case "AUTH":
+			buf, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN ")) // nolint: errcheck
+			if string(buf) == "\x00"+s.username+"\x00"+s.password {
+				tc.PrintfLine("235 Authentication successful") // nolint: errcheck
+			} else {
+				tc.PrintfLine("535 Authentication failed") // nolint: errcheck
+			}
+		case "MAIL":
+			s.mu.Lock()
+			s.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			s.to = nil
+			s.mu.Unlock()
+			tc.PrintfLine("250 OK") // nolint: errcheck
+		case "RCPT":
+			s.mu.Lock()
+			s.to = append(s.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			s.mu.Unlock()
+			tc.PrintfLine("250 OK") // nolint: errcheck
+		case "DATA":
+			tc.PrintfLine("354 End data with <CR><LF>.<CR><LF>") // nolint: errcheck
+			buf, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data = buf
+			s.mu.Unlock()
+			tc.PrintfLine("250 OK") // nolint: errcheck
+		case "QUIT":
+			tc.PrintfLine("221 Bye") // nolint: errcheck
+			return
+		default:
+			tc.PrintfLine("250 OK") // nolint: errcheck
+		}
+	}
+}
+
+// newTLSConfig returns the config of a server with a self-signed certificate for 127.0.0.1,
+// and the config of a client that trusts it.
+func newTLSConfig(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		&tls.Config{RootCAs: pool}
+}
+
+func TestSMTPSender(t *testing.T) {
+	serverTLS, clientTLS := newTLSConfig(t)
+
+	t.Run("starttls", func(t *testing.T) {
+		srv := newSMTPServer(t, serverTLS)
+
+		mailer := New(newApp(t), &SMTPSender{Addr: srv.addr, Username: "ada", Password: "secret", TLSConfig: clientTLS, RequireTLS: true},
+			WithFrom("noreply@example.com"))
+
+		err := mailer.Send(context.TODO(), &Message{
+			To:       []string{"ada@example.com"},
+			Bcc:      []string{"audit@example.com"},
+			Template: "mail/welcome",
+			Data:     user{Name: "Ada"},
+		})
+		require.NoError(t, err)
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+
+		require.True(t, srv.upgraded)
+		require.Equal(t, "noreply@example.com", srv.from)
+		require.Equal(t, []string{"ada@example.com", "audit@example.com"}, srv.to)
+
+		msg, err := Parse(srv.data)
+		require.NoError(t, err)
+		require.Equal(t, "Welcome, Ada", msg.Subject)
+		require.Equal(t, "Hi Ada\nhttps://example.com", msg.Text)
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		srv := newSMTPServer(t, nil)
+
+		// the password may be sent over a plain connection to localhost only
+		sender := &SMTPSender{Addr: srv.addr, Username: "ada", Password: "secret", LocalName: "xun.local"}
+		err := sender.Send(context.TODO(), "noreply@example.com", []string{"ada@example.com"}, []byte("Subject: Hi\r\n\r\nHi\r\n"))
+		require.NoError(t, err)
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+
+		require.False(t, srv.upgraded)
+		require.Equal(t, "Subject: Hi\r\n\r\nHi\r\n", strings.ReplaceAll(string(srv.data), "\n", "\r\n"))
+	})
+
+	t.Run("require_tls", func(t *testing.T) {
+		srv := newSMTPServer(t, nil)
+
+		sender := &SMTPSender{Addr: srv.addr, RequireTLS: true}
+		err := sender.Send(context.TODO(), "noreply@example.com", []string{"ada@example.com"}, []byte("Subject: Hi\r\n\r\nHi\r\n"))
+		require.ErrorIs(t, err, ErrNoTLS)
+	})
+
+	t.Run("untrusted_certificate", func(t *testing.T) {
+		srv := newSMTPServer(t, serverTLS)
+
+		sender := &SMTPSender{Addr: srv.addr}
+		err := sender.Send(context.TODO(), "noreply@example.com", []string{"ada@example.com"}, []byte("Subject: Hi\r\n\r\nHi\r\n"))
+		require.ErrorContains(t, err, "mail: smtp:")
+		require.ErrorContains(t, err, "certificate")
+	})
+
+	t.Run("bad_password", func(t *testing.T) {
+		srv := newSMTPServer(t, serverTLS)
+
+		sender := &SMTPSender{Addr: srv.addr, Username: "ada", Password: "wrong", TLSConfig: clientTLS}
+		err := sender.Send(context.TODO(), "noreply@example.com", []string{"ada@example.com"}, []byte("Subject: Hi\r\n\r\nHi\r\n"))
+		require.ErrorContains(t, err, "mail: smtp: 535")
+	})
+
+	t.Run("invalid_addr", func(t *testing.T) {
+		err := (&SMTPSender{Addr: "localhost"}).Send(context.TODO(), "noreply@example.com", []string{"ada@example.com"}, nil)
+		require.ErrorContains(t, err, "mail: smtp:")
+	})
+
+	t.Run("context", func(t *testing.T) {
+		// a server that never greets
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		sender := &SMTPSender{Addr: l.Addr().String()}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		err = sender.Send(ctx, "noreply@example.com", []string{"ada@example.com"}, nil)
+		require.ErrorIs(t, err, context.Canceled)
+
+		ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err = sender.Send(ctx, "noreply@example.com", []string{"ada@example.com"}, nil)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
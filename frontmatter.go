@@ -0,0 +1,136 @@
+package xun
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FrontMatter holds the fields declared in the front matter block of a page.
+type FrontMatter map[string]any
+
+// GetString returns the field as a string. If it does not exist or is not a string, it returns an empty string.
+func (fm FrontMatter) GetString(name string) string {
+	s, _ := fm[name].(string)
+	return s
+}
+
+// GetStrings returns the field as a slice of strings. A string field is returned as a slice of one string.
+func (fm FrontMatter) GetStrings(name string) []string {
+	switch v := fm[name].(type) {
+	case []string:
+		return v
+	case string:
+		return []string{v}
+	}
+	return nil
+}
+
+// parseFrontMatter splits the front matter block from the beginning of buf:
+//
+//	---
+//	title: Getting Started
+//	tags: [go, web]
+//	draft: false
+//	---
+//
+// Each line is a `key: value` pair. A value is a string (optionally quoted), a bool,
+// an integer or a [list, of, strings]. Lines starting with # are comments. It returns
+// nil and buf unchanged if buf does not start with a front matter block.
+func parseFrontMatter(buf []byte) (FrontMatter, []byte, error) {
+	const delimiter = "---"
+
+	rest, ok := bytes.CutPrefix(buf, []byte(delimiter+"\n"))
+	if !ok {
+		rest, ok = bytes.CutPrefix(buf, []byte(delimiter+"\r\n"))
+		if !ok {
+			return nil, buf, nil
+		}
+	}
+
+	fm := make(FrontMatter)
+	for n := 2; ; n++ {
+		if len(rest) == 0 {
+			return nil, buf, fmt.Errorf("xun: front matter: missing closing %s", delimiter)
+		}
+
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		text := strings.TrimSpace(string(line))
+
+		if text == delimiter {
+			return fm, rest, nil
+		}
+
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, buf, fmt.Errorf("xun: front matter: line %d: expected key: value", n)
+		}
+
+		v, err := parseFrontMatterValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, buf, fmt.Errorf("xun: front matter: line %d: %w", n, err)
+		}
+
+		fm[key] = v
+	}
+}
+
+func parseFrontMatterValue(value string) (any, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch value[0] {
+	case '"':
+		return strconv.Unquote(value)
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return nil, fmt.Errorf("unterminated string %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case '[':
+		if value[len(value)-1] != ']' {
+			return nil, fmt.Errorf("unterminated list %s", value)
+		}
+
+		items := []string{}
+		for _, it := range strings.Split(value[1:len(value)-1], ",") {
+			it = strings.TrimSpace(it)
+			if it == "" {
+				continue
+			}
+
+			v, err := parseFrontMatterValue(it)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, fmt.Sprint(v))
+		}
+		return items, nil
+	}
+
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	if i, err := strconv.Atoi(value); err == nil {
+		return i, nil
+	}
+
+	// a trailing comment of an unquoted value
+	if i := strings.Index(value, " #"); i >= 0 {
+		return parseFrontMatterValue(strings.TrimSpace(value[:i]))
+	}
+
+	return value, nil
+}
@@ -0,0 +1,77 @@
+package xun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	t.Run("should_work", func(t *testing.T) {
+		fm, body, err := parseFrontMatter([]byte(`---
+# comment
+title: "Hello: \"xun\""
+layout: docs
+name: 'it''s'
+tags: [go, "web", 'x']
+empty: []
+draft: false
+pin: true
+order: 3
+note: plain text # comment
+blank:
+---
+# Body`))
+		require.NoError(t, err)
+		require.Equal(t, "# Body", string(body))
+		require.Equal(t, FrontMatter{
+			"title":  `Hello: "xun"`,
+			"layout": "docs",
+			"name":   "it's",
+			"tags":   []string{"go", "web", "x"},
+			"empty":  []string{},
+			"draft":  false,
+			"pin":    true,
+			"order":  3,
+			"note":   "plain text",
+			"blank":  "",
+		}, fm)
+
+		require.Equal(t, "docs", fm.GetString("layout"))
+		require.Equal(t, "", fm.GetString("order"))
+		require.Equal(t, []string{"go", "web", "x"}, fm.GetStrings("tags"))
+		require.Equal(t, []string{"docs"}, fm.GetStrings("layout"))
+		require.Nil(t, fm.GetStrings("order"))
+	})
+
+	t.Run("crlf_should_work", func(t *testing.T) {
+		fm, body, err := parseFrontMatter([]byte("---\r\ntitle: a\r\n---\r\nbody"))
+		require.NoError(t, err)
+		require.Equal(t, FrontMatter{"title": "a"}, fm)
+		require.Equal(t, "body", string(body))
+	})
+
+	t.Run("no_front_matter", func(t *testing.T) {
+		fm, body, err := parseFrontMatter([]byte("# Title\n---\n"))
+		require.NoError(t, err)
+		require.Nil(t, fm)
+		require.Equal(t, "# Title\n---\n", string(body))
+	})
+
+	t.Run("invalid_should_fail", func(t *testing.T) {
+		for _, src := range []string{
+			"---\ntitle: a\n",
+			"---\ntitle\n---\n",
+			"---\n: a\n---\n",
+			"---\ntitle: \"a\n---\n",
+			"---\ntitle: 'a\n---\n",
+			"---\ntags: [a, b\n---\n",
+			"---\ntags: [\"a]\n---\n",
+		} {
+			fm, body, err := parseFrontMatter([]byte(src))
+			require.Error(t, err, src)
+			require.Nil(t, fm)
+			require.Equal(t, src, string(body))
+		}
+	})
+}
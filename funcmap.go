@@ -1,16 +1,442 @@
 package xun
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"math"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
+// builtins are the functions that every html and text template can use, unless the app is
+// created with WithoutBuiltinFuncs. The functions of WithTemplateFunc and WithTemplateFuncMap
+// take precedence over them.
+//
+// The value that a function works on is its last argument, so it can be piped:
+//
+//	{{ .Title | truncate 60 }}  {{ .Price | number 2 }}  {{ .Name | default "anonymous" }}
 var builtins = template.FuncMap{
 	"upper": strings.ToUpper,
 	"lower": strings.ToLower,
 	"join":  join,
+
+	"dict":     dict,
+	"list":     list,
+	"default":  defaultValue,
+	"coalesce": coalesce,
+	"ternary":  ternary,
+
+	"date":   date,
+	"ago":    ago,
+	"number": number,
+	"bytes":  bytesize,
+
+	"truncate": truncate,
+	"slugify":  slugify,
+	"contains": contains,
+	"seq":      seq,
+
+	"add": add,
+	"sub": sub,
+	"mul": mul,
+	"div": div,
+
+	"json":     toJSON,
+	"safeHTML": safeHTML,
+	"safeURL":  safeURL,
+	"safeJS":   safeJS,
+}
+
+// flush marks where a streaming HtmlViewer flushes the rendered html to the client. It is
+// removed from the output, and ignored when the page is buffered.
+func flush() template.HTML {
+	return flushMarker
 }
 
 func join(sep string, a ...string) string {
 	return strings.Join(a, sep)
 }
+
+// dict returns a map of the key and value pairs, e.g. to pass more than one value to a
+// template: {{ template "card" dict "User" .Data "Wide" true }}.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		k, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[k] = pairs[i+1]
+	}
+
+	return m, nil
+}
+
+// list returns the arguments as a slice.
+func list(items ...any) []any {
+	return items
+}
+
+// defaultValue returns v, or def if v is empty, see isEmpty.
+func defaultValue(def, v any) any {
+	if isEmpty(v) {
+		return def
+	}
+	return v
+}
+
+// coalesce returns the first argument that is not empty, or nil.
+func coalesce(v ...any) any {
+	for _, it := range v {
+		if !isEmpty(it) {
+			return it
+		}
+	}
+	return nil
+}
+
+// ternary returns a if cond is true, otherwise b: {{ .Done | ternary "done" "todo" }}.
+func ternary(a, b any, cond bool) any {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// isEmpty reports whether v is nil, a zero value, or an empty string, slice, map or array.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array, reflect.Chan:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+// timeOf returns the time of a time.Time or a *time.Time.
+func timeOf(name string, v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, nil
+		}
+		return *t, nil
+	default:
+		return time.Time{}, fmt.Errorf("%s: %T is not a time", name, v)
+	}
+}
+
+// date formats the time with the layout of time.Format, e.g. {{ .Created | date "2006-01-02" }}.
+// A zero time is formatted as "".
+func date(layout string, v any) (string, error) {
+	t, err := timeOf("date", v)
+	if err != nil || t.IsZero() {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+// ago formats the time relative to now, e.g. "just now", "5 minutes ago" or "in 2 days".
+// A zero time is formatted as "".
+func ago(v any) (string, error) {
+	t, err := timeOf("ago", v)
+	if err != nil || t.IsZero() {
+		return "", err
+	}
+
+	d := now().Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var n int64
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now", nil
+	case d < time.Hour:
+		n, unit = int64(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int64(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int64(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int64(d/(365*24*time.Hour)), "year"
+	}
+
+	if n > 1 {
+		unit += "s"
+	}
+
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit), nil
+	}
+	return fmt.Sprintf("%d %s ago", n, unit), nil
+}
+
+// number formats a number with thousands separators and the given decimals, e.g.
+// {{ 1234567.891 | number 2 }} is "1,234,567.89".
+func number(decimals int, v any) (string, error) {
+	var s string
+	if i, ok := toInt(v); ok && decimals <= 0 {
+		s = strconv.FormatInt(i, 10)
+	} else if f, ok := toFloat(v); ok {
+		s = strconv.FormatFloat(f, 'f', max(decimals, 0), 64)
+	} else {
+		return "", fmt.Errorf("number: %T is not a number", v)
+	}
+
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+
+	frac := ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, frac = s[:i], s[i:]
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteString(frac)
+
+	return b.String(), nil
+}
+
+// bytesize formats a size in bytes with binary units, e.g. {{ 1536 | bytes }} is "1.5 KiB".
+func bytesize(v any) (string, error) {
+	f, ok := toFloat(v)
+	if !ok {
+		return "", fmt.Errorf("bytes: %T is not a number", v)
+	}
+
+	if math.Abs(f) < 1024 {
+		return strconv.FormatFloat(f, 'f', -1, 64) + " B", nil
+	}
+
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	i := -1
+	for math.Abs(f) >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+
+	s := strconv.FormatFloat(f, 'f', 1, 64)
+	return strings.TrimSuffix(s, ".0") + " " + units[i], nil
+}
+
+// truncate keeps the first n characters of s, and appends "…" if it cuts any.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	if n <= 0 {
+		return ""
+	}
+	return string(r[:n]) + "…"
+}
+
+// slugify returns s in lower case, with every run of characters that are not letters or digits
+// replaced by a hyphen, e.g. "Hello, World!" is "hello-world".
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = true
+			continue
+		}
+
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// contains reports whether the string has the substring, the slice or array has the element,
+// or the map has the key: {{ if .Tags | contains "go" }}.
+func contains(needle, haystack any) (bool, error) {
+	if haystack == nil {
+		return false, nil
+	}
+
+	if s, ok := haystack.(string); ok {
+		sub, ok := needle.(string)
+		if !ok {
+			sub = fmt.Sprint(needle)
+		}
+		return strings.Contains(s, sub), nil
+	}
+
+	rv := reflect.ValueOf(haystack)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if reflect.DeepEqual(rv.Index(i).Interface(), needle) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		k := reflect.ValueOf(needle)
+		if !k.IsValid() || !k.Type().AssignableTo(rv.Type().Key()) {
+			return false, nil
+		}
+		return rv.MapIndex(k).IsValid(), nil
+	default:
+		return false, fmt.Errorf("contains: can't search in %T", haystack)
+	}
+}
+
+// seq returns a sequence of integers like the seq command: seq 3 is 1 2 3, seq 2 4 is 2 3 4,
+// and seq 0 5 10 is 0 5 10 (first, increment, last).
+func seq(args ...int) ([]int, error) {
+	first, step, last := 1, 1, 0
+	switch len(args) {
+	case 1:
+		last = args[0]
+	case 2:
+		first, last = args[0], args[1]
+		if last < first {
+			step = -1
+		}
+	case 3:
+		first, step, last = args[0], args[1], args[2]
+	default:
+		return nil, errors.New("seq: 1 to 3 arguments are required")
+	}
+
+	if step == 0 {
+		return nil, errors.New("seq: increment is zero")
+	}
+
+	var s []int
+	for i := first; (step > 0 && i <= last) || (step < 0 && i >= last); i += step {
+		s = append(s, i)
+	}
+	return s, nil
+}
+
+func add(a, b any) (any, error) {
+	return arith("add", a, b, func(x, y int64) int64 { return x + y }, func(x, y float64) float64 { return x + y })
+}
+
+func sub(a, b any) (any, error) {
+	return arith("sub", a, b, func(x, y int64) int64 { return x - y }, func(x, y float64) float64 { return x - y })
+}
+
+func mul(a, b any) (any, error) {
+	return arith("mul", a, b, func(x, y int64) int64 { return x * y }, func(x, y float64) float64 { return x * y })
+}
+
+// div divides a by b. Integers are divided as integers, like in Go.
+func div(a, b any) (any, error) {
+	if f, ok := toFloat(b); ok && f == 0 {
+		return nil, errors.New("div: division by zero")
+	}
+	return arith("div", a, b, func(x, y int64) int64 { return x / y }, func(x, y float64) float64 { return x / y })
+}
+
+// arith applies the integer operation if both numbers are integers, and the float operation otherwise.
+func arith(name string, a, b any, i func(x, y int64) int64, f func(x, y float64) float64) (any, error) {
+	x, ok1 := toInt(a)
+	y, ok2 := toInt(b)
+	if ok1 && ok2 {
+		return int(i(x, y)), nil
+	}
+
+	fx, ok1 := toFloat(a)
+	fy, ok2 := toFloat(b)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("%s: %T and %T are not numbers", name, a, b)
+	}
+	return f(fx, fy), nil
+}
+
+func toInt(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(rv.Uint()), true // nolint: gosec
+	default:
+		return 0, false
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Float32 || rv.Kind() == reflect.Float64 {
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// toJSON encodes v as JSON. It returns template.JS, so html/template writes it as it is in a
+// <script>, and escapes it as text anywhere else. json.Marshal escapes <, > and &, so the
+// JSON can't close the <script> element.
+func toJSON(v any) (template.JS, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(buf), nil // nolint: gosec
+}
+
+// safeHTML marks s as trusted html, so html/template writes it without escaping. Never pass
+// user input to it: that is a XSS hole.
+func safeHTML(s string) template.HTML {
+	return template.HTML(s) // nolint: gosec
+}
+
+// safeURL marks s as a trusted url, so html/template doesn't replace an unsafe scheme (e.g.
+// javascript:) with #ZgotmplZ. It is still escaped as an attribute value.
+func safeURL(s string) template.URL {
+	return template.URL(s) // nolint: gosec
+}
+
+// safeJS marks s as a trusted JavaScript expression, so html/template writes it in a <script>
+// or an event handler attribute without quoting it as a string.
+func safeJS(s string) template.JS {
+	return template.JS(s) // nolint: gosec
+}
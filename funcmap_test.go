@@ -1,13 +1,16 @@
 package xun
 
 import (
+	htmltemplate "html/template"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"testing/fstest"
 	"text/template"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -99,3 +102,132 @@ func TestFuncMap(t *testing.T) {
 	buf, _ = io.ReadAll(resp.Body)
 	require.Equal(t, "hello world", string(buf))
 }
+
+func TestBuiltinFuncs(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	created := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+
+	data := map[string]any{
+		"Name":    "",
+		"Title":   "Hello, World! Go 1.24",
+		"Tags":    []string{"go", "web"},
+		"Langs":   map[string]int{"go": 1},
+		"Created": created,
+		"Zero":    time.Time{},
+		"Nil":     (*time.Time)(nil),
+		"Done":    true,
+		"User":    map[string]any{"Name": "<Ada>"},
+	}
+
+	tests := []struct {
+		name string
+		tpl  string
+		html string
+		text string
+	}{
+		{name: "dict", tpl: `{{ $d := dict "a" 1 "b" "x" }}{{ $d.a }}{{ $d.b }}`, html: "1x"},
+		{name: "list", tpl: `{{ range list 1 "a" true }}{{ . }},{{ end }}`, html: "1,a,true,"},
+		{name: "default", tpl: `{{ .Name | default "anonymous" }} {{ .Title | default "x" | len }} {{ default 5 0 }}`, html: "anonymous 21 5"},
+		{name: "coalesce", tpl: `{{ coalesce .Name .Missing "first" "second" }}`, html: "first"},
+		{name: "ternary", tpl: `{{ .Done | ternary "done" "todo" }} {{ ternary "done" "todo" false }}`, html: "done todo"},
+		{name: "date", tpl: `{{ .Created | date "2006-01-02 15:04" }}|{{ .Zero | date "2006" }}|{{ .Nil | date "2006" }}`, html: "2024-05-01 08:30||"},
+		{name: "ago", tpl: `{{ ago .Created }}`, html: "9 days ago"},
+		{name: "number", tpl: `{{ 1234567.891 | number 2 }} {{ -1234 | number 0 }} {{ 999 | number 0 }} {{ 1000 | number 1 }}`, html: "1,234,567.89 -1,234 999 1,000.0"},
+		{name: "bytes", tpl: `{{ bytes 512 }} {{ bytes 1536 }} {{ bytes 1048576 }}`, html: "512 B 1.5 KiB 1 MiB"},
+		{name: "truncate", tpl: `{{ .Title | truncate 5 }}|{{ "héllo" | truncate 5 }}|{{ "abc" | truncate 0 }}`, html: "Hello…|héllo|"},
+		{name: "slugify", tpl: `{{ slugify .Title }}|{{ slugify "  Ünïcode -- test " }}`, html: "hello-world-go-1-24|ünïcode-test"},
+		{name: "contains", tpl: `{{ .Title | contains "World" }} {{ .Tags | contains "go" }} {{ .Tags | contains "rust" }} {{ .Langs | contains "go" }} {{ .Langs | contains 1 }}`, html: "true true false true false"},
+		{name: "seq", tpl: `{{ seq 3 }} {{ seq 2 4 }} {{ seq 3 1 }} {{ seq 0 5 10 }} {{ seq 0 }}`, html: "[1 2 3] [2 3 4] [3 2 1] [0 5 10] []"},
+		{name: "arith", tpl: `{{ add 1 2 }} {{ sub 1 2 }} {{ mul 3 4 }} {{ div 7 2 }} {{ div 7.0 2 }} {{ add 1 0.5 }}`, html: "3 -1 12 3 3.5 1.5"},
+		{name: "json", tpl: `<script>var u = {{ json .User }};</script><p>{{ json .Tags }}</p>`,
+			html: `<script>var u = {"Name":"\u003cAda\u003e"};</script><p>[&#34;go&#34;,&#34;web&#34;]</p>`,
+			text: `<script>var u = {"Name":"\u003cAda\u003e"};</script><p>["go","web"]</p>`},
+		{name: "safe", tpl: `{{ safeHTML "<b>x</b>" }}<a href="{{ safeURL "javascript:go()" }}" onclick="{{ safeJS "go()" }}">{{ "<b>" }}</a>`,
+			html: `<b>x</b><a href="javascript:go%28%29" onclick="go()">&lt;b&gt;</a>`,
+			text: `<b>x</b><a href="javascript:go()" onclick="go()"><b></a>`},
+		{name: "core", tpl: `{{ upper "a" }}{{ lower "B" }}{{ join "-" "c" "d" }}`, html: "Abc-d"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf strings.Builder
+			ht := htmltemplate.Must(htmltemplate.New(test.name).Funcs(builtins).Parse(test.tpl))
+			require.NoError(t, ht.Execute(&buf, data))
+			require.Equal(t, test.html, buf.String())
+
+			want := test.text
+			if want == "" {
+				want = test.html
+			}
+
+			buf.Reset()
+			tt := template.Must(template.New(test.name).Funcs(builtins).Parse(test.tpl))
+			require.NoError(t, tt.Execute(&buf, data))
+			require.Equal(t, want, buf.String())
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tpl := range []string{
+			`{{ dict "a" }}`,
+			`{{ dict 1 2 }}`,
+			`{{ date "2006" "yesterday" }}`,
+			`{{ number 2 "x" }}`,
+			`{{ bytes "x" }}`,
+			`{{ div 1 0 }}`,
+			`{{ add 1 "x" }}`,
+			`{{ seq 1 0 5 }}`,
+			`{{ seq }}`,
+			`{{ contains 1 2 }}`,
+		} {
+			tt := template.Must(template.New("errors").Funcs(builtins).Parse(tpl))
+			require.Error(t, tt.Execute(io.Discard, nil), tpl)
+		}
+	})
+
+	t.Run("ago", func(t *testing.T) {
+		at := now()
+		for d, want := range map[time.Duration]string{
+			30 * time.Second:      "just now",
+			-time.Minute:          "in 1 minute",
+			5 * time.Minute:       "5 minutes ago",
+			3 * time.Hour:         "3 hours ago",
+			-48 * time.Hour:       "in 2 days",
+			45 * 24 * time.Hour:   "1 month ago",
+			800 * 24 * time.Hour:  "2 years ago",
+			-400 * 24 * time.Hour: "in 1 year",
+		} {
+			got, err := ago(at.Add(-d))
+			require.NoError(t, err)
+			require.Equal(t, want, got, d.String())
+		}
+	})
+}
+
+func TestWithoutBuiltinFuncs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"text/upper.txt": {Data: []byte(`{{upper "a"}}`)},
+		"text/mine.txt":  {Data: []byte(`{{slugify "A B"}}`)},
+	}
+
+	var logs strings.Builder
+	app := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithoutBuiltinFuncs(),
+		WithTemplateFunc("slugify", strings.ToLower), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	_, ok := app.funcMap["upper"]
+	require.False(t, ok)
+	require.NotNil(t, app.funcMap["flush"])
+
+	problems := app.CheckTemplates()
+	require.Len(t, problems, 1)
+	require.Equal(t, `text/upper.txt:1: function "upper" is not defined`, problems[0].String())
+
+	// the builtins don't override the functions of the app, and the apps don't share them
+	other := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	require.NotNil(t, other.funcMap["upper"])
+	require.Empty(t, other.CheckTemplates())
+
+	require.Equal(t, "a b", app.funcMap["slugify"].(func(string) string)("A B"))
+}
@@ -0,0 +1,113 @@
+package xun
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+// liveReloadPattern is the event stream that the pages listen to in watch mode.
+const liveReloadPattern = "GET /_xun/livereload"
+
+// liveReloadTag is injected before </body> of the html pages in watch mode. It reloads the
+// page on a "reload" event, and only refreshes the stylesheets on a "css" event, so the page
+// keeps its state. EventSource reconnects by itself when the server is restarted.
+var liveReloadTag = []byte(`<script>(function(){` +
+	`var es=new EventSource("/_xun/livereload");` +
+	`es.addEventListener("reload",function(){location.reload()});` +
+	`es.addEventListener("css",function(){document.querySelectorAll('link[rel="stylesheet"]').forEach(function(l){` +
+	`var u=new URL(l.href);u.searchParams.set("_xun",Date.now());l.href=u.href})})` +
+	`})();</script>`)
+
+// liveReload pushes the file changes of watch mode to the pages that are open in the browser.
+type liveReload struct {
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+}
+
+func newLiveReload() *liveReload {
+	return &liveReload{
+		clients: make(map[chan string]struct{}),
+	}
+}
+
+// ServeHTTP streams the events to a page until it is closed.
+func (lr *liveReload) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events := make(chan string, 1)
+
+	lr.mu.Lock()
+	lr.clients[events] = struct{}{}
+	lr.mu.Unlock()
+
+	defer func() {
+		lr.mu.Lock()
+		delete(lr.clients, events)
+		lr.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// reconnect soon after the server is restarted
+	w.Write([]byte("retry: 1000\n\n")) // nolint: errcheck
+	f.Flush()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case name := <-events:
+			if _, err := w.Write([]byte("event: " + name + "\ndata: " + name + "\n\n")); err != nil {
+				return
+			}
+			f.Flush()
+		}
+	}
+}
+
+// notify sends the event of a file change to all pages: "css" for a stylesheet in public/,
+// otherwise "reload". The events that a page hasn't read yet are merged, and "reload" wins.
+func (lr *liveReload) notify(event fsnotify.Event) {
+	name := "reload"
+	if strings.HasPrefix(event.Name, "public/") && strings.EqualFold(filepath.Ext(event.Name), ".css") {
+		name = "css"
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	for c := range lr.clients {
+		if name == "reload" {
+			select {
+			case <-c:
+			default:
+			}
+		}
+
+		select {
+		case c <- name:
+		default:
+		}
+	}
+}
+
+// liveReloadScript returns the script that is injected into the html pages, or nil if the app
+// is not in watch mode.
+func (app *App) liveReloadScript() []byte {
+	if app == nil || app.liveReload == nil {
+		return nil
+	}
+
+	return liveReloadTag
+}
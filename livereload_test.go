@@ -0,0 +1,130 @@
+package xun
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestLiveReload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.html":    {Data: []byte(`<html><body><p>index</p></body></html>`)},
+		"pages/fragment.html": {Data: []byte(`<p>fragment</p>`)},
+		"views/stream.html":   {Data: []byte(`<html><head></head><body>{{ flush }}<p>stream</p></body></html>`)},
+	}
+
+	newServer := func(opts ...Option) (*httptest.Server, *App) {
+		mux := http.NewServeMux()
+		srv := httptest.NewServer(mux)
+
+		opts = append(opts, WithMux(mux), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		app := New(opts...)
+
+		app.Get("/stream", func(c *Context) error {
+			return c.View(nil, "views/stream")
+		}, WithStreaming())
+
+		return srv, app
+	}
+
+	t.Run("off", func(t *testing.T) {
+		srv, app := newServer()
+		defer srv.Close()
+		defer app.Close()
+
+		rw := serve(srv.Config.Handler, http.MethodGet, "/_xun/livereload", "Accept", "text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		rw = serve(srv.Config.Handler, http.MethodGet, "/", "Accept", "text/html")
+		require.Equal(t, `<html><body><p>index</p></body></html>`, rw.Body.String())
+
+		rw = serve(srv.Config.Handler, http.MethodGet, "/stream", "Accept", "text/html")
+		require.Equal(t, `<html><head></head><body><p>stream</p></body></html>`, rw.Body.String())
+	})
+
+	t.Run("inject", func(t *testing.T) {
+		srv, app := newServer(WithWatch())
+		defer srv.Close()
+		defer app.Close()
+
+		rw := serve(srv.Config.Handler, http.MethodGet, "/", "Accept", "text/html")
+		require.Equal(t, `<html><body><p>index</p>`+string(liveReloadTag)+`</body></html>`, rw.Body.String())
+
+		rw = serve(srv.Config.Handler, http.MethodGet, "/stream", "Accept", "text/html")
+		require.Equal(t, `<html><head></head><body><p>stream</p>`+string(liveReloadTag)+`</body></html>`, rw.Body.String())
+
+		// a page without </body> is left as it is
+		rw = serve(srv.Config.Handler, http.MethodGet, "/fragment", "Accept", "text/html")
+		require.Equal(t, `<p>fragment</p>`, rw.Body.String())
+	})
+
+	t.Run("events", func(t *testing.T) {
+		srv, app := newServer(WithWatch())
+		defer srv.Close()
+		defer app.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/_xun/livereload", nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		require.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+		r := bufio.NewReader(resp.Body)
+		next := func() string {
+			var lines []string
+			for {
+				line, err := r.ReadString('\n')
+				require.NoError(t, err)
+				if line == "\n" {
+					return strings.Join(lines, "")
+				}
+				lines = append(lines, line)
+			}
+		}
+
+		// the client is registered before the first message
+		require.Equal(t, "retry: 1000\n", next())
+
+		// the changes go through the hot reload loop
+		app.watcher.Events <- fsnotify.Event{Name: "pages/index.html", Op: fsnotify.Write}
+		require.Equal(t, "event: reload\ndata: reload\n", next())
+
+		app.watcher.Events <- fsnotify.Event{Name: "public/css/app.css", Op: fsnotify.Write}
+		require.Equal(t, "event: css\ndata: css\n", next())
+
+		app.watcher.Events <- fsnotify.Event{Name: "public/app.js", Op: fsnotify.Create}
+		require.Equal(t, "event: reload\ndata: reload\n", next())
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		lr := newLiveReload()
+		c := make(chan string, 1)
+		lr.clients[c] = struct{}{}
+
+		lr.notify(fsnotify.Event{Name: "public/app.css"})
+		lr.notify(fsnotify.Event{Name: "public/app.css"})
+		require.Equal(t, "css", <-c)
+
+		lr.notify(fsnotify.Event{Name: "public/app.css"})
+		lr.notify(fsnotify.Event{Name: "layouts/main.html"})
+		lr.notify(fsnotify.Event{Name: "public/app.css"})
+		require.Equal(t, "reload", <-c)
+		require.Empty(t, c)
+	})
+}
@@ -0,0 +1,67 @@
+package xun
+
+import "strings"
+
+// LoaderFunc loads the data of a page for a request. The data is passed to the page as .Data,
+// and an error goes through the error handling of the route as if a handler returned it.
+type LoaderFunc func(c *Context) (any, error)
+
+// Loader attaches a data loader to the page with the given name, e.g. "admin/dashboard" for
+// pages/admin/dashboard.html, without registering its route again:
+//
+//	app.Loader("admin/dashboard", func(c *Context) (any, error) {
+//		return stats.Load(c.Request.Context())
+//	})
+//
+// The loader runs after the middlewares of the app, for every locale variant of the page. A
+// page without a loader renders its front matter as before. It can be attached before the
+// page is created in watch mode, and attaching another loader to a page replaces it.
+func (app *App) Loader(name string, load LoaderFunc) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	if app.loaders == nil {
+		app.loaders = make(map[string]LoaderFunc)
+	}
+
+	app.loaders[strings.TrimPrefix(name, "pages/")] = load
+}
+
+// pageHandler returns the handler of a page route, which runs the named middlewares that the
+// page declares, and renders the page with the data of its loader and the Cache-Control header
+// that it declares.
+func (app *App) pageHandler(name string, v Viewer) HandleFunc {
+	render := func(c *Context) error {
+		app.mu.RLock()
+		load, ok := app.loaders[name]
+		app.mu.RUnlock()
+
+		var data any
+		if ok {
+			var err error
+			if data, err = load(c); err != nil {
+				return err
+			}
+		}
+
+		if cc := c.Routing.Options.GetString(CacheControl); cc != "" {
+			c.WriteHeader("Cache-Control", cc)
+		}
+
+		return v.Render(c, data)
+	}
+
+	return func(c *Context) error {
+		names, _ := c.Routing.Options.Get(RouteMiddleware).([]string)
+		if len(names) == 0 {
+			return render(c)
+		}
+
+		next, err := app.wrapMiddlewares(names, render)
+		if err != nil {
+			return err
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,128 @@
+package xun
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html":          {Data: []byte(`<main>{{ block "content" . }}{{ end }}</main>`)},
+		"pages/admin/dashboard.html": {Data: []byte(`<!--layout:main-->{{ define "content" }}{{ .TempData.user }}: {{ .Data.Visits }} visits{{ end }}`)},
+		"pages/admin/dashboard.fr.html": {
+			Data: []byte(`<!--layout:main-->{{ define "content" }}{{ .TempData.user }} : {{ .Data.Visits }} visites{{ end }}`),
+		},
+		"pages/users/{id}.html": {Data: []byte(`<p>{{ .Data }}</p>`)},
+		"pages/about.html":      {Data: []byte(`<p>about {{ .Data }}</p>`)},
+		"pages/broken.html":     {Data: []byte(`<p>{{ .Data }}</p>`)},
+		"pages/login.html":      {Data: []byte(`<p>login</p>`)},
+	}
+
+	mux := http.NewServeMux()
+
+	var logs strings.Builder
+	app := New(WithMux(mux), WithFsys(fsys), WithLocales("fr"), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	// the loaders run after the middlewares
+	app.Use(func(next HandleFunc) HandleFunc {
+		return func(c *Context) error {
+			c.Set("user", "ada")
+			return next(c)
+		}
+	})
+
+	app.Loader("admin/dashboard", func(c *Context) (any, error) {
+		return map[string]int{"Visits": 42}, nil
+	})
+
+	app.Loader("pages/users/{id}", func(c *Context) (any, error) {
+		id := c.Request.PathValue("id")
+		if id == "0" {
+			return nil, NewProblem(http.StatusNotFound, "user 0 is not found")
+		}
+
+		if id == "me" {
+			c.Redirect("/login")
+			return nil, ErrCancelled
+		}
+
+		return "user " + id, nil
+	})
+
+	app.Loader("broken", func(c *Context) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	app.Start()
+	defer app.Close()
+
+	t.Run("data", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/admin/dashboard")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<main>ada: 42 visits</main>", rw.Body.String())
+
+		// the locale variants share the loader of the page
+		rw = serve(mux, http.MethodGet, "/admin/dashboard", "Accept-Language", "fr")
+		require.Equal(t, "<main>ada : 42 visites</main>", rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/users/7")
+		require.Equal(t, "<p>user 7</p>", rw.Body.String())
+
+		// no loader, no data
+		rw = serve(mux, http.MethodGet, "/about")
+		require.Equal(t, "<p>about </p>", rw.Body.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/broken")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.NotEmpty(t, rw.Header().Get("X-Log-Id"))
+		require.Contains(t, logs.String(), "xun: view")
+		require.Contains(t, logs.String(), "boom")
+
+		// a browser gets the problem as a html page
+		rw = serve(mux, http.MethodGet, "/users/0", "Accept", "text/html, application/json")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Equal(t, "text/html; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Contains(t, rw.Body.String(), "<title>404 Not Found</title>")
+		require.Contains(t, rw.Body.String(), "<p>user 0 is not found</p>")
+		require.Contains(t, rw.Body.String(), "<small>"+rw.Header().Get("X-Log-Id")+"</small>")
+
+		rw = serve(mux, http.MethodGet, "/users/0", "Accept", "application/problem+json, text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+		require.Contains(t, rw.Body.String(), "user 0 is not found")
+
+		rw = serve(mux, http.MethodGet, "/users/me")
+		require.Equal(t, http.StatusFound, rw.Code)
+		require.Equal(t, "/login", rw.Header().Get("Location"))
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		// a loader can be attached to a page that doesn't exist yet
+		app.Loader("reports", func(c *Context) (any, error) {
+			return "q3", nil
+		})
+
+		fsys["pages/reports.html"] = &fstest.MapFile{Data: []byte(`<p>{{ .Data }}</p>`)}
+		require.NoError(t, app.engines[1].FileChanged(fsys, app, fsnotify.Event{Name: "pages/reports.html", Op: fsnotify.Create}))
+
+		rw := serve(mux, http.MethodGet, "/reports")
+		require.Equal(t, "<p>q3</p>", rw.Body.String())
+
+		// another loader replaces it
+		app.Loader("reports", func(c *Context) (any, error) {
+			return "q4", nil
+		})
+
+		rw = serve(mux, http.MethodGet, "/reports")
+		require.Equal(t, "<p>q4</p>", rw.Body.String())
+	})
+}
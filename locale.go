@@ -0,0 +1,135 @@
+package xun
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	// LocaleParam is the query parameter that selects the locale of a request, e.g. ?lang=zh-CN.
+	LocaleParam = "lang"
+
+	// DefaultLocaleCookie is the name of the cookie that stores the preferred locale of a client.
+	DefaultLocaleCookie = "lang"
+)
+
+// splitLocale splits the locale suffix off a file name whose extension has already been removed.
+// A suffix is only a locale if it is one of the locales that WithLocales configures, so
+// pages/user.id.html is the page /user.id unless "id" is configured.
+//
+//	about.zh-CN => about, zh-CN
+//	about       => about, ""
+func (app *App) splitLocale(name string) (string, string) {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 || i < strings.LastIndexByte(name, '/') {
+		return name, ""
+	}
+
+	locale := name[i+1:]
+	if !slices.ContainsFunc(app.locales, func(it string) bool {
+		return strings.EqualFold(it, locale)
+	}) {
+		return name, ""
+	}
+
+	return name[:i], locale
+}
+
+// parentLocale removes the last subtag of the locale. It returns an empty string
+// when there is no subtag left.
+//
+//	zh-Hant-TW => zh-Hant => zh => ""
+func parentLocale(locale string) string {
+	i := strings.LastIndexByte(locale, '-')
+	if i < 0 {
+		return ""
+	}
+	return locale[:i]
+}
+
+// lookupLocale returns the best matched variant for the preferred locales.
+//
+// Each preferred locale is tried as is and then with its subtags removed one by one
+// before the next preferred locale is tried, so zh-TW falls back to zh.
+func lookupLocale[T any](preferred []string, variants map[string]T) (string, T, bool) {
+	for _, p := range preferred {
+		for tag := p; tag != ""; tag = parentLocale(tag) {
+			for locale, v := range variants {
+				if strings.EqualFold(locale, tag) {
+					return locale, v, true
+				}
+			}
+		}
+	}
+
+	var zero T
+	return "", zero, false
+}
+
+// firstLocale returns the variant with the lowest locale in lexical order. It is
+// used when neither a default file nor a matched variant exists.
+func firstLocale[T any](variants map[string]T) (string, T, bool) {
+	var zero T
+	if len(variants) == 0 {
+		return "", zero, false
+	}
+
+	keys := make([]string, 0, len(variants))
+	for k := range variants {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys[0], variants[keys[0]], true
+}
+
+// matchLocale reports whether the locale, or a locale that it falls back to, is the given tag.
+//
+//	en-US matches en-US and en
+func matchLocale(locale, tag string) bool {
+	for ; locale != ""; locale = parentLocale(locale) {
+		if strings.EqualFold(locale, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// pickLocale returns the variant of the best matched locale. The default template is the variant
+// of defaultLocale, if it is set, so it wins over the variants of the locales that are less
+// preferred. Without a match it returns the default template with defaultLocale, or the first
+// variant if there is no default template.
+func pickLocale[T comparable](preferred []string, defaultLocale string, def T, variants map[string]T) (string, T) {
+	var zero T
+	if def != zero && defaultLocale != "" {
+		for i, p := range preferred {
+			if matchLocale(p, defaultLocale) {
+				// e.g. an en-US variant still wins over the default template of en for en-US
+				preferred = preferred[:i+1]
+				break
+			}
+		}
+	}
+
+	if locale, v, ok := lookupLocale(preferred, variants); ok {
+		return locale, v
+	}
+
+	if def != zero {
+		return defaultLocale, def
+	}
+
+	locale, v, _ := firstLocale(variants)
+	return locale, v
+}
+
+// writeLocaleHeaders sets Content-Language and Vary headers for a localized response.
+func writeLocaleHeaders(c *Context, locale string) {
+	h := c.Response.Header()
+	h.Add("Vary", "Accept-Language")
+	h.Add("Vary", "Cookie")
+
+	if locale != "" {
+		h.Set("Content-Language", locale)
+	}
+}
@@ -0,0 +1,239 @@
+package xun
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		locale string
+	}{
+		{"pages/about", "pages/about", ""},
+		{"pages/about.zh-CN", "pages/about", "zh-CN"},
+		{"pages/about.fr", "pages/about", "fr"},
+		{"pages/about.zh-Hant-TW", "pages/about", "zh-Hant-TW"},
+		{"pages/about.es-419", "pages/about", "es-419"},
+		{"text/robots", "text/robots", ""},
+		{"text/sitemap.xml", "text/sitemap.xml", ""},
+		{"pages/@abc.com/index", "pages/@abc.com/index", ""},
+		{"pages/v1.2", "pages/v1.2", ""},
+		{"pages/user.id", "pages/user.id", ""},
+		{"pages/about.fil", "pages/about", "fil"},
+		{"pages/about.ZH-cn", "pages/about", "ZH-cn"},
+	}
+
+	app := New(WithMux(http.NewServeMux()), WithLocales("zh-CN", "fr", "zh-Hant-TW", "es_419", "fil"))
+	defer app.Close()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			base, locale := app.splitLocale(test.name)
+			require.Equal(t, test.base, base)
+			require.Equal(t, test.locale, locale)
+		})
+	}
+}
+
+func TestLookupLocale(t *testing.T) {
+	variants := map[string]string{
+		"zh":    "zh",
+		"zh-CN": "zh-CN",
+		"fr":    "fr",
+	}
+
+	tests := []struct {
+		name      string
+		preferred []string
+		locale    string
+		ok        bool
+	}{
+		{"exact", []string{"zh-CN"}, "zh-CN", true},
+		{"case_insensitive", []string{"zh-cn"}, "zh-CN", true},
+		{"fallback_to_language", []string{"zh-TW"}, "zh", true},
+		{"fallback_before_next_preference", []string{"zh-Hant-TW", "fr"}, "zh", true},
+		{"next_preference", []string{"de", "fr-CA"}, "fr", true},
+		{"no_match", []string{"de", "en-US"}, "", false},
+		{"empty", nil, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			locale, v, ok := lookupLocale(test.preferred, variants)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.locale, locale)
+			require.Equal(t, test.locale, v)
+		})
+	}
+}
+
+func TestContextLocales(t *testing.T) {
+	app := New(WithMux(http.NewServeMux()), WithLocaleCookie("locale"))
+
+	r := httptest.NewRequest(http.MethodGet, "/?lang=zh_TW", nil)
+	r.AddCookie(&http.Cookie{Name: "locale", Value: "fr"})
+	r.Header.Set("Accept-Language", "en-US,en;q=0.9,*;q=0.5")
+
+	c := &Context{Request: r, App: app}
+	require.Equal(t, []string{"zh-TW", "fr", "en-US", "en"}, c.Locales())
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	c = &Context{Request: r}
+	require.Empty(t, c.Locales())
+}
+
+func TestLocaleVariants(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html":      {Data: []byte(`<html>{{ block "content" . }}{{ end }}</html>`)},
+		"pages/about.html":       {Data: []byte(`<!--layout:main-->{{ define "content" }}about{{ end }}`)},
+		"pages/about.zh-CN.html": {Data: []byte(`<!--layout:main-->{{ define "content" }}关于{{ end }}`)},
+		"pages/about.fr.html":    {Data: []byte(`<!--layout:main-->{{ define "content" }}à propos{{ end }}`)},
+		"pages/index.zh.html":    {Data: []byte(`首页`)},
+		"pages/index.en.html":    {Data: []byte(`home`)},
+		"pages/contact.html":     {Data: []byte(`contact`)},
+		"pages/user.id.html":     {Data: []byte(`user id`)},
+
+		"views/email/welcome.html":    {Data: []byte(`welcome`)},
+		"views/email/welcome.fr.html": {Data: []byte(`bienvenue`)},
+
+		"text/mail/welcome.txt":    {Data: []byte(`welcome`)},
+		"text/mail/welcome.fr.txt": {Data: []byte(`bienvenue`)},
+	}
+
+	mux := http.NewServeMux()
+
+	app := New(WithMux(mux), WithFsys(fsys), WithLocales("zh-CN", "zh", "en", "fr"))
+
+	app.Get("/welcome", func(c *Context) error {
+		return c.View(nil, "views/email/welcome")
+	})
+
+	app.Get("/welcome.txt", func(c *Context) error {
+		return c.View(nil, "text/mail/welcome.txt")
+	})
+
+	app.Start()
+	defer app.Close()
+
+	t.Run("default_should_be_used_without_preference", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/about", "Accept", "text/html, text/plain")
+		require.Equal(t, "<html>about</html>", rw.Body.String())
+		require.Empty(t, rw.Header().Get("Content-Language"))
+		require.Equal(t, []string{"Accept-Language", "Cookie"}, rw.Header().Values("Vary"))
+	})
+
+	t.Run("accept_language_should_work", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/about", "Accept", "text/html, text/plain", "Accept-Language", "fr-CA,fr;q=0.9,en;q=0.8")
+		require.Equal(t, "<html>à propos</html>", rw.Body.String())
+		require.Equal(t, "fr", rw.Header().Get("Content-Language"))
+	})
+
+	t.Run("query_should_override_cookie_and_accept_language", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/about?lang=zh-CN", "Accept", "text/html, text/plain",
+			"Cookie", DefaultLocaleCookie+"=fr", "Accept-Language", "fr")
+		require.Equal(t, "<html>关于</html>", rw.Body.String())
+		require.Equal(t, "zh-CN", rw.Header().Get("Content-Language"))
+	})
+
+	t.Run("cookie_should_override_accept_language", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/about", "Accept", "text/html, text/plain",
+			"Cookie", DefaultLocaleCookie+"=zh-CN", "Accept-Language", "fr")
+		require.Equal(t, "<html>关于</html>", rw.Body.String())
+		require.Equal(t, "zh-CN", rw.Header().Get("Content-Language"))
+	})
+
+	t.Run("unknown_locale_should_fall_back_to_default", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/about", "Accept", "text/html, text/plain", "Accept-Language", "zh-TW")
+		require.Equal(t, "<html>about</html>", rw.Body.String())
+		require.Empty(t, rw.Header().Get("Content-Language"))
+	})
+
+	t.Run("page_without_default_should_use_variant", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/", "Accept", "text/html, text/plain", "Accept-Language", "zh-TW")
+		require.Equal(t, "首页", rw.Body.String())
+		require.Equal(t, "zh", rw.Header().Get("Content-Language"))
+
+		rw = serve(mux, http.MethodGet, "/", "Accept", "text/html, text/plain", "Accept-Language", "de")
+		require.Equal(t, "home", rw.Body.String())
+		require.Equal(t, "en", rw.Header().Get("Content-Language"))
+	})
+
+	t.Run("page_without_variants_should_not_vary", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/contact", "Accept", "text/html, text/plain", "Accept-Language", "fr")
+		require.Equal(t, "contact", rw.Body.String())
+		require.Empty(t, rw.Header().Get("Content-Language"))
+		require.Empty(t, rw.Header().Values("Vary"))
+	})
+
+	t.Run("named_html_view_should_work", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/welcome", "Accept", "text/html, text/plain", "Accept-Language", "fr")
+		require.Equal(t, "bienvenue", rw.Body.String())
+		require.Equal(t, "fr", rw.Header().Get("Content-Language"))
+	})
+
+	t.Run("named_text_view_should_work", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/welcome.txt?lang=fr", "Accept", "text/html, text/plain")
+		require.Equal(t, "bienvenue", rw.Body.String())
+		require.Equal(t, "fr", rw.Header().Get("Content-Language"))
+
+		rw = serve(mux, http.MethodGet, "/welcome.txt", "Accept", "text/html, text/plain")
+		require.Equal(t, "welcome", rw.Body.String())
+		require.Empty(t, rw.Header().Get("Content-Language"))
+	})
+	t.Run("undeclared_suffix_should_be_a_part_of_name", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/user.id", "Accept", "text/html, text/plain")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "user id", rw.Body.String())
+		require.Empty(t, rw.Header().Get("Content-Language"))
+
+		rw = serve(mux, http.MethodGet, "/user", "Accept", "text/html, text/plain", "Accept-Language", "id")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+}
+
+func TestDefaultLocale(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/home.html":       {Data: []byte(`home`)},
+		"pages/home.fr.html":    {Data: []byte(`accueil`)},
+		"pages/home.en-GB.html": {Data: []byte(`home page`)},
+		"text/hello.txt":        {Data: []byte(`hello`)},
+		"text/hello.fr.txt":     {Data: []byte(`bonjour`)},
+	}
+
+	mux := http.NewServeMux()
+	app := New(WithMux(mux), WithFsys(fsys), WithLocales("fr", "en-GB"), WithDefaultLocale("en"))
+	app.Start()
+	defer app.Close()
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		body           string
+		locale         string
+	}{
+		{"default_should_win_over_less_preferred", "en-US,en;q=0.9,fr;q=0.5", "home", "en"},
+		{"variant_should_win_over_default", "en-GB,en;q=0.9,fr;q=0.5", "home page", "en-GB"},
+		{"more_preferred_should_win_over_default", "fr,en;q=0.9", "accueil", "fr"},
+		{"default_should_be_used_without_match", "de", "home", "en"},
+		{"default_should_be_used_without_preference", "", "home", "en"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rw := serve(mux, http.MethodGet, "/home", "Accept", "text/html", "Accept-Language", test.acceptLanguage)
+
+			require.Equal(t, test.body, rw.Body.String())
+			require.Equal(t, test.locale, rw.Header().Get("Content-Language"))
+		})
+	}
+
+	buf, err := app.RenderText("text/hello.txt", nil, WithRenderLocale("en", "fr"))
+	require.NoError(t, err)
+	require.Equal(t, "hello", buf)
+}
@@ -0,0 +1,355 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// punctuation can be escaped by a backslash.
+const punctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// inline renders the inline content of a block.
+func (r *renderer) inline(text string) { // skipcq: GO-R1005
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch c {
+		case '\\':
+			if i+1 < len(text) && strings.IndexByte(punctuation, text[i+1]) >= 0 {
+				r.buf.WriteString(html.EscapeString(text[i+1 : i+2]))
+				i += 2
+				continue
+			}
+		case '`':
+			if n, ok := r.codeSpan(text[i:]); ok {
+				i += n
+				continue
+			}
+		case '*', '_', '~':
+			if n, ok := r.emphasis(text[i:]); ok {
+				i += n
+				continue
+			}
+		case '!':
+			if i+1 < len(text) && text[i+1] == '[' {
+				if n, ok := r.link(text[i+1:], true); ok {
+					i += n + 1
+					continue
+				}
+			}
+		case '[':
+			if n, ok := r.link(text[i:], false); ok {
+				i += n
+				continue
+			}
+		case '<':
+			if n, ok := r.autolink(text[i:]); ok {
+				i += n
+				continue
+			}
+		}
+
+		// raw html and any other text is escaped
+		j := i + 1
+		for j < len(text) && strings.IndexByte("\\`*_~![<", text[j]) < 0 {
+			j++
+		}
+		r.buf.WriteString(html.EscapeString(text[i:j]))
+		i = j
+	}
+}
+
+func (r *renderer) codeSpan(text string) (int, bool) {
+	n := 0
+	for n < len(text) && text[n] == '`' {
+		n++
+	}
+
+	fence := text[:n]
+	for i := n; i < len(text); {
+		j := strings.Index(text[i:], fence)
+		if j < 0 {
+			return 0, false
+		}
+		j += i
+
+		// the closing fence must be exactly as long as the opening one
+		end := j + n
+		if end < len(text) && text[end] == '`' {
+			for end < len(text) && text[end] == '`' {
+				end++
+			}
+			i = end
+			continue
+		}
+
+		code := strings.ReplaceAll(text[n:j], "\n", " ")
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+
+		r.buf.WriteString("<code>")
+		r.buf.WriteString(html.EscapeString(code))
+		r.buf.WriteString("</code>")
+		return end, true
+	}
+	return 0, false
+}
+
+func (r *renderer) emphasis(text string) (int, bool) {
+	c := text[0]
+	n := 1
+	for n < len(text) && text[n] == c && n < 3 {
+		n++
+	}
+
+	tags := map[int][2]string{
+		1: {"<em>", "</em>"},
+		2: {"<strong>", "</strong>"},
+		3: {"<em><strong>", "</strong></em>"},
+	}
+
+	if c == '~' {
+		if n != 2 {
+			return 0, false
+		}
+		tags[2] = [2]string{"<del>", "</del>"}
+	}
+
+	// the opening delimiter must be followed by a non-space
+	if n >= len(text) || text[n] == ' ' {
+		return 0, false
+	}
+
+	delim := text[:n]
+	for i := n; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+			continue
+		case '`':
+			// code spans take precedence
+			if end, ok := codeSpanEnd(text[i:]); ok {
+				i += end - 1
+				continue
+			}
+		}
+
+		if !strings.HasPrefix(text[i:], delim) || text[i-1] == ' ' {
+			continue
+		}
+
+		end := i + n
+		if end < len(text) && text[end] == c {
+			continue
+		}
+
+		// an underscore inside a word is not emphasis
+		if c == '_' && end < len(text) && isWordChar(text[end]) {
+			continue
+		}
+
+		r.buf.WriteString(tags[n][0])
+		r.inline(text[n:i])
+		r.buf.WriteString(tags[n][1])
+		return end, true
+	}
+
+	return 0, false
+}
+
+func codeSpanEnd(text string) (int, bool) {
+	n := 0
+	for n < len(text) && text[n] == '`' {
+		n++
+	}
+
+	j := strings.Index(text[n:], text[:n])
+	if j < 0 {
+		return 0, false
+	}
+	return n + j + n, true
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// link renders [text](url "title"), or ![alt](src "title") if image is true.
+func (r *renderer) link(text string, image bool) (int, bool) {
+	depth := 0
+	closing := -1
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = i
+			}
+		}
+
+		if closing >= 0 {
+			break
+		}
+	}
+
+	if closing < 0 || closing+1 >= len(text) || text[closing+1] != '(' {
+		return 0, false
+	}
+
+	// the destination may contain balanced parentheses
+	end := -1
+	for i, depth := closing+2, 0; i < len(text) && end < 0; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				end = i
+			}
+			depth--
+		}
+	}
+
+	if end < 0 {
+		return 0, false
+	}
+
+	dest := strings.TrimSpace(text[closing+2 : end])
+	title := ""
+	if n := strings.IndexAny(dest, " \t"); n >= 0 {
+		title = strings.TrimSpace(dest[n:])
+		dest = dest[:n]
+		if len(title) < 2 || !(title[0] == '"' && title[len(title)-1] == '"' || title[0] == '\'' && title[len(title)-1] == '\'') {
+			return 0, false
+		}
+		title = title[1 : len(title)-1]
+	}
+
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	label := text[1:closing]
+
+	if !isSafeURL(dest, image) {
+		// an unsafe link keeps its text only
+		r.inline(label)
+		return end + 1, true
+	}
+
+	if image {
+		r.buf.WriteString(`<img src="`)
+		r.buf.WriteString(escapeURL(unescape(dest)))
+		r.buf.WriteString(`" alt="`)
+		r.buf.WriteString(html.EscapeString(stripInline(label)))
+		r.buf.WriteString(`"`)
+		if title != "" {
+			r.buf.WriteString(` title="` + html.EscapeString(unescape(title)) + `"`)
+		}
+		r.buf.WriteString(">")
+		return end + 1, true
+	}
+
+	r.buf.WriteString(`<a href="`)
+	r.buf.WriteString(escapeURL(unescape(dest)))
+	r.buf.WriteString(`"`)
+	if title != "" {
+		r.buf.WriteString(` title="` + html.EscapeString(unescape(title)) + `"`)
+	}
+	r.buf.WriteString(">")
+	r.inline(label)
+	r.buf.WriteString("</a>")
+	return end + 1, true
+}
+
+func (r *renderer) autolink(text string) (int, bool) {
+	end := strings.IndexByte(text, '>')
+	if end < 0 {
+		return 0, false
+	}
+
+	dest := text[1:end]
+	if strings.ContainsAny(dest, " <") {
+		return 0, false
+	}
+
+	href := dest
+	switch {
+	case strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://"):
+	case strings.Contains(dest, "@") && !strings.Contains(dest, ":"):
+		href = "mailto:" + dest
+	default:
+		return 0, false
+	}
+
+	r.buf.WriteString(`<a href="`)
+	r.buf.WriteString(escapeURL(href))
+	r.buf.WriteString(`">`)
+	r.buf.WriteString(html.EscapeString(dest))
+	r.buf.WriteString("</a>")
+	return end + 1, true
+}
+
+// isSafeURL reports whether a link destination can't run a script. Images can also use
+// data:image/ urls.
+func isSafeURL(dest string, image bool) bool {
+	i := strings.IndexAny(dest, ":/?#")
+	if i < 0 || dest[i] != ':' {
+		// relative url
+		return true
+	}
+
+	scheme := strings.ToLower(dest[:i])
+	switch scheme {
+	case "http", "https", "mailto", "ftp", "tel":
+		return true
+	case "data":
+		return image && strings.HasPrefix(strings.ToLower(dest[i+1:]), "image/")
+	}
+	return false
+}
+
+func escapeURL(u string) string {
+	u = strings.ReplaceAll(u, " ", "%20")
+	return html.EscapeString(u)
+}
+
+// unescape removes the backslashes of escaped punctuation.
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// stripInline returns the plain text of inline content, for heading anchors and image alt.
+func stripInline(text string) string {
+	r := &renderer{}
+	r.inline(text)
+
+	out := r.buf.String()
+	var sb strings.Builder
+	tag := false
+	for _, c := range out {
+		switch {
+		case c == '<':
+			tag = true
+		case c == '>' && tag:
+			tag = false
+		case !tag:
+			sb.WriteRune(c)
+		}
+	}
+	return html.UnescapeString(sb.String())
+}
@@ -0,0 +1,646 @@
+// Package markdown renders a CommonMark subset to HTML without any dependency.
+//
+// It supports ATX headings with anchors, paragraphs, block quotes, ordered and
+// unordered lists, fenced and indented code, thematic breaks, GFM tables, and the
+// inline code, emphasis, strikethrough, links, images, autolinks and line breaks.
+// Raw HTML is escaped rather than passed through, and links with a script scheme
+// are dropped, so the output is safe to embed in a page.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Convert renders the markdown source to HTML.
+func Convert(src []byte) []byte {
+	r := &renderer{slugs: make(map[string]int)}
+
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+
+	// tabs are expanded for the indentation of blocks, but kept in fenced code
+	fence := ""
+	for i, l := range lines {
+		body := strings.TrimLeft(l, " \t")
+		if fence != "" {
+			if strings.HasPrefix(body, fence) && strings.Trim(strings.TrimSpace(body), fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+
+		lines[i] = expandTabs(l)
+		if f, ok := fenceOf(body); ok {
+			fence = f
+		}
+	}
+
+	r.blocks(lines)
+	return r.buf.Bytes()
+}
+
+// Slug returns the anchor id of a heading text: lower case letters and digits, with
+// any other runs of characters replaced by a single '-'.
+func Slug(text string) string {
+	var sb strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(text) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			dash = false
+			sb.WriteRune(c)
+			continue
+		}
+		dash = true
+	}
+	return sb.String()
+}
+
+type renderer struct {
+	buf   bytes.Buffer
+	slugs map[string]int
+}
+
+// blocks renders a sequence of block level lines.
+func (r *renderer) blocks(lines []string) {
+	for i := 0; i < len(lines); {
+		i = r.block(lines, i)
+	}
+}
+
+// block renders the block that starts at lines[i], and returns the index of the next line.
+func (r *renderer) block(lines []string, i int) int { // skipcq: GO-R1005
+	line := lines[i]
+	trimmed := strings.TrimSpace(line)
+	indent := indentOf(line)
+
+	switch {
+	case trimmed == "":
+		return i + 1
+	case indent >= 4:
+		return r.indentedCode(lines, i)
+	}
+
+	body := line[indent:]
+
+	if fence, ok := fenceOf(body); ok {
+		return r.fencedCode(lines, i, indent, fence)
+	}
+
+	if level, text, ok := headingOf(body); ok {
+		r.heading(level, text)
+		return i + 1
+	}
+
+	if isThematicBreak(body) {
+		r.buf.WriteString("<hr>\n")
+		return i + 1
+	}
+
+	if strings.HasPrefix(body, ">") {
+		return r.blockquote(lines, i)
+	}
+
+	if _, _, _, ok := listItemOf(line); ok {
+		return r.list(lines, i)
+	}
+
+	if i+1 < len(lines) && strings.Contains(body, "|") {
+		if aligns, ok := tableDelimiterOf(lines[i+1]); ok && len(splitRow(body)) == len(aligns) {
+			return r.table(lines, i, aligns)
+		}
+	}
+
+	return r.paragraph(lines, i)
+}
+
+func (r *renderer) heading(level int, text string) {
+	slug := Slug(stripInline(text))
+	if n, ok := r.slugs[slug]; ok {
+		r.slugs[slug] = n + 1
+		slug = fmt.Sprintf("%s-%d", slug, n+1)
+	} else {
+		r.slugs[slug] = 0
+	}
+
+	fmt.Fprintf(&r.buf, "<h%d id=\"%s\">", level, html.EscapeString(slug))
+	r.inline(text)
+	fmt.Fprintf(&r.buf, "</h%d>\n", level)
+}
+
+func (r *renderer) indentedCode(lines []string, i int) int {
+	var code []string
+	j := i
+	for ; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j]) == "" {
+			code = append(code, "")
+			continue
+		}
+		if indentOf(lines[j]) < 4 {
+			break
+		}
+		code = append(code, lines[j][4:])
+	}
+
+	// trailing blank lines are not part of the code
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+
+	r.buf.WriteString("<pre><code>")
+	r.buf.WriteString(html.EscapeString(strings.Join(code, "\n") + "\n"))
+	r.buf.WriteString("</code></pre>\n")
+	return j
+}
+
+func (r *renderer) fencedCode(lines []string, i, indent int, fence string) int {
+	info := strings.TrimSpace(lines[i][indent+len(fence):])
+	if n := strings.IndexAny(info, " \t"); n >= 0 {
+		info = info[:n]
+	}
+
+	var code []string
+	j := i + 1
+	for ; j < len(lines); j++ {
+		body := strings.TrimSpace(lines[j])
+		if strings.HasPrefix(body, fence) && strings.Trim(body, fence[:1]) == "" {
+			j++
+			break
+		}
+
+		l := lines[j]
+		// remove the indentation of the opening fence
+		if n := min(indent, indentOf(l)); n > 0 {
+			l = l[n:]
+		}
+		code = append(code, l)
+	}
+
+	r.buf.WriteString("<pre><code")
+	if info != "" {
+		r.buf.WriteString(` class="language-`)
+		r.buf.WriteString(html.EscapeString(unescape(info)))
+		r.buf.WriteString(`"`)
+	}
+	r.buf.WriteString(">")
+	if len(code) > 0 {
+		r.buf.WriteString(html.EscapeString(strings.Join(code, "\n") + "\n"))
+	}
+	r.buf.WriteString("</code></pre>\n")
+	return j
+}
+
+func (r *renderer) blockquote(lines []string, i int) int {
+	var inner []string
+	j := i
+	for ; j < len(lines); j++ {
+		body := strings.TrimSpace(lines[j])
+		if body == "" {
+			break
+		}
+
+		if !strings.HasPrefix(body, ">") {
+			// lazy continuation of a paragraph
+			if len(inner) == 0 || strings.TrimSpace(inner[len(inner)-1]) == "" || !isParagraphLine(lines[j]) {
+				break
+			}
+			inner = append(inner, body)
+			continue
+		}
+
+		body = body[1:]
+		if strings.HasPrefix(body, " ") {
+			body = body[1:]
+		}
+		inner = append(inner, body)
+	}
+
+	r.buf.WriteString("<blockquote>\n")
+	sub := &renderer{slugs: r.slugs}
+	sub.blocks(inner)
+	r.buf.Write(sub.buf.Bytes())
+	r.buf.WriteString("</blockquote>\n")
+	return j
+}
+
+func (r *renderer) list(lines []string, i int) int {
+	first, ordered, start, _ := listItemOf(lines[i])
+	bullet := bulletOf(first)
+
+	type item struct {
+		lines []string
+	}
+
+	var (
+		items []item
+		loose bool
+		j     = i
+	)
+
+	for j < len(lines) {
+		marker, _, _, ok := listItemOf(lines[j])
+		if !ok || bulletOf(marker) != bullet {
+			break
+		}
+
+		width := len(marker)
+		content := []string{lines[j][width:]}
+		j++
+
+		blank := false
+		for ; j < len(lines); j++ {
+			l := lines[j]
+			if strings.TrimSpace(l) == "" {
+				blank = true
+				content = append(content, "")
+				continue
+			}
+
+			if indentOf(l) >= width {
+				if blank {
+					loose = true
+				}
+				blank = false
+				content = append(content, l[width:])
+				continue
+			}
+
+			// lazy continuation of a paragraph
+			if !blank && isParagraphLine(l) {
+				if _, _, _, ok := listItemOf(l); !ok {
+					content = append(content, strings.TrimSpace(l))
+					continue
+				}
+			}
+			break
+		}
+
+		for len(content) > 0 && content[len(content)-1] == "" {
+			content = content[:len(content)-1]
+		}
+
+		if blank && j < len(lines) {
+			if marker, _, _, ok := listItemOf(lines[j]); ok && bulletOf(marker) == bullet {
+				loose = true
+			}
+		}
+
+		items = append(items, item{lines: content})
+	}
+
+	if ordered {
+		if start != 1 {
+			fmt.Fprintf(&r.buf, "<ol start=\"%d\">\n", start)
+		} else {
+			r.buf.WriteString("<ol>\n")
+		}
+	} else {
+		r.buf.WriteString("<ul>\n")
+	}
+
+	for _, it := range items {
+		sub := &renderer{slugs: r.slugs}
+		sub.blocks(it.lines)
+		out := sub.buf.String()
+
+		if !loose {
+			// a tight list item shows its paragraphs without <p>
+			out = strings.ReplaceAll(out, "<p>", "")
+			out = strings.ReplaceAll(out, "</p>\n", "\n")
+		}
+
+		r.buf.WriteString("<li>")
+		r.buf.WriteString(strings.TrimSuffix(out, "\n"))
+		r.buf.WriteString("</li>\n")
+	}
+
+	if ordered {
+		r.buf.WriteString("</ol>\n")
+	} else {
+		r.buf.WriteString("</ul>\n")
+	}
+
+	return j
+}
+
+func (r *renderer) table(lines []string, i int, aligns []string) int {
+	writeRow := func(tag string, cells []string) {
+		r.buf.WriteString("<tr>\n")
+		for n, align := range aligns {
+			r.buf.WriteString("<" + tag)
+			if align != "" {
+				r.buf.WriteString(` style="text-align:` + align + `"`)
+			}
+			r.buf.WriteString(">")
+			if n < len(cells) {
+				r.inline(cells[n])
+			}
+			r.buf.WriteString("</" + tag + ">\n")
+		}
+		r.buf.WriteString("</tr>\n")
+	}
+
+	r.buf.WriteString("<table>\n<thead>\n")
+	writeRow("th", splitRow(strings.TrimSpace(lines[i])))
+	r.buf.WriteString("</thead>\n")
+
+	j := i + 2
+	if j < len(lines) && strings.TrimSpace(lines[j]) != "" {
+		r.buf.WriteString("<tbody>\n")
+		for ; j < len(lines); j++ {
+			l := strings.TrimSpace(lines[j])
+			if l == "" || !strings.Contains(l, "|") {
+				break
+			}
+			writeRow("td", splitRow(l))
+		}
+		r.buf.WriteString("</tbody>\n")
+	}
+
+	r.buf.WriteString("</table>\n")
+	return j
+}
+
+func (r *renderer) paragraph(lines []string, i int) int {
+	var text []string
+	j := i
+	for ; j < len(lines); j++ {
+		if j > i && !isParagraphLine(lines[j]) {
+			break
+		}
+		text = append(text, lines[j])
+	}
+
+	// trailing spaces of the paragraph are not a hard break
+	text[len(text)-1] = strings.TrimRight(text[len(text)-1], " ")
+
+	r.buf.WriteString("<p>")
+	for n, l := range text {
+		if n > 0 {
+			r.buf.WriteString("\n")
+		}
+
+		l = strings.TrimLeft(l, " ")
+		switch {
+		case strings.HasSuffix(l, "  "):
+			r.inline(strings.TrimRight(l, " "))
+			r.buf.WriteString("<br>")
+		case strings.HasSuffix(l, "\\") && n < len(text)-1:
+			r.inline(l[:len(l)-1])
+			r.buf.WriteString("<br>")
+		default:
+			r.inline(l)
+		}
+	}
+	r.buf.WriteString("</p>\n")
+	return j
+}
+
+// isParagraphLine reports whether the line continues a paragraph rather than starting
+// another block.
+func isParagraphLine(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+
+	indent := indentOf(line)
+	if indent >= 4 {
+		return true
+	}
+
+	body := line[indent:]
+	if _, ok := fenceOf(body); ok {
+		return false
+	}
+
+	if _, _, ok := headingOf(body); ok {
+		return false
+	}
+
+	if isThematicBreak(body) || strings.HasPrefix(body, ">") {
+		return false
+	}
+
+	if marker, ordered, start, ok := listItemOf(line); ok {
+		// only a list that starts with 1 and is not empty can interrupt a paragraph
+		if strings.TrimSpace(line[len(marker):]) != "" && (!ordered || start == 1) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func indentOf(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+
+	var sb strings.Builder
+	col := 0
+	for _, c := range line {
+		if c == '\t' {
+			n := 4 - col%4
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		sb.WriteRune(c)
+		col++
+	}
+	return sb.String()
+}
+
+func fenceOf(body string) (string, bool) {
+	for _, c := range []string{"`", "~"} {
+		n := 0
+		for n < len(body) && body[n] == c[0] {
+			n++
+		}
+
+		if n >= 3 {
+			// the info string of a backtick fence can't contain backticks
+			if c == "`" && strings.Contains(body[n:], "`") {
+				return "", false
+			}
+			return body[:n], true
+		}
+	}
+	return "", false
+}
+
+func headingOf(body string) (int, string, bool) {
+	level := 0
+	for level < len(body) && body[level] == '#' {
+		level++
+	}
+
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+
+	rest := body[level:]
+	if rest != "" && rest[0] != ' ' {
+		return 0, "", false
+	}
+
+	rest = strings.TrimSpace(rest)
+
+	// remove the optional closing sequence
+	if n := strings.TrimRight(rest, "#"); n != rest && (n == "" || strings.HasSuffix(n, " ")) {
+		rest = strings.TrimSpace(n)
+	}
+
+	return level, rest, true
+}
+
+func isThematicBreak(body string) bool {
+	body = strings.ReplaceAll(strings.TrimSpace(body), " ", "")
+	if len(body) < 3 {
+		return false
+	}
+
+	c := body[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	return strings.Count(body, string(c)) == len(body)
+}
+
+// listItemOf returns the marker of a list item including its indentation and the spaces
+// after it, whether the list is ordered, and the start number of an ordered list.
+func listItemOf(line string) (string, bool, int, bool) {
+	indent := indentOf(line)
+	if indent >= 4 {
+		return "", false, 0, false
+	}
+
+	body := line[indent:]
+	if body == "" {
+		return "", false, 0, false
+	}
+
+	var (
+		n       int
+		ordered bool
+		start   int
+	)
+
+	switch body[0] {
+	case '-', '*', '+':
+		if isThematicBreak(body) {
+			return "", false, 0, false
+		}
+		n = 1
+	default:
+		for n < len(body) && n < 9 && body[n] >= '0' && body[n] <= '9' {
+			start = start*10 + int(body[n]-'0')
+			n++
+		}
+		if n == 0 || n >= len(body) || (body[n] != '.' && body[n] != ')') {
+			return "", false, 0, false
+		}
+		n++
+		ordered = true
+	}
+
+	if n < len(body) && body[n] != ' ' {
+		return "", false, 0, false
+	}
+
+	spaces := indentOf(body[n:])
+	if spaces > 4 || n+spaces == len(body) {
+		// an empty item or an indented code block in the item
+		spaces = 1
+	}
+
+	width := indent + n + spaces
+	if width > len(line) {
+		width = len(line)
+	}
+
+	return line[:width], ordered, start, true
+}
+
+// bulletOf returns the bullet or the delimiter of a list item marker. A list ends at an
+// item with a different one.
+func bulletOf(marker string) byte {
+	marker = strings.TrimSpace(marker)
+	return marker[len(marker)-1]
+}
+
+func tableDelimiterOf(line string) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.Contains(line, "-") || !strings.Contains(line, "|") {
+		return nil, false
+	}
+
+	cells := splitRow(line)
+	aligns := make([]string, len(cells))
+	for i, c := range cells {
+		c = strings.TrimSpace(c)
+		left := strings.HasPrefix(c, ":")
+		right := strings.HasSuffix(c, ":")
+		c = strings.Trim(c, ":")
+		if c == "" || strings.Trim(c, "-") != "" {
+			return nil, false
+		}
+
+		switch {
+		case left && right:
+			aligns[i] = "center"
+		case left:
+			aligns[i] = "left"
+		case right:
+			aligns[i] = "right"
+		}
+	}
+	return aligns, true
+}
+
+// splitRow splits a table row by pipes that are not escaped or inside code spans.
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+
+	var (
+		cells []string
+		cell  strings.Builder
+		code  bool
+	)
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`':
+			code = !code
+			cell.WriteByte(c)
+		case c == '|' && !code:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+
+	return append(cells, strings.TrimSpace(cell.String()))
+}
@@ -0,0 +1,92 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "headings",
+			src:  "# Hello *World*\n## Hello World ##\n####### seven\n#nospace",
+			want: "<h1 id=\"hello-world\">Hello <em>World</em></h1>\n<h2 id=\"hello-world-1\">Hello World</h2>\n<p>####### seven\n#nospace</p>\n",
+		},
+		{
+			name: "paragraphs",
+			src:  "first  \nsecond\\\nthird\n\nnext",
+			want: "<p>first<br>\nsecond<br>\nthird</p>\n<p>next</p>\n",
+		},
+		{
+			name: "inline",
+			src:  "**bold** *em* ***both*** __b__ _e_ snake_case_name ~~del~~ `a * b` `` a`b `` \\*not\\*",
+			want: "<p><strong>bold</strong> <em>em</em> <em><strong>both</strong></em> <strong>b</strong> <em>e</em> snake_case_name <del>del</del> <code>a * b</code> <code>a`b</code> *not*</p>\n",
+		},
+		{
+			name: "raw_html_should_be_escaped",
+			src:  "<script>alert(1)</script>\n<b onclick=\"x\">b</b> & {{ .Data }}",
+			want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;\n&lt;b onclick=&#34;x&#34;&gt;b&lt;/b&gt; &amp; {{ .Data }}</p>\n",
+		},
+		{
+			name: "links",
+			src:  `[xun](https://github.com/yaitoo/xun "Xun") [docs](/docs/a\_b.md) [wiki](https://en.wikipedia.org/wiki/Go_(language)) [x](javascript:alert(1)) ![logo](logo.png "Logo") ![pixel](data:image/png;base64,AAA) [d](data:text/html,x) <https://go.dev> <me@example.com> <b>`,
+			want: `<p><a href="https://github.com/yaitoo/xun" title="Xun">xun</a> <a href="/docs/a_b.md">docs</a> <a href="https://en.wikipedia.org/wiki/Go_(language)">wiki</a> x <img src="logo.png" alt="logo" title="Logo"> <img src="data:image/png;base64,AAA" alt="pixel"> d <a href="https://go.dev">https://go.dev</a> <a href="mailto:me@example.com">me@example.com</a> &lt;b&gt;</p>` + "\n",
+		},
+		{
+			name: "fenced_code",
+			src:  "```go\nfunc main() {\n\tprintln(\"<x>\")\n}\n```\n~~~\n~~~",
+			want: "<pre><code class=\"language-go\">func main() {\n\tprintln(&#34;&lt;x&gt;&#34;)\n}\n</code></pre>\n<pre><code></code></pre>\n",
+		},
+		{
+			name: "indented_code",
+			src:  "    a < b\n\n    c\n\nd",
+			want: "<pre><code>a &lt; b\n\nc\n</code></pre>\n<p>d</p>\n",
+		},
+		{
+			name: "lists",
+			src:  "- a\n- b\n  - c\n* other\n\n3. three\n4. four\n\npara\n\n1. loose\n\n2. list\n3) next",
+			want: "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul></li>\n</ul>\n<ul>\n<li>other</li>\n</ul>\n<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n<p>para</p>\n<ol>\n<li><p>loose</p></li>\n<li><p>list</p></li>\n</ol>\n<ol start=\"3\">\n<li>next</li>\n</ol>\n",
+		},
+		{
+			name: "blockquote",
+			src:  "> # Title\n> quote\nlazy\n\n> next",
+			want: "<blockquote>\n<h1 id=\"title\">Title</h1>\n<p>quote\nlazy</p>\n</blockquote>\n<blockquote>\n<p>next</p>\n</blockquote>\n",
+		},
+		{
+			name: "thematic_break",
+			src:  "a\n\n---\n* * *",
+			want: "<p>a</p>\n<hr>\n<hr>\n",
+		},
+		{
+			name: "table",
+			src:  "| Name | Value | Note |\n|:-----|------:|:----:|\n| `a\\|b` | 1 | **x** |\n| c | 2 |\n\nafter",
+			want: "<table>\n<thead>\n<tr>\n<th style=\"text-align:left\">Name</th>\n<th style=\"text-align:right\">Value</th>\n<th style=\"text-align:center\">Note</th>\n</tr>\n</thead>\n<tbody>\n" +
+				"<tr>\n<td style=\"text-align:left\"><code>a|b</code></td>\n<td style=\"text-align:right\">1</td>\n<td style=\"text-align:center\"><strong>x</strong></td>\n</tr>\n" +
+				"<tr>\n<td style=\"text-align:left\">c</td>\n<td style=\"text-align:right\">2</td>\n<td style=\"text-align:center\"></td>\n</tr>\n" +
+				"</tbody>\n</table>\n<p>after</p>\n",
+		},
+		{
+			name: "not_table",
+			src:  "a | b\n-- | -- | --",
+			want: "<p>a | b\n-- | -- | --</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, string(Convert([]byte(test.src))))
+		})
+	}
+}
+
+func TestSlug(t *testing.T) {
+	require.Equal(t, "getting-started", Slug("Getting Started!"))
+	require.Equal(t, "what-s-new-in-v1-2", Slug("  What's new in v1.2? "))
+	require.Equal(t, "中文-标题", Slug("中文 标题"))
+	require.Equal(t, "", Slug("!!!"))
+}
@@ -7,6 +7,37 @@ import (
 	"strings"
 )
 
+// FormatParam is the query parameter that selects a representation by name, e.g. ?format=csv.
+const FormatParam = "format"
+
+// Formats maps ?format= values to MIME types. A format that is not listed is
+// looked up as a file extension by mime.TypeByExtension.
+var Formats = map[string]string{
+	"html":   "text/html",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+	"xml":    "text/xml",
+	"txt":    "text/plain",
+	"text":   "text/plain",
+	"csv":    "text/csv",
+	"tsv":    "text/tab-separated-values",
+}
+
+// FormatMimeType returns the MIME type of a ?format= value without parameters,
+// or an empty string if the format is unknown.
+func FormatMimeType(format string) string {
+	format = strings.ToLower(format)
+	mt, ok := Formats[format]
+	if !ok {
+		mt = mime.TypeByExtension("." + format)
+	}
+
+	if i := strings.IndexByte(mt, ';'); i >= 0 {
+		mt = mt[:i]
+	}
+	return strings.TrimSpace(mt)
+}
+
 type MimeType struct {
 	Type    string
 	SubType string
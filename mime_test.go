@@ -2,6 +2,8 @@ package xun
 
 import (
 	"mime"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -103,3 +105,29 @@ func TestMime(t *testing.T) {
 	}
 
 }
+
+func TestFormatMimeType(t *testing.T) {
+	require.Equal(t, "text/csv", FormatMimeType("csv"))
+	require.Equal(t, "text/csv", FormatMimeType("CSV"))
+	require.Equal(t, "application/json", FormatMimeType("json"))
+	require.Equal(t, "text/css", FormatMimeType("css"))
+	require.Equal(t, "", FormatMimeType("unknown"))
+}
+
+func TestWithFormat(t *testing.T) {
+	app := New(WithMux(http.NewServeMux()), WithFormat("Atom", "application/atom+xml"), WithFormat("csv", "text/x-csv"), WithFormat("", "text/plain"))
+	defer app.Close()
+
+	accept := func(query string) []MimeType {
+		c := &Context{Request: httptest.NewRequest(http.MethodGet, "/"+query, nil), App: app}
+		return c.Accept()
+	}
+
+	require.Equal(t, []MimeType{{Type: "application", SubType: "atom+xml"}}, accept("?format=atom"))
+	// the app's formats take precedence over Formats
+	require.Equal(t, []MimeType{{Type: "text", SubType: "x-csv"}}, accept("?format=csv"))
+	require.Equal(t, []MimeType{{Type: "application", SubType: "json"}}, accept("?format=json"))
+
+	_, ok := Formats["atom"]
+	require.False(t, ok)
+}
@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"strings"
 )
 
 // Option is a function that takes a pointer to an App and modifies it.
@@ -25,7 +26,16 @@ func WithMux(mux *http.ServeMux) Option {
 	}
 }
 
-// WithWatch enable hot reload feature, please don't enable it on production. It is not thread-safe.
+// WithWatch enable hot reload feature, please don't enable it on production. The templates are
+// swapped atomically and the registries are locked while they are reloaded, so it is safe to
+// serve requests during a reload, e.g. on a staging environment.
+//
+// It also turns on the error overlay: a failing request that accepts text/html gets a html page
+// with the error chain, the failing template lines, the stack of a panic, the request and the
+// routing, instead of an empty 500.
+//
+// The html pages listen to the /_xun/livereload event stream, and are reloaded in the browser
+// when a file is changed.
 func WithWatch() Option {
 	return func(app *App) {
 		app.watch = true
@@ -103,9 +113,84 @@ func WithTemplateFuncMap(fm template.FuncMap) Option {
 	}
 }
 
+// WithoutBuiltinFuncs leaves the builtin template functions (dict, default, date, truncate,
+// safeHTML, ...) out of the function map, e.g. for an app that has its own functions by the
+// same names. The functions of WithTemplateFunc and WithTemplateFuncMap are kept.
+func WithoutBuiltinFuncs() Option {
+	return func(app *App) {
+		app.noBuiltinFuncs = true
+	}
+}
+
 // WithBuildAssetURL adds a matcher function for identifying assets that need URL processing.
 func WithBuildAssetURL(match func(string) bool) Option {
 	return func(app *App) {
 		app.buildAssetURLs = append(app.buildAssetURLs, match)
 	}
 }
+
+// WithAssetPipeline minifies the css and js files that WithBuildAssetURL fingerprints, builds
+// the bundles that bundles.json declares, and computes the Subresource Integrity hashes of the
+// fingerprinted files for the assetTag function. The bundles are rebuilt when bundles.json or
+// their files are changed in watch mode.
+func WithAssetPipeline() Option {
+	return func(app *App) {
+		app.assetPipeline = true
+	}
+}
+
+// WithETag enables ETag for the content rendered by HtmlViewer, JsonViewer, XmlViewer,
+// TextViewer, and CsvViewer and NdjsonViewer when they buffer a slice, on all routes. A GET request with a matching If-None-Match header gets
+// 304 Not Modified. Use WithRouteETag to enable it on some routes only.
+func WithETag() Option {
+	return func(app *App) {
+		app.etag = true
+	}
+}
+
+// WithFormat maps a ?format= value to a MIME type on the app, e.g. WithFormat("atom", "application/atom+xml").
+// It takes precedence over Formats, which is shared by all apps.
+func WithFormat(name, mimeType string) Option {
+	return func(app *App) {
+		if name == "" || mimeType == "" {
+			return
+		}
+
+		if app.formats == nil {
+			app.formats = make(map[string]string)
+		}
+		app.formats[strings.ToLower(name)] = mimeType
+	}
+}
+
+// WithLocales declares the locales of the variants of pages, views and texts, e.g.
+// pages/about.zh-CN.html. A file name suffix that isn't declared is a part of the name, so
+// pages/user.id.html is the page /user.id. Without it, no file is a locale variant.
+func WithLocales(locales ...string) Option {
+	return func(app *App) {
+		for _, it := range locales {
+			if it != "" {
+				app.locales = append(app.locales, strings.ReplaceAll(it, "_", "-"))
+			}
+		}
+	}
+}
+
+// WithDefaultLocale sets the locale of the default templates, e.g. "en" for pages/about.html
+// next to pages/about.fr.html. A client that prefers it gets the default template before the
+// variants of the locales it prefers less, and its Content-Language is set.
+func WithDefaultLocale(locale string) Option {
+	return func(app *App) {
+		app.defaultLocale = strings.ReplaceAll(locale, "_", "-")
+	}
+}
+
+// WithLocaleCookie sets the name of the cookie that stores the preferred locale of a client.
+// If not set, it will use DefaultLocaleCookie.
+func WithLocaleCookie(name string) Option {
+	return func(app *App) {
+		if name != "" {
+			app.localeCookie = name
+		}
+	}
+}
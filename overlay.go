@@ -0,0 +1,247 @@
+package xun
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// overlayContext is the number of lines that the overlay shows before and after the failing
+// line of a template.
+const overlayContext = 3
+
+// overlayRedactedHeaders are the request headers whose values the overlay doesn't show, because
+// they carry credentials.
+var overlayRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// writeOverlay writes the error of a request as a html page in watch mode (see WithWatch), so a
+// developer sees it in the browser instead of the logs. It reports false without writing anything
+// when the app is not in watch mode, the client doesn't ask for html, or the response has a body
+// already. The stack is the one of a recovered panic; a returned error has none.
+func (app *App) writeOverlay(ctx *Context, err error, stack []byte, logID string) bool {
+	if !app.watch || ctx.Response.BodyBytesSent() > 0 ||
+		!strings.Contains(ctx.Request.Header.Get("Accept"), "text/html") {
+		return false
+	}
+
+	o := overlay{
+		LogID:   logID,
+		Request: ctx.Request,
+		Pattern: ctx.Routing.Pattern,
+		Stack:   string(stack),
+		Script:  template.HTML(app.liveReloadScript()), // nolint: gosec
+	}
+
+	o.Chain = errorChain(err)
+	o.Source = app.templateSource(err.Error())
+
+	for k, v := range ctx.Request.Header {
+		if slices.Contains(overlayRedactedHeaders, k) {
+			o.Headers = append(o.Headers, [2]string{k, "[redacted]"})
+			continue
+		}
+		o.Headers = append(o.Headers, [2]string{k, strings.Join(v, ", ")})
+	}
+	slices.SortFunc(o.Headers, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+
+	for _, v := range ctx.Routing.Viewers {
+		o.Viewers = append(o.Viewers, v.MimeType().String())
+	}
+
+	if ctx.Routing.Options != nil {
+		for k, v := range ctx.Routing.Options.metadata {
+			o.Metadata = append(o.Metadata, [2]string{k, fmt.Sprint(v)})
+		}
+		slices.SortFunc(o.Metadata, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := overlayTemplate.Execute(buf, o); err != nil {
+		app.logger.Error("xun: overlay", slog.Any("err", err), slog.String("logid", logID))
+		return false
+	}
+
+	ctx.WriteHeader("Content-Type", "text/html; charset=utf-8")
+	ctx.WriteStatus(http.StatusInternalServerError)
+	buf.WriteTo(ctx.Response) // nolint: errcheck
+	return true
+}
+
+// recoverOverlay recovers a panic of a handler in watch mode, and shows it with its stack in
+// the overlay. Outside watch mode, panics are left to the http.Server as usual.
+func (app *App) recoverOverlay(ctx *Context) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	// an aborted response is left to the http.Server, e.g. a stream that fails
+	if p == http.ErrAbortHandler { // nolint: errorlint
+		panic(p)
+	}
+
+	stack := debug.Stack()
+
+	err, ok := p.(error)
+	if ok {
+		err = fmt.Errorf("panic: %w", err)
+	} else {
+		err = fmt.Errorf("panic: %v", p)
+	}
+
+	logID := nextLogID()
+	ctx.WriteHeader("X-Log-Id", logID)
+	app.logger.Error("xun: handle", slog.Any("err", err), slog.String("logid", logID))
+
+	if !app.writeOverlay(ctx, err, stack, logID) {
+		ctx.WriteStatus(http.StatusInternalServerError)
+	}
+}
+
+// overlay is the model of the overlay page.
+type overlay struct {
+	LogID    string
+	Chain    []overlayError
+	Stack    string
+	Source   *overlaySource
+	Request  *http.Request
+	Headers  [][2]string
+	Pattern  string
+	Viewers  []string
+	Metadata [][2]string
+	// Script is the live reload script, so the page reloads when the error is fixed.
+	Script template.HTML
+}
+
+type overlayError struct {
+	Type    string
+	Message string
+}
+
+// overlaySource is the part of a template file around the line that fails.
+type overlaySource struct {
+	File  string
+	Line  int
+	Lines []overlayLine
+}
+
+type overlayLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+// errorChain returns the error and the errors that it wraps, depth first.
+func errorChain(err error) []overlayError {
+	var chain []overlayError
+	for err != nil {
+		chain = append(chain, overlayError{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+
+		switch it := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range it.Unwrap() {
+				chain = append(chain, errorChain(e)...)
+			}
+			return chain
+		default:
+			err = errors.Unwrap(err)
+		}
+	}
+	return chain
+}
+
+// templateErrorPosition matches the position in the errors of text/template and html/template,
+// e.g. `template: about.html:3:5: executing "about.html" at <.Data.Name>: ...`.
+var templateErrorPosition = regexp.MustCompile(`(?:html/)?template: ?([^\s:]+):(\d+)`)
+
+// templateSource returns the lines of the template file around the position in the error message,
+// or nil if the message has no position or the file isn't found.
+func (app *App) templateSource(msg string) *overlaySource {
+	m := templateErrorPosition.FindStringSubmatch(msg)
+	if m == nil || app.fsys == nil {
+		return nil
+	}
+
+	line, _ := strconv.Atoi(m[2])
+
+	// pages are named without the pages/ prefix, the other html templates without the extension
+	for _, file := range []string{m[1], "pages/" + m[1], m[1] + ".html"} {
+		buf, err := fs.ReadFile(app.fsys, file)
+		if err != nil {
+			continue
+		}
+
+		src := &overlaySource{File: file, Line: line}
+		for i, text := range strings.Split(string(buf), "\n") {
+			n := i + 1
+			if n >= line-overlayContext && n <= line+overlayContext {
+				src.Lines = append(src.Lines, overlayLine{Number: n, Text: text, Current: n == line})
+			}
+		}
+		return src
+	}
+
+	return nil
+}
+
+var overlayTemplate = template.Must(template.New("overlay").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>xun: {{ (index .Chain 0).Message }}</title>
+<style>
+body{margin:0;padding:24px;background:#1e1e1e;color:#e6e6e6;font:14px/1.5 ui-monospace,Menlo,Consolas,monospace}
+h1{color:#ff6b6b;font-size:18px;margin:0 0 16px;white-space:pre-wrap}
+h2{color:#9cdcfe;font-size:14px;margin:24px 0 8px;text-transform:uppercase}
+pre,table{background:#252526;padding:12px;margin:0;overflow:auto;border-radius:4px}
+table{border-collapse:collapse;width:100%}
+td{padding:2px 12px 2px 0;vertical-align:top;white-space:pre-wrap;word-break:break-all}
+td:first-child{color:#9cdcfe;white-space:nowrap}
+.current{background:#5a1d1d}
+.type{color:#808080}
+</style>
+</head>
+<body>
+<h1>{{ (index .Chain 0).Message }}</h1>
+<div>log id: {{ .LogID }}</div>
+<h2>Errors</h2>
+<table>{{ range .Chain }}<tr><td class="type">{{ .Type }}</td><td>{{ .Message }}</td></tr>{{ end }}</table>
+{{- with .Source }}
+<h2>{{ .File }}:{{ .Line }}</h2>
+<table>{{ range .Lines }}<tr{{ if .Current }} class="current"{{ end }}><td>{{ .Number }}</td><td>{{ .Text }}</td></tr>{{ end }}</table>
+{{- end }}
+{{- if .Stack }}
+<h2>Stack</h2>
+<pre>{{ .Stack }}</pre>
+{{- end }}
+<h2>Request</h2>
+<table>
+<tr><td>{{ .Request.Method }}</td><td>{{ .Request.URL }}</td></tr>
+<tr><td>Proto</td><td>{{ .Request.Proto }}</td></tr>
+<tr><td>RemoteAddr</td><td>{{ .Request.RemoteAddr }}</td></tr>
+{{- range .Headers }}
+<tr><td>{{ index . 0 }}</td><td>{{ index . 1 }}</td></tr>
+{{- end }}
+</table>
+<h2>Routing</h2>
+<table>
+<tr><td>Pattern</td><td>{{ .Pattern }}</td></tr>
+<tr><td>Viewers</td><td>{{ range $i, $v := .Viewers }}{{ if $i }}, {{ end }}{{ $v }}{{ end }}</td></tr>
+{{- range .Metadata }}
+<tr><td>{{ index . 0 }}</td><td>{{ index . 1 }}</td></tr>
+{{- end }}
+</table>
+{{ .Script }}
+</body>
+</html>
+`))
@@ -0,0 +1,116 @@
+package xun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type userError struct{}
+
+func (userError) Error() string { return "user error" }
+
+func TestOverlay(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html": {Data: []byte("<html>\n<body>\n{{ block \"content\" . }}{{ end }}\n</body>\n</html>")},
+		"pages/index.html":  {Data: []byte("<!--layout:main-->\n{{ define \"content\" }}\n<h1>Home</h1>\n<p>{{ call .TempData.fail }}</p>\n{{ end }}")},
+	}
+
+	newApp := func(opts ...Option) (*http.ServeMux, *App) {
+		mux := http.NewServeMux()
+
+		opts = append(opts, WithMux(mux), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		app := New(opts...)
+
+		app.Use(func(next HandleFunc) HandleFunc {
+			return func(c *Context) error {
+				c.Set("fail", func() (string, error) { return "", errors.New("<boom>") })
+				return next(c)
+			}
+		})
+
+		app.Get("/error", func(c *Context) error {
+			return fmt.Errorf("load user: %w", userError{})
+		}, WithMetadata("auth", "admin"))
+
+		app.Get("/panic", func(c *Context) error {
+			panic("nil user")
+		})
+
+		app.Start()
+		return mux, app
+	}
+
+	// the credentials are sent with every request, and are redacted in the overlay
+	credentials := []string{"Authorization", "Bearer secret-token", "Cookie", "session=secret-session",
+		"X-Api-Key", "secret-key", "X-Request-Id", "42"}
+
+	t.Run("watch", func(t *testing.T) {
+		mux, app := newApp(WithWatch())
+		defer app.Close()
+
+		rw := serve(mux, http.MethodGet, "/", append(credentials, "Accept", "text/html,*/*")...)
+		body := rw.Body.String()
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Equal(t, "text/html; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Contains(t, body, "log id: "+rw.Header().Get("X-Log-Id"))
+		require.Contains(t, body, "&lt;boom&gt;")
+		require.Contains(t, body, "<h2>pages/index.html:4</h2>")
+		require.Contains(t, body, `<tr class="current"><td>4</td><td>&lt;p&gt;{{ call .TempData.fail }}&lt;/p&gt;</td></tr>`)
+		require.Contains(t, body, "<tr><td>1</td><td>&lt;!--layout:main--&gt;</td></tr>")
+		require.Contains(t, body, "<tr><td>Pattern</td><td>GET /{$}</td></tr>")
+
+		rw = serve(mux, http.MethodGet, "/error", append(credentials, "Accept", "text/html")...)
+		body = rw.Body.String()
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, body, "<td class=\"type\">*fmt.wrapError</td><td>load user: user error</td>")
+		require.Contains(t, body, "<td class=\"type\">xun.userError</td><td>user error</td>")
+		// a returned error has no stack
+		require.NotContains(t, body, "<h2>Stack</h2>")
+		// the credentials are redacted
+		require.Contains(t, body, "<tr><td>Authorization</td><td>[redacted]</td></tr>")
+		require.Contains(t, body, "<tr><td>Cookie</td><td>[redacted]</td></tr>")
+		require.Contains(t, body, "<tr><td>X-Api-Key</td><td>[redacted]</td></tr>")
+		require.Contains(t, body, "<tr><td>X-Request-Id</td><td>42</td></tr>")
+		require.NotContains(t, body, "secret")
+		require.Contains(t, body, "<tr><td>auth</td><td>admin</td></tr>")
+		require.Contains(t, body, "<tr><td>GET</td><td>/error</td></tr>")
+
+		rw = serve(mux, http.MethodGet, "/panic", append(credentials, "Accept", "text/html")...)
+		body = rw.Body.String()
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.NotEmpty(t, rw.Header().Get("X-Log-Id"))
+		require.Contains(t, body, "<h1>panic: nil user</h1>")
+		require.Contains(t, body, "<h2>Stack</h2>")
+		require.Contains(t, body, "overlay_test.go")
+
+		// an api client still gets the empty 500
+		rw = serve(mux, http.MethodGet, "/error", append(credentials, "Accept", "application/json")...)
+		body = rw.Body.String()
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.NotEmpty(t, rw.Header().Get("X-Log-Id"))
+		require.Empty(t, body)
+	})
+
+	t.Run("production", func(t *testing.T) {
+		mux, app := newApp()
+		defer app.Close()
+
+		rw := serve(mux, http.MethodGet, "/", append(credentials, "Accept", "text/html")...)
+		body := rw.Body.String()
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.NotEmpty(t, rw.Header().Get("X-Log-Id"))
+		require.Empty(t, body)
+
+		rw = serve(mux, http.MethodGet, "/error", append(credentials, "Accept", "text/html")...)
+		body = rw.Body.String()
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Empty(t, body)
+	})
+}
@@ -0,0 +1,189 @@
+package xun
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Middleware registers a named middleware that pages declare in their front matter:
+//
+//	---
+//	middleware: [auth, audit]
+//	---
+//
+// The middlewares of a page run after the middlewares of the app, in the declared order. The
+// names are resolved when the page is requested, so a middleware can be registered after the
+// pages are loaded, and a page that declares a name that is not registered fails with 500.
+func (app *App) Middleware(name string, m Middleware) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	if app.named == nil {
+		app.named = make(map[string]Middleware)
+	}
+
+	app.named[name] = m
+}
+
+// wrapMiddlewares wraps next in the named middlewares.
+func (app *App) wrapMiddlewares(names []string, next HandleFunc) (HandleFunc, error) {
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+
+	for i := len(names); i > 0; i-- {
+		m, ok := app.named[names[i-1]]
+		if !ok {
+			return nil, fmt.Errorf("xun: middleware %q is not registered", names[i-1])
+		}
+		next = m(next)
+	}
+
+	return next, nil
+}
+
+// pageOptions returns the methods of a page and the routing options that its front matter
+// declares. Every field is added to the metadata of its routes, where the methods and the
+// middlewares are lists of strings. A page without methods is served on GET.
+func pageOptions(fm FrontMatter) ([]string, []RoutingOption, error) {
+	methods := []string{http.MethodGet}
+	opts := make([]RoutingOption, 0, len(fm))
+
+	for key, value := range fm {
+		switch key {
+		case RouteMethods:
+			methods = methods[:0]
+			for _, it := range fm.GetStrings(key) {
+				m := strings.ToUpper(strings.TrimSpace(it))
+				if !isMethod(m) {
+					return nil, nil, fmt.Errorf("xun: front matter: invalid method %q", it)
+				}
+				if !slices.Contains(methods, m) {
+					methods = append(methods, m)
+				}
+			}
+
+			if len(methods) == 0 {
+				return nil, nil, fmt.Errorf("xun: front matter: %s is empty", key)
+			}
+			value = methods
+		case RouteMiddleware:
+			names := fm.GetStrings(key)
+			if names == nil {
+				return nil, nil, fmt.Errorf("xun: front matter: %s should be a list of names", key)
+			}
+			value = names
+		case CacheControl:
+			if fm.GetString(key) == "" {
+				return nil, nil, fmt.Errorf("xun: front matter: %s should be a Cache-Control header", key)
+			}
+		}
+
+		opts = append(opts, WithMetadata(key, value))
+	}
+
+	return methods, opts, nil
+}
+
+func isMethod(m string) bool {
+	if m == "" {
+		return false
+	}
+
+	for i := 0; i < len(m); i++ {
+		if m[i] < 'A' || m[i] > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// routePage registers the viewer of a page on the routes of the methods that the front matter
+// of its default template declares, with its fields in their routing options. It is called
+// again when the front matter is known or changed, e.g. a locale variant that is created before
+// its default template, so the routes of the methods that are not declared any more are removed,
+// and the others get the new options.
+func (app *App) routePage(name string, v Viewer, fm FrontMatter) error {
+	methods, opts, err := pageOptions(fm)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range app.pageRoutes(name, v) {
+		method, _, _ := strings.Cut(pattern, " ")
+		if !slices.Contains(methods, method) {
+			app.removeViewer(pattern, name, v)
+			app.viewers[name] = v
+		}
+	}
+
+	_, path, _ := strings.Cut(pagePattern(name), " ")
+	for _, method := range methods {
+		pattern := method + " " + path
+
+		r, ok := app.routes[pattern]
+		if ok && !r.removed && slices.Contains(r.Viewers, v) {
+			// the front matter is changed, and the viewer that serves the route gets its options
+			if r.Viewers[0] == v {
+				ro := &RoutingOptions{viewers: []Viewer{v}}
+				for _, o := range opts {
+					o(ro)
+				}
+				r.Options = ro
+			}
+			continue
+		}
+
+		app.HandlePage(pattern, name, v, opts...)
+	}
+
+	return nil
+}
+
+// pageRoutes returns the patterns of the routes that the viewer of a page is registered on.
+func (app *App) pageRoutes(name string, v Viewer) []string {
+	_, path, _ := strings.Cut(pagePattern(name), " ")
+
+	var patterns []string
+	for pattern, r := range app.routes {
+		_, p, _ := strings.Cut(pattern, " ")
+		if p == path && slices.Contains(r.Viewers, v) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// pageSource hides the front matter of a html page from the template, and returns it. The
+// block is replaced by a template comment of as many lines, so the errors of the template
+// report the lines of the file. The <!--layout:name--> directive below it, or the layout field
+// of the front matter, is moved to the first line where the layout is looked for.
+func pageSource(buf []byte) ([]byte, FrontMatter, error) {
+	fm, body, err := parseFrontMatter(buf)
+	if err != nil || fm == nil {
+		return buf, fm, err
+	}
+
+	lines := bytes.Count(buf[:len(buf)-len(body)], []byte("\n"))
+
+	var directive []byte
+	if layoutOf(body) != "" {
+		i := bytes.Index(body, []byte("-->")) + 3
+		directive, body = body[:i], body[i:]
+	} else if layout := fm.GetString("layout"); layout != "" {
+		directive = []byte("<!--layout:" + layout + "-->")
+	}
+
+	src := make([]byte, 0, len(directive)+lines+8+len(body))
+	src = append(src, directive...)
+	src = append(src, "{{/*"...)
+	src = append(src, bytes.Repeat([]byte("\n"), lines)...)
+	src = append(src, "*/}}"...)
+	src = append(src, body...)
+
+	return src, fm, nil
+}
@@ -0,0 +1,247 @@
+package xun
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestPageFrontMatter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html": {Data: []byte(`<main>{{ block "content" . }}{{ end }}</main>`)},
+		"pages/admin/dashboard.html": {Data: []byte(`---
+methods: [get, post]
+middleware: [auth, audit]
+cache: private, max-age=60
+name: Dashboard
+icon: home
+access: admin
+weight: 2
+---
+<!--layout:main-->
+{{ define "content" }}{{ .Data }} dashboard{{ end }}`)},
+		"pages/admin/dashboard.fr.html": {Data: []byte(`<!--layout:main-->{{ define "content" }}tableau{{ end }}`)},
+		"pages/about.html": {Data: []byte(`---
+layout: main
+---
+{{ define "content" }}about{{ end }}`)},
+		"pages/docs.md": {Data: []byte(`---
+methods: POST
+cache: no-store
+---
+# Docs`)},
+		"pages/contact.html":    {Data: []byte("---\nmethods: [POST]\n---\n<p>contact</p>")},
+		"pages/contact.fr.html": {Data: []byte("<p>contacter</p>")},
+		"pages/faq.md":          {Data: []byte("---\nmethods: [POST]\n---\n# FAQ")},
+		"pages/faq.fr.md":       {Data: []byte("# Questions")},
+		"pages/secret.html":     {Data: []byte("---\nmiddleware: [missing]\n---\n<p>secret</p>")},
+		"pages/invalid.html":    {Data: []byte("---\nmethods: [GET, \"PO ST\"]\n---\n<p>invalid</p>")},
+		"pages/broken.html":     {Data: []byte("---\ntitle: broken\n---\n\n<p>{{ .Data.title }</p>")},
+	}
+
+	mux := http.NewServeMux()
+
+	var logs strings.Builder
+	app := New(WithMux(mux), WithFsys(fsys), WithLocales("fr"), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithViewEngines(&StaticViewEngine{}, &HtmlViewEngine{}, &MarkdownViewEngine{}))
+
+	var calls []string
+	app.Use(func(next HandleFunc) HandleFunc {
+		return func(c *Context) error {
+			calls = append(calls, "app")
+			return next(c)
+		}
+	})
+
+	// the named middlewares can be registered after the pages are loaded
+	app.Middleware("auth", func(next HandleFunc) HandleFunc {
+		return func(c *Context) error {
+			calls = append(calls, "auth:"+c.Routing.Options.GetString(NavigationAccess))
+			if c.Request.Header.Get("X-User") == "" {
+				c.WriteStatus(http.StatusUnauthorized)
+				return ErrCancelled
+			}
+			return next(c)
+		}
+	})
+
+	app.Middleware("audit", func(next HandleFunc) HandleFunc {
+		return func(c *Context) error {
+			calls = append(calls, "audit")
+			return next(c)
+		}
+	})
+
+	app.Loader("admin/dashboard", func(c *Context) (any, error) {
+		return c.Request.Method, nil
+	})
+
+	app.Start()
+	defer app.Close()
+
+	t.Run("options", func(t *testing.T) {
+		for _, pattern := range []string{"GET /admin/dashboard", "POST /admin/dashboard"} {
+			r, ok := app.routes[pattern]
+			require.True(t, ok, pattern)
+
+			ro := r.Options
+			require.Equal(t, []string{"GET", "POST"}, ro.Get(RouteMethods))
+			require.Equal(t, []string{"auth", "audit"}, ro.Get(RouteMiddleware))
+			require.Equal(t, "private, max-age=60", ro.GetString(CacheControl))
+			require.Equal(t, "Dashboard", ro.GetString(NavigationName))
+			require.Equal(t, "home", ro.GetString(NavigationIcon))
+			require.Equal(t, "admin", ro.GetString(NavigationAccess))
+			require.Equal(t, 2, ro.GetInt("weight"))
+		}
+
+		// a page without front matter has no metadata
+		_, ok := app.routes["GET /about"]
+		require.True(t, ok)
+		require.Nil(t, app.routes["GET /about"].Options.Get(RouteMethods))
+	})
+
+	t.Run("methods", func(t *testing.T) {
+		rw := serve(mux, http.MethodPost, "/admin/dashboard", "Accept", "text/html", "X-User", "ada")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<main>POST dashboard</main>", rw.Body.String())
+
+		rw = serve(mux, http.MethodPut, "/admin/dashboard", "Accept", "text/html", "X-User", "ada")
+		require.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+
+		// the markdown page is only served on POST
+		rw = serve(mux, http.MethodPost, "/docs", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<h1 id=\"docs\">Docs</h1>\n", rw.Body.String())
+		require.Equal(t, "no-store", rw.Header().Get("Cache-Control"))
+
+		rw = serve(mux, http.MethodGet, "/docs", "Accept", "text/html")
+		require.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+
+		// the locale variants sort before their default templates, but don't route them on GET
+		for _, path := range []string{"/contact", "/faq"} {
+			rw = serve(mux, http.MethodPost, path, "Accept", "text/html")
+			require.Equal(t, http.StatusOK, rw.Code, path)
+
+			rw = serve(mux, http.MethodGet, path, "Accept", "text/html")
+			require.Equal(t, http.StatusMethodNotAllowed, rw.Code, path)
+		}
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		calls = nil
+		rw := serve(mux, http.MethodGet, "/admin/dashboard", "Accept", "text/html", "X-User", "ada")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<main>GET dashboard</main>", rw.Body.String())
+		require.Equal(t, "private, max-age=60", rw.Header().Get("Cache-Control"))
+		require.Equal(t, []string{"app", "auth:admin", "audit"}, calls)
+
+		// the locale variants share the routes of the page
+		rw = serve(mux, http.MethodGet, "/admin/dashboard", "Accept", "text/html", "X-User", "ada", "Accept-Language", "fr")
+		require.Equal(t, "<main>tableau</main>", rw.Body.String())
+
+		// the Cache-Control header is not written when a middleware refuses the request
+		calls = nil
+		rw = serve(mux, http.MethodGet, "/admin/dashboard", "Accept", "text/html")
+		require.Equal(t, http.StatusUnauthorized, rw.Code)
+		require.Empty(t, rw.Header().Get("Cache-Control"))
+		require.Equal(t, []string{"app", "auth:admin"}, calls)
+
+		rw = serve(mux, http.MethodGet, "/secret", "Accept", "text/html")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, logs.String(), `xun: middleware \"missing\" is not registered`)
+	})
+
+	t.Run("layout", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/about", "Accept", "text/html")
+		require.Equal(t, "<main>about</main>", rw.Body.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		// the page of an invalid front matter is not registered
+		_, ok := app.routes["GET /invalid"]
+		require.False(t, ok)
+		require.Contains(t, logs.String(), `xun: front matter: invalid method \"PO ST\"`)
+
+		// the lines of a template error are the lines of the file
+		require.Contains(t, logs.String(), "broken.html:5:")
+	})
+}
+
+func TestPageFrontMatterOnWatch(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/form.html":    {Data: []byte("---\nmethods: [GET, POST]\ncache: no-cache\n---\n<p>form</p>")},
+		"pages/form.fr.html": {Data: []byte("<p>formulaire</p>")},
+	}
+
+	mux := http.NewServeMux()
+
+	app := New(WithMux(mux), WithFsys(fsys), WithLocales("fr"), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	app.watch = true
+	app.Start()
+	defer app.Close()
+
+	changed := func(t *testing.T, name string, op fsnotify.Op) {
+		require.NoError(t, app.engines[1].FileChanged(fsys, app, fsnotify.Event{Name: name, Op: op}))
+	}
+
+	rw := serve(mux, http.MethodPost, "/form", "Accept", "text/html")
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Equal(t, "<p>form</p>", rw.Body.String())
+	require.Equal(t, "no-cache", rw.Header().Get("Cache-Control"))
+
+	t.Run("write", func(t *testing.T) {
+		fsys["pages/form.html"] = &fstest.MapFile{Data: []byte("---\nmethods: [GET, PUT]\ncache: no-store\n---\n<p>form v2</p>")}
+		changed(t, "pages/form.html", fsnotify.Write)
+
+		rw := serve(mux, http.MethodPut, "/form", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<p>form v2</p>", rw.Body.String())
+		require.Equal(t, "no-store", rw.Header().Get("Cache-Control"))
+
+		rw = serve(mux, http.MethodPost, "/form", "Accept", "text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		rw = serve(mux, http.MethodGet, "/form", "Accept", "text/html")
+		require.Equal(t, "no-store", rw.Header().Get("Cache-Control"))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		// the locale variant is left on GET without the front matter
+		delete(fsys, "pages/form.html")
+		changed(t, "pages/form.html", fsnotify.Remove)
+
+		rw := serve(mux, http.MethodGet, "/form", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<p>formulaire</p>", rw.Body.String())
+		require.Empty(t, rw.Header().Get("Cache-Control"))
+
+		rw = serve(mux, http.MethodPut, "/form", "Accept", "text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		fsys["pages/form.html"] = &fstest.MapFile{Data: []byte("---\nmethods: [POST]\n---\n<p>form v3</p>")}
+		changed(t, "pages/form.html", fsnotify.Create)
+
+		rw := serve(mux, http.MethodPost, "/form", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<p>form v3</p>", rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/form", "Accept", "text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		delete(fsys, "pages/form.html")
+		changed(t, "pages/form.html", fsnotify.Remove)
+		delete(fsys, "pages/form.fr.html")
+		changed(t, "pages/form.fr.html", fsnotify.Remove)
+
+		rw = serve(mux, http.MethodGet, "/form", "Accept", "text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+}
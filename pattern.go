@@ -4,7 +4,7 @@ import (
 	"strings"
 )
 
-func splitPattern(s string) (string, string, string) { //nolint: unused
+func splitPattern(s string) (string, string, string) {
 	if len(s) == 0 {
 		return "", "", ""
 	}
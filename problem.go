@@ -0,0 +1,207 @@
+package xun
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// ProblemNamespace is the XML namespace of a problem details document.
+const ProblemNamespace = "urn:ietf:rfc:7807"
+
+// Problem is a problem details object defined by RFC 9457.
+//
+// It implements error, so a handler can return it (or any error that wraps it), and the
+// app renders it as "application/problem+json" or "application/problem+xml" with its
+// Status. Extensions are written as extra members next to the standard ones.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// NewProblem creates a Problem with the given status, the status text as Title and detail.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Error returns the title and the detail of the problem.
+func (p *Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+
+	if p.Detail == "" {
+		return title
+	}
+
+	return title + ": " + p.Detail
+}
+
+// With sets an extension member, and returns the problem for chaining.
+func (p *Problem) With(name string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+
+	p.Extensions[name] = value
+	return p
+}
+
+// members returns the standard members that are set, followed by the extensions that
+// don't collide with them.
+func (p *Problem) members() map[string]any {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+
+	set := func(name string, value string) {
+		if value == "" {
+			delete(m, name)
+			return
+		}
+		m[name] = value
+	}
+
+	set("type", p.Type)
+	set("title", p.Title)
+	set("detail", p.Detail)
+	set("instance", p.Instance)
+
+	if p.Status > 0 {
+		m["status"] = p.Status
+	} else {
+		delete(m, "status")
+	}
+
+	return m
+}
+
+// MarshalJSON writes the problem as a JSON object.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.members())
+}
+
+// UnmarshalJSON reads the problem from a JSON object. Members that are not standard are
+// kept in Extensions.
+func (p *Problem) UnmarshalJSON(buf []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return err
+	}
+
+	*p = Problem{}
+	for k, v := range m {
+		switch k {
+		case "type":
+			p.Type, _ = v.(string)
+		case "title":
+			p.Title, _ = v.(string)
+		case "detail":
+			p.Detail, _ = v.(string)
+		case "instance":
+			p.Instance, _ = v.(string)
+		case "status":
+			if n, ok := v.(float64); ok {
+				p.Status = int(n)
+			}
+		default:
+			p.With(k, v)
+		}
+	}
+	return nil
+}
+
+// MarshalXML writes the problem as a <problem> element in ProblemNamespace. Maps and
+// structs are written as child elements, and slices as <i> elements (RFC 9457 Appendix B).
+func (p *Problem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "problem"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: ProblemNamespace}},
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	m := p.members()
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := encodeXmlValue(e, name, reflect.ValueOf(m[name])); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func encodeXmlValue(e *xml.Encoder, name string, v reflect.Value) error {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		for _, k := range keys {
+			if err := encodeXmlValue(e, fmt.Sprint(k.Interface()), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXmlValue(e, "i", v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Float32, reflect.Float64:
+		return e.EncodeElement(strconv.FormatFloat(v.Float(), 'f', -1, 64), start)
+	}
+
+	return e.EncodeElement(v.Interface(), start)
+}
@@ -0,0 +1,74 @@
+package xun
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblem(t *testing.T) {
+	p := NewProblem(http.StatusForbidden, "Your current balance is 30, but that costs 50.").
+		With("balance", 30).
+		With("accounts", []string{"/account/12345", "/account/67890"})
+	p.Type = "https://example.com/probs/out-of-credit"
+	p.Title = "You do not have enough credit."
+	p.Instance = "/account/12345/msgs/abc"
+
+	t.Run("error_should_work", func(t *testing.T) {
+		require.Equal(t, "You do not have enough credit.: Your current balance is 30, but that costs 50.", p.Error())
+		require.Equal(t, "Not Found", (&Problem{Status: http.StatusNotFound}).Error())
+	})
+
+	t.Run("json_should_work", func(t *testing.T) {
+		buf, err := json.Marshal(p)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+	"type": "https://example.com/probs/out-of-credit",
+	"title": "You do not have enough credit.",
+	"status": 403,
+	"detail": "Your current balance is 30, but that costs 50.",
+	"instance": "/account/12345/msgs/abc",
+	"balance": 30,
+	"accounts": ["/account/12345", "/account/67890"]
+}`, string(buf))
+
+		var it Problem
+		require.NoError(t, json.Unmarshal(buf, &it))
+		require.Equal(t, p.Type, it.Type)
+		require.Equal(t, p.Title, it.Title)
+		require.Equal(t, p.Status, it.Status)
+		require.Equal(t, p.Detail, it.Detail)
+		require.Equal(t, p.Instance, it.Instance)
+		require.Equal(t, map[string]any{"balance": 30.0, "accounts": []any{"/account/12345", "/account/67890"}}, it.Extensions)
+
+		require.Error(t, json.Unmarshal([]byte(`[]`), &it))
+	})
+
+	t.Run("standard_members_should_win", func(t *testing.T) {
+		buf, err := json.Marshal(&Problem{Status: 400, Extensions: map[string]any{"status": "bad", "title": "x"}})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"status": 400}`, string(buf))
+	})
+
+	t.Run("xml_should_work", func(t *testing.T) {
+		it := NewProblem(http.StatusBadRequest, "validation failed").
+			With("errors", map[string]string{"name": "required", "email": "invalid"}).
+			With("ids", []int{1, 2}).
+			With("ratio", 0.5).
+			With("missing", nil)
+
+		buf, err := xml.Marshal(it)
+		require.NoError(t, err)
+		require.Equal(t, `<problem xmlns="urn:ietf:rfc:7807">`+
+			`<detail>validation failed</detail>`+
+			`<errors><email>invalid</email><name>required</name></errors>`+
+			`<ids><i>1</i><i>2</i></ids>`+
+			`<ratio>0.5</ratio>`+
+			`<status>400</status>`+
+			`<title>Bad Request</title>`+
+			`</problem>`, string(buf))
+	})
+}
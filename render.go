@@ -0,0 +1,137 @@
+package xun
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RenderOptions holds the options of App.Render.
+type RenderOptions struct {
+	locales  []string
+	tempData map[string]any
+}
+
+// RenderOption is a function that takes a pointer to RenderOptions and modifies it.
+type RenderOption func(*RenderOptions)
+
+// WithRenderLocale picks the locale variant of the template for the given locales in order of
+// preference, e.g. the language of the user that an email is sent to. A template without a
+// matched variant is rendered in its default file.
+func WithRenderLocale(locales ...string) RenderOption {
+	return func(o *RenderOptions) {
+		o.locales = append(o.locales, locales...)
+	}
+}
+
+// WithRenderTempData adds a key-value pair to the TempData that the template reads as
+// {{ .TempData.key }}, as a middleware sets it for a request.
+func WithRenderTempData(key string, value any) RenderOption {
+	return func(o *RenderOptions) {
+		o.tempData[key] = value
+	}
+}
+
+// Render renders the named template outside of a request, e.g. for emails, PDFs and background
+// reports, and writes the result to w:
+//
+//	err := app.Render(&buf, "views/email/welcome", user, xun.WithRenderLocale("fr"))
+//
+// The name is a view ("views/email/welcome"), a page ("pages/about" or "about"), a text
+// template ("text/welcome.txt"), a component or a layout. The template runs with the funcMap
+// and the asset URLs of the app, in the layout that it declares, and with .TempData from the
+// options. Its front matter is the data of a page if data is nil.
+//
+// It is safe to call from any goroutine, including while the files are reloaded in watch mode.
+// The result is buffered, so nothing is written to w if the template fails. It returns an
+// error that wraps ErrViewNotFound if there is no such template.
+func (app *App) Render(w io.Writer, name string, data any, opts ...RenderOption) error {
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := app.render(buf, name, data, opts, ""); err != nil {
+		return err
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// RenderHTML renders the named html template like Render, and returns the html.
+func (app *App) RenderHTML(name string, data any, opts ...RenderOption) (string, error) {
+	return app.renderString(name, data, opts, "html")
+}
+
+// RenderText renders the named text template like Render, and returns the text.
+func (app *App) RenderText(name string, data any, opts ...RenderOption) (string, error) {
+	return app.renderString(name, data, opts, "text")
+}
+
+func (app *App) renderString(name string, data any, opts []RenderOption, kind string) (string, error) {
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := app.render(buf, name, data, opts, kind); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// render executes the named template, which must be a html or a text template if kind is set.
+func (app *App) render(w io.Writer, name string, data any, opts []RenderOption, kind string) error {
+	o := &RenderOptions{tempData: make(map[string]any)}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ht, tt := app.lookupTemplate(name, o.locales)
+
+	switch {
+	case ht != nil && kind != "text":
+		if fm := ht.FrontMatter(); data == nil && fm != nil {
+			data = fm
+		}
+		// the flush markers of a streaming page are removed
+		return ht.Execute(&flushWriter{w: w}, ViewModel{TempData: o.tempData, Data: data})
+	case tt != nil && kind != "html":
+		return tt.Execute(w, ViewModel{TempData: o.tempData, Data: data})
+	}
+
+	return fmt.Errorf("%w: %s", ErrViewNotFound, name)
+}
+
+// lookupTemplate returns the html or the text template with the given name, in the best
+// matched variant for the locales.
+func (app *App) lookupTemplate(name string, locales []string) (*HtmlTemplate, *TextTemplate) {
+	it, ok := app.viewer(name)
+	if !ok {
+		// pages are registered without the "pages/" prefix
+		it, ok = app.viewer(strings.TrimPrefix(name, "pages/"))
+	}
+
+	app.reloadMu.RLock()
+	defer app.reloadMu.RUnlock()
+
+	if ok {
+		switch v := it.(type) {
+		case *HtmlViewer:
+			_, t := pickLocale(locales, app.defaultLocale, v.template, v.locales)
+			return t, nil
+		case *TextViewer:
+			_, t := pickLocale(locales, app.defaultLocale, v.template, v.locales)
+			return nil, t
+		}
+	}
+
+	// the components and the layouts have no viewers
+	for _, e := range app.engines {
+		if ve, ok := e.(*HtmlViewEngine); ok {
+			if t, ok := ve.templates[name]; ok {
+				return t, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
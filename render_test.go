@@ -0,0 +1,189 @@
+package xun
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public/logo.svg":          {Data: []byte(`<svg></svg>`)},
+		"components/button.html":   {Data: []byte(`<a href="{{ .Data.URL }}">{{ .Data.Text }}</a>`)},
+		"layouts/email.html":       {Data: []byte(`<html><body><img src="{{ asset "/logo.svg" }}">{{ block "content" . }}{{ end }}{{ flush }}</body></html>`)},
+		"views/email/welcome.html": {Data: []byte(`<!--layout:email-->{{ define "content" }}<p>{{ upper .Data.Name }}, {{ .TempData.from }}</p>{{ end }}`)},
+		"views/email/welcome.fr.html": {
+			Data: []byte(`<!--layout:email-->{{ define "content" }}<p>Bonjour {{ .Data.Name }}</p>{{ end }}`),
+		},
+		"views/broken.html":   {Data: []byte(`<p><b>{{ .Data.Name }}</b></p>`)},
+		"pages/about.html":    {Data: []byte("---\ntitle: About\n---\n<h1>{{ .Data.title }}</h1>")},
+		"text/welcome.txt":    {Data: []byte(`Hi {{ .Data.Name }}`)},
+		"text/welcome.fr.txt": {Data: []byte(`Salut {{ .Data.Name }}`)},
+	}
+
+	app := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithLocales("fr"), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithBuildAssetURL(func(s string) bool { return s == "/logo.svg" }))
+	defer app.Close()
+
+	logo := app.AssetURLs["/logo.svg"]
+	require.NotEqual(t, "/logo.svg", logo)
+
+	type user struct{ Name string }
+
+	t.Run("html", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := app.Render(&buf, "views/email/welcome", user{Name: "ada"}, WithRenderTempData("from", "xun"))
+		require.NoError(t, err)
+		require.Equal(t, `<html><body><img src="`+logo+`"><p>ADA, xun</p></body></html>`, buf.String())
+
+		s, err := app.RenderHTML("views/email/welcome", user{Name: "ada"}, WithRenderLocale("fr-CA", "en"))
+		require.NoError(t, err)
+		require.Equal(t, `<html><body><img src="`+logo+`"><p>Bonjour ada</p></body></html>`, s)
+
+		// an unmatched locale renders the default file
+		s, err = app.RenderHTML("views/email/welcome", user{Name: "ada"}, WithRenderLocale("de"))
+		require.NoError(t, err)
+		require.Equal(t, `<html><body><img src="`+logo+`"><p>ADA, </p></body></html>`, s)
+	})
+
+	t.Run("pages_and_components", func(t *testing.T) {
+		for _, name := range []string{"pages/about", "about"} {
+			s, err := app.RenderHTML(name, nil)
+			require.NoError(t, err)
+			require.Equal(t, "<h1>About</h1>", s)
+		}
+
+		s, err := app.RenderHTML("components/button", map[string]string{"URL": "/go", "Text": "Go"})
+		require.NoError(t, err)
+		require.Equal(t, `<a href="/go">Go</a>`, s)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		s, err := app.RenderText("text/welcome.txt", user{Name: "<ada>"})
+		require.NoError(t, err)
+		require.Equal(t, "Hi <ada>", s)
+
+		s, err = app.RenderText("text/welcome.txt", user{Name: "ada"}, WithRenderLocale("fr"))
+		require.NoError(t, err)
+		require.Equal(t, "Salut ada", s)
+
+		var buf bytes.Buffer
+		require.NoError(t, app.Render(&buf, "text/welcome.txt", user{Name: "ada"}))
+		require.Equal(t, "Hi ada", buf.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := app.Render(&buf, "views/missing", nil)
+		require.ErrorIs(t, err, ErrViewNotFound)
+
+		// a text template is not html, and a html template is not text
+		_, err = app.RenderHTML("text/welcome.txt", nil)
+		require.ErrorIs(t, err, ErrViewNotFound)
+		_, err = app.RenderText("views/email/welcome", nil)
+		require.ErrorIs(t, err, ErrViewNotFound)
+
+		// nothing is written when the template fails
+		err = app.Render(&buf, "views/broken", 42)
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrViewNotFound))
+		require.Empty(t, buf.String())
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 16)
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				name := strconv.Itoa(i)
+				s, err := app.RenderText("text/welcome.txt", user{Name: name})
+				if err == nil && s != "Hi "+name {
+					err = errors.New("unexpected " + s)
+				}
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+	})
+}
+
+func TestRenderOnWatch(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/email.html": {Data: []byte(`<main>{{ block "content" . }}{{ end }}</main>`)},
+		"views/notice.html":  {Data: []byte(`<!--layout:email-->{{ define "content" }}v1{{ end }}`)},
+	}
+
+	app := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	app.watch = true
+	defer app.Close()
+
+	changed := func(name string, op fsnotify.Op) {
+		app.fileChanged(fsnotify.Event{Name: name, Op: op})
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				s, err := app.RenderHTML("views/notice", nil)
+				if err != nil && !errors.Is(err, ErrViewNotFound) {
+					errs <- err
+					return
+				}
+				if err == nil && !strings.HasPrefix(s, "<") {
+					errs <- errors.New("unexpected " + s)
+					return
+				}
+			}
+		}()
+	}
+
+	fsys["layouts/email.html"] = &fstest.MapFile{Data: []byte(`<section>{{ block "content" . }}{{ end }}</section>`)}
+	changed("layouts/email.html", fsnotify.Write)
+	fsys["views/notice.html"] = &fstest.MapFile{Data: []byte(`<!--layout:email-->{{ define "content" }}v2{{ end }}`)}
+	changed("views/notice.html", fsnotify.Write)
+
+	close(done)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	s, err := app.RenderHTML("views/notice", nil)
+	require.NoError(t, err)
+	require.Equal(t, "<section>v2</section>", s)
+
+	delete(fsys, "views/notice.html")
+	changed("views/notice.html", fsnotify.Remove)
+
+	_, err = app.RenderHTML("views/notice", nil)
+	require.ErrorIs(t, err, ErrViewNotFound)
+}
@@ -8,6 +8,11 @@ type Routing struct {
 
 	Options *RoutingOptions
 	Viewers []Viewer
+
+	// static is true if the route only renders its viewers, as the routes of HandlePage and
+	// HandleFile do, and removed is true if all of them have been removed in watch mode.
+	static  bool
+	removed bool
 }
 
 func (r *Routing) Next(ctx *Context) error {
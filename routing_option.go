@@ -53,6 +53,26 @@ const (
 	NavigationName   = "name"
 	NavigationIcon   = "icon"
 	NavigationAccess = "access"
+
+	// HtmlStreaming is the metadata key that WithStreaming sets.
+	HtmlStreaming = "html_streaming"
+
+	// RouteETag is the metadata key that WithRouteETag sets.
+	RouteETag = "etag"
+
+	// BlockHeader is the metadata key that WithBlockHeader sets.
+	BlockHeader = "block_header"
+
+	// RouteMethods is the metadata key of the methods that a page declares in its front matter.
+	RouteMethods = "methods"
+
+	// RouteMiddleware is the metadata key of the named middlewares that a page declares in its
+	// front matter, see App.Middleware.
+	RouteMiddleware = "middleware"
+
+	// CacheControl is the metadata key of the Cache-Control header that a page declares in its
+	// front matter.
+	CacheControl = "cache"
 )
 
 // WithMetadata adds a key-value pair to the routing metadata.
@@ -81,6 +101,29 @@ func WithNavigation(name, icon, access string) RoutingOption {
 	}
 }
 
+// WithStreaming makes the HtmlViewer of the route stream the page instead of buffering it.
+//
+// The html before the first action, e.g. the <head> of the layout, is sent at once, and
+// the rendered html is flushed at every {{ flush }} in the templates. An error after the
+// first byte can't change the status any more, so it is logged with a log id and the
+// response ends where the error occurred.
+func WithStreaming() RoutingOption {
+	return WithMetadata(HtmlStreaming, true)
+}
+
+// WithRouteETag enables ETag for the content that the viewers of the route render, like
+// WithETag does for all routes.
+func WithRouteETag() RoutingOption {
+	return WithMetadata(RouteETag, true)
+}
+
+// WithBlockHeader makes the HtmlViewer of the route render only the block named by the given
+// request header, e.g. "HX-Target", instead of the whole page. If the header is empty or the
+// page has no such block, the whole page is rendered. The header is added to Vary.
+func WithBlockHeader(header string) RoutingOption {
+	return WithMetadata(BlockHeader, header)
+}
+
 // WithViewer sets the viewer for the routing options.
 func WithViewer(v ...Viewer) RoutingOption {
 	return func(ro *RoutingOptions) {
@@ -0,0 +1,225 @@
+package xun
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+const (
+	// DefaultFlushSize is the number of bytes a streaming viewer writes before it flushes the response.
+	DefaultFlushSize = 32 << 10
+
+	// DefaultFlushInterval is the longest time a streaming viewer holds written data before it flushes the response.
+	DefaultFlushInterval = time.Second
+)
+
+// streamWriter writes to the response and flushes it after every size bytes or interval,
+// so the client receives a long response incrementally. The first write is flushed at
+// once to send the first byte early. The flush goes through the gzip/deflate writers
+// when the response is compressed.
+type streamWriter struct {
+	rw       ResponseWriter
+	size     int
+	interval time.Duration
+
+	pending int
+	written int
+	last    time.Time
+}
+
+func newStreamWriter(rw ResponseWriter, size int, interval time.Duration) *streamWriter {
+	if size <= 0 {
+		size = DefaultFlushSize
+	}
+
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+
+	return &streamWriter{
+		rw:       rw,
+		size:     size,
+		interval: interval,
+	}
+}
+
+// Write writes p to the response, and flushes it if the size or interval is reached.
+func (w *streamWriter) Write(p []byte) (int, error) {
+	n, err := w.rw.Write(p)
+	w.pending += n
+	w.written += n
+
+	if w.pending >= w.size || time.Since(w.last) >= w.interval {
+		w.Flush()
+	}
+
+	return n, err
+}
+
+// Flush sends any pending data to the client.
+func (w *streamWriter) Flush() {
+	if f, ok := w.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	w.pending = 0
+	w.last = time.Now()
+}
+
+// abortStream ends a stream that fails. An error before anything is written is returned as
+// it is, so the App writes its error response. Otherwise the response has been sent partly:
+// the error is logged with a log id, and the response is aborted with http.ErrAbortHandler,
+// so the client sees an interrupted response rather than data that looks complete.
+func abortStream(ctx *Context, sw *streamWriter, err error) error {
+	if err == nil || errors.Is(err, ErrCancelled) || sw.written == 0 {
+		return err
+	}
+
+	logger := slog.Default()
+	if ctx.App != nil {
+		logger = ctx.App.logger
+	}
+	logger.Error("xun: stream", slog.Any("err", err), slog.String("logid", nextLogID()))
+
+	sw.Flush()
+	panic(http.ErrAbortHandler)
+}
+
+// flushMarker is written by the flush template func.
+const flushMarker = "<!--xun:flush-->"
+
+var (
+	headEnd = []byte("</head>")
+	bodyEnd = []byte("</body>")
+)
+
+// flushWriter removes the flush markers of a rendered template. If flush is not nil, it is
+// called at every marker and after </head>, so the head of a page is sent first.
+//
+// A template writes the result of a func in a single Write, so a marker is never split.
+type flushWriter struct {
+	w       io.Writer
+	flush   func()
+	written int
+
+	// script is written before </body> once, e.g. the live reload script in watch mode.
+	script []byte
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	if string(p) == flushMarker {
+		if fw.flush != nil {
+			fw.flush()
+		}
+		return len(p), nil
+	}
+
+	out := p
+	if fw.script != nil {
+		if i := bytes.LastIndex(p, bodyEnd); i >= 0 {
+			out = make([]byte, 0, len(p)+len(fw.script))
+			out = append(append(append(out, p[:i]...), fw.script...), p[i:]...)
+			fw.script = nil
+		}
+	}
+
+	n, err := fw.w.Write(out)
+	fw.written += n
+	if err == nil {
+		n = len(p)
+	}
+
+	if fw.flush != nil && bytes.Contains(p, headEnd) {
+		fw.flush()
+	}
+
+	return n, err
+}
+
+// seqElem returns the element type of an iter.Seq type.
+func seqElem(t reflect.Type) (reflect.Type, bool) {
+	if t == nil || t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return nil, false
+	}
+
+	y := t.In(0)
+	if y.Kind() != reflect.Func || y.NumIn() != 1 || y.NumOut() != 1 || y.Out(0).Kind() != reflect.Bool {
+		return nil, false
+	}
+
+	return y.In(0), true
+}
+
+// rangeSeq calls fn for each element of an iter.Seq until fn returns false.
+func rangeSeq(seq reflect.Value, fn func(v reflect.Value) bool) {
+	yield := reflect.MakeFunc(seq.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(fn(args[0]))}
+	})
+
+	seq.Call([]reflect.Value{yield})
+}
+
+// streamElem returns the element type of an iter.Seq or a channel that can be received from.
+func streamElem(t reflect.Type) (reflect.Type, bool) {
+	if t != nil && t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0 {
+		return t.Elem(), true
+	}
+
+	return seqElem(t)
+}
+
+// rangeStream calls fn for each element of an iter.Seq or a channel until fn returns false,
+// the channel is closed or ctx is done. It returns ErrCancelled if ctx is done, so the
+// handler stops without writing an error to a response that has been sent partly.
+//
+// While a channel has nothing to receive, the pending data of w is flushed every interval.
+func rangeStream(ctx context.Context, v reflect.Value, w *streamWriter, fn func(v reflect.Value) bool) error {
+	if v.Kind() == reflect.Chan {
+		timer := time.NewTimer(w.interval)
+		defer timer.Stop()
+
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: v},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)},
+		}
+
+		for {
+			chosen, e, ok := reflect.Select(cases)
+			switch chosen {
+			case 0:
+				return ErrCancelled
+			case 2:
+				if w.pending > 0 {
+					w.Flush()
+				}
+				timer.Reset(w.interval)
+				continue
+			}
+
+			if !ok || !fn(e) {
+				return nil
+			}
+		}
+	}
+
+	var cancelled bool
+	rangeSeq(v, func(e reflect.Value) bool {
+		if ctx.Err() != nil {
+			cancelled = true
+			return false
+		}
+		return fn(e)
+	})
+
+	if cancelled {
+		return ErrCancelled
+	}
+	return nil
+}
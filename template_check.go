@@ -0,0 +1,469 @@
+package xun
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// TemplateProblem is a problem in a template file that CheckTemplates reports.
+type TemplateProblem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// String returns the problem as "file:line: message".
+func (p TemplateProblem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// CheckTemplates parses every template under components, layouts, pages, views and text,
+// and reports the problems that break or surprise a page at runtime:
+//   - functions that are not in the FuncMap of the app
+//   - {{template "name"}} calls and slots of a template that is not defined
+//   - components that are not found, and component calls whose props don't match the declaration
+//   - layouts in <!--layout:name--> that don't exist, and layout cycles
+//   - components that no template uses, by {{template}}, {{block}} or the component function
+//   - {{define}} blocks of a page that no block of its layout executes, so they shadow nothing
+//
+// It returns nil when all templates are fine, so a test can keep a broken template from shipping:
+//
+//	require.Empty(t, app.CheckTemplates())
+func (app *App) CheckTemplates() []TemplateProblem {
+	if app.fsys == nil {
+		return nil
+	}
+
+	c := &templateChecker{
+		fsys:    app.fsys,
+		funcMap: app.funcMap,
+		files:   make(map[string]*checkedFile),
+		unknown: make(map[string]struct{}),
+		seen:    make(map[string]struct{}),
+	}
+
+	for _, dir := range []string{"components", "layouts", "pages", "views", "text"} {
+		fs.WalkDir(c.fsys, dir, func(path string, d fs.DirEntry, err error) error { // nolint: errcheck
+			if err != nil || d.IsDir() {
+				return nil
+			}
+
+			if dir == "text" || strings.EqualFold(filepath.Ext(path), ".html") {
+				c.parseFile(path, dir)
+			}
+			return nil
+		})
+	}
+
+	c.loadHtml()
+	c.checkCalls()
+	c.checkLayouts()
+	c.checkComponents()
+	c.checkDefines()
+
+	slices.SortFunc(c.problems, func(a, b TemplateProblem) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Message, b.Message))
+	})
+
+	return c.problems
+}
+
+// checkedFile is a template file that is parsed by the checker.
+type checkedFile struct {
+	path string
+	dir  string
+	buf  []byte
+	// trees are the root of the file, keyed by its path, and its {{define}} and {{block}} templates.
+	trees map[string]*parse.Tree
+	calls []templateCall
+	// components are the {{ component "name" }} calls of a html file.
+	components []componentCall
+	broken     bool
+
+	template *HtmlTemplate
+}
+
+// templateCall is a {{template}} or {{block}} action.
+type templateCall struct {
+	tree *parse.Tree
+	node *parse.TemplateNode
+}
+
+type templateChecker struct {
+	fsys    fs.FS
+	funcMap template.FuncMap
+
+	// paths are in the order that the view engines load them
+	paths []string
+	files map[string]*checkedFile
+	// unknown are the functions that are not in the FuncMap
+	unknown map[string]struct{}
+	// names are the paths of the html templates, keyed by the template name
+	names map[string]string
+
+	problems []TemplateProblem
+	seen     map[string]struct{}
+}
+
+func (c *templateChecker) report(file string, line int, format string, args ...any) {
+	p := TemplateProblem{File: file, Line: line, Message: fmt.Sprintf(format, args...)}
+	if _, ok := c.seen[p.String()]; ok {
+		return
+	}
+
+	c.seen[p.String()] = struct{}{}
+	c.problems = append(c.problems, p)
+}
+
+// reportErr reports an error of the parser or the loader, with the line in the error if it has one.
+func (c *templateChecker) reportErr(file string, err error) {
+	if m := templateErrorRegexp.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		c.report(file, line, "%s", m[2])
+		return
+	}
+
+	c.report(file, 1, "%s", err.Error())
+}
+
+// templateErrorRegexp matches the "template: name:line: message" errors of text/template/parse.
+var templateErrorRegexp = regexp.MustCompile(`(?s)^template: .*?:(\d+): (.*)$`)
+
+func (c *templateChecker) parseFile(path, dir string) {
+	buf, err := fs.ReadFile(c.fsys, path)
+	if err != nil {
+		c.report(path, 1, "%s", err.Error())
+		return
+	}
+
+	f := &checkedFile{path: path, dir: dir, buf: buf, trees: make(map[string]*parse.Tree)}
+	c.paths = append(c.paths, path)
+	c.files[path] = f
+
+	if dir == "pages" {
+		if f.buf, _, err = pageSource(buf); err != nil {
+			f.broken = true
+			c.report(path, 1, "%s", err.Error())
+			return
+		}
+		buf = f.buf
+	}
+
+	if len(buf) == 0 {
+		return
+	}
+
+	t := parse.New(path)
+	t.Mode = parse.SkipFuncCheck
+	if _, err := t.Parse(string(buf), "", "", f.trees); err != nil {
+		f.broken = true
+		c.reportErr(path, err)
+		return
+	}
+
+	for _, tree := range f.trees {
+		walkTemplate(tree.Root, func(n parse.Node) {
+			switch n := n.(type) {
+			case *parse.IdentifierNode:
+				if !c.isFunc(n.Ident) && (dir == "text" || (n.Ident != "component" && n.Ident != "slot")) {
+					c.unknown[n.Ident] = struct{}{}
+					c.report(path, lineOf(tree, n), "function %q is not defined", n.Ident)
+				}
+			case *parse.TemplateNode:
+				f.calls = append(f.calls, templateCall{tree: tree, node: n})
+			}
+		})
+
+		if dir != "text" {
+			f.components = append(f.components, componentCalls(tree)...)
+		}
+	}
+}
+
+// textBuiltins are the functions that text/template and html/template predefine.
+var textBuiltins = map[string]struct{}{
+	"and": {}, "call": {}, "html": {}, "index": {}, "slice": {}, "js": {}, "len": {}, "not": {}, "or": {},
+	"print": {}, "printf": {}, "println": {}, "urlquery": {},
+	"eq": {}, "ge": {}, "gt": {}, "le": {}, "lt": {}, "ne": {},
+}
+
+func (c *templateChecker) isFunc(name string) bool {
+	if _, ok := textBuiltins[name]; ok {
+		return true
+	}
+
+	_, ok := c.funcMap[name]
+	return ok
+}
+
+// loadHtml loads the html templates like HtmlViewEngine does, so the checks see the same
+// template sets as the pages at runtime. The unknown functions are stubbed, they are reported
+// already.
+func (c *templateChecker) loadHtml() {
+	fm := make(template.FuncMap, len(c.funcMap)+len(c.unknown))
+	for name, fn := range c.funcMap {
+		fm[name] = fn
+	}
+	for name := range c.unknown {
+		fm[name] = func(...any) any { return nil }
+	}
+
+	ve := &HtmlViewEngine{
+		fsys:      c.fsys,
+		app:       &App{funcMap: fm},
+		templates: make(map[string]*HtmlTemplate),
+	}
+
+	for _, dir := range []string{"components", "layouts", "pages", "views"} {
+		for _, path := range c.paths {
+			f := c.files[path]
+			if f.dir != dir || f.broken {
+				continue
+			}
+
+			var err error
+			switch dir {
+			case "layouts":
+				err = ve.loadLayout(path, nil)
+			case "pages":
+				t := NewHtmlTemplate(path[6:], path)
+				t.preprocess = pageSource
+				if err = t.Load(ve.fsys, ve.templates, fm); err == nil {
+					ve.templates[path[:len(path)-5]] = t
+				}
+			default:
+				_, err = ve.loadTemplate(path)
+			}
+
+			if err != nil {
+				c.reportErr(path, err)
+			}
+		}
+	}
+
+	c.names = make(map[string]string, len(ve.templates))
+	for _, t := range ve.templates {
+		c.names[t.name] = t.path
+		if f, ok := c.files[t.path]; ok {
+			f.template = t
+		}
+	}
+}
+
+// checkCalls reports the {{template}} calls of templates that are not defined. The html pages
+// and views are checked with the templates of their layouts and components, where the calls of
+// a layout or a component are reported in its own file.
+func (c *templateChecker) checkCalls() {
+	for _, path := range c.paths {
+		f := c.files[path]
+
+		if f.dir == "text" {
+			for _, call := range f.calls {
+				if _, ok := f.trees[call.node.Name]; !ok {
+					c.report(path, lineOf(call.tree, call.node), "template %q is not defined", call.node.Name)
+				}
+			}
+			continue
+		}
+
+		if f.template == nil || (f.dir != "pages" && f.dir != "views") {
+			continue
+		}
+
+		nt := f.template.current().template
+		for _, it := range nt.Templates() {
+			if it.Tree == nil {
+				continue
+			}
+
+			missing := func(n parse.Node, name string) {
+				file := c.names[it.Tree.ParseName]
+				if file == "" || file == path {
+					c.report(path, lineOf(it.Tree, n), "template %q is not defined", name)
+					return
+				}
+
+				c.report(file, lineOf(it.Tree, n), "template %q is not defined in %s", name, path)
+			}
+
+			walkTemplate(it.Tree.Root, func(n parse.Node) {
+				if tn, ok := n.(*parse.TemplateNode); ok && nt.Lookup(tn.Name) == nil {
+					missing(tn, tn.Name)
+				}
+			})
+
+			slotTemplates(it.Tree, func(cmd *parse.CommandNode, name string) {
+				if nt.Lookup(name) == nil {
+					missing(cmd, name)
+				}
+			})
+		}
+	}
+
+	for _, path := range c.paths {
+		for _, call := range c.files[path].components {
+			if _, ok := c.files[call.name+".html"]; !ok {
+				c.report(path, lineOf(call.tree, call.node), "component %q is not found", call.name)
+			}
+		}
+	}
+}
+
+// checkLayouts reports the layouts that are declared but don't exist.
+func (c *templateChecker) checkLayouts() {
+	for _, path := range c.paths {
+		f := c.files[path]
+		if f.dir == "text" {
+			continue
+		}
+
+		if name := layoutOf(f.buf); name != "" {
+			if _, ok := c.files["layouts/"+name+".html"]; !ok {
+				c.report(path, 1, "layout %q is not found", name)
+			}
+		}
+	}
+}
+
+// checkComponents reports the components that no {{template}}, {{block}} or {{define}} of
+// another file uses.
+func (c *templateChecker) checkComponents() {
+	used := make(map[string]struct{})
+	for _, f := range c.files {
+		self := f.path[:len(f.path)-len(filepath.Ext(f.path))]
+		for _, call := range f.calls {
+			if call.node.Name != self {
+				used[call.node.Name] = struct{}{}
+			}
+		}
+
+		for name := range f.trees {
+			if name != f.path && name != self {
+				used[name] = struct{}{}
+			}
+		}
+
+		for _, call := range f.components {
+			if call.name != self {
+				used[call.name] = struct{}{}
+			}
+		}
+	}
+
+	for _, path := range c.paths {
+		f := c.files[path]
+		if f.dir != "components" {
+			continue
+		}
+
+		if _, ok := used[path[:len(path)-5]]; !ok {
+			c.report(path, 1, "component %q is not used", path[:len(path)-5])
+		}
+	}
+}
+
+// checkDefines reports the {{define}} blocks of the templates in a layout that nothing executes.
+// A page defines them to shadow blocks of its layout, so it is a typo or a block that the
+// layout doesn't have any more.
+func (c *templateChecker) checkDefines() {
+	for _, path := range c.paths {
+		f := c.files[path]
+		if f.template == nil || f.template.parent == "" || f.dir == "components" {
+			continue
+		}
+
+		if _, ok := c.files[f.template.parent+".html"]; !ok {
+			continue
+		}
+
+		executed := make(map[string]struct{})
+		for _, it := range f.template.current().template.Templates() {
+			if it.Tree == nil {
+				continue
+			}
+			walkTemplate(it.Tree.Root, func(n parse.Node) {
+				if tn, ok := n.(*parse.TemplateNode); ok {
+					executed[tn.Name] = struct{}{}
+				}
+			})
+
+			slotTemplates(it.Tree, func(_ *parse.CommandNode, name string) {
+				executed[name] = struct{}{}
+			})
+		}
+
+		for name, tree := range f.trees {
+			if name == path {
+				continue
+			}
+
+			if _, ok := executed[name]; !ok {
+				c.report(path, lineOf(tree, tree.Root), "define %q shadows no block of layout %q", name, strings.TrimPrefix(f.template.parent, "layouts/"))
+			}
+		}
+	}
+}
+
+// walkTemplate calls fn for the {{template}} actions and the function identifiers under n.
+func walkTemplate(n parse.Node, fn func(parse.Node)) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, it := range n.Nodes {
+			walkTemplate(it, fn)
+		}
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, it := range n.Cmds {
+			walkTemplate(it, fn)
+		}
+	case *parse.CommandNode:
+		for _, it := range n.Args {
+			walkTemplate(it, fn)
+		}
+	case *parse.ActionNode:
+		walkTemplate(n.Pipe, fn)
+	case *parse.ChainNode:
+		walkTemplate(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		fn(n)
+		walkTemplate(n.Pipe, fn)
+	case *parse.IdentifierNode:
+		fn(n)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walkTemplate(n.Pipe, fn)
+	walkTemplate(n.List, fn)
+	walkTemplate(n.ElseList, fn)
+}
+
+// lineOf returns the line of the node in the file of the tree.
+func lineOf(tree *parse.Tree, n parse.Node) int {
+	location, _ := tree.ErrorContext(n) // name:line:col
+	if i := strings.LastIndexByte(location, ':'); i > 0 {
+		location = location[:i]
+	}
+
+	line, _ := strconv.Atoi(location[strings.LastIndexByte(location, ':')+1:])
+	return line
+}
@@ -0,0 +1,86 @@
+package xun
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTemplates(t *testing.T) {
+	newApp := func(fsys fstest.MapFS) *App {
+		return New(WithMux(http.NewServeMux()), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		app := newApp(fstest.MapFS{
+			"components/header.html": {Data: []byte(`<header>{{upper .Data.Title}}</header>`)},
+			"layouts/main.html":      {Data: []byte(`<html>{{block "components/header" .}}{{end}}{{block "content" .}}{{end}}</html>`)},
+			"pages/index.html":       {Data: []byte(`<!--layout:main-->{{define "content"}}{{template "item" .}}{{end}}{{define "item"}}{{len .Data}}{{end}}`)},
+			"pages/docs.html":        {Data: []byte("---\ntitle: Docs\nmethods: [GET, POST]\n---\n<!--layout:main-->\n{{define \"content\"}}{{.Data.title}}{{end}}")},
+			"views/user.html":        {Data: []byte(`<p>{{.Name}}</p>`)},
+			"text/welcome.txt":       {Data: []byte(`Hi {{lower .Name}}`)},
+		})
+
+		require.Empty(t, app.CheckTemplates())
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		app := newApp(fstest.MapFS{
+			"components/header.html": {Data: []byte("<header>\n{{template \"logo\" .}}</header>")},
+			"components/unused.html": {Data: []byte(`<aside></aside>`)},
+			"layouts/main.html":      {Data: []byte(`<html>{{block "components/header" .}}{{end}}{{block "content" .}}{{end}}</html>`)},
+			"layouts/a.html":         {Data: []byte(`<!--layout:b-->`)},
+			"layouts/b.html":         {Data: []byte(`<!--layout:a-->`)},
+			"pages/index.html":       {Data: []byte("<!--layout:main-->\n{{define \"content\"}}\n{{shout .Data}}{{end}}\n{{define \"contnet\"}}{{end}}")},
+			"pages/about.html":       {Data: []byte("<!--layout:missing-->\n<p>{{template \"bio\" .}}</p>")},
+			"pages/broken.html":      {Data: []byte("<p>\n{{if .Data}}</p>")},
+			"pages/form.html":        {Data: []byte("---\nlayout: main\n---\n{{define \"contnet\"}}{{end}}")},
+			"pages/matter.html":      {Data: []byte("---\ntitle\n---\n<p></p>")},
+			"views/user.html":        {Data: []byte("<p>{{.Name}}</p>\n\n{{template \"card\" .}}")},
+			"text/welcome.txt":       {Data: []byte("Hi\n{{nope .Name}}\n{{template \"footer\"}}")},
+		})
+
+		var problems []string
+		for _, p := range app.CheckTemplates() {
+			problems = append(problems, p.String())
+		}
+
+		require.Equal(t, []string{
+			`components/header.html:2: template "logo" is not defined in pages/form.html`,
+			`components/header.html:2: template "logo" is not defined in pages/index.html`,
+			`components/unused.html:1: component "components/unused" is not used`,
+			`layouts/a.html:1: xun: layout_cycle: layouts/a.html -> layouts/b.html -> layouts/a.html`,
+			`layouts/b.html:1: xun: layout_cycle: layouts/b.html -> layouts/a.html -> layouts/b.html`,
+			`pages/about.html:1: layout "missing" is not found`,
+			`pages/about.html:2: template "bio" is not defined`,
+			`pages/broken.html:2: unexpected EOF`,
+			`pages/form.html:4: define "contnet" shadows no block of layout "main"`,
+			`pages/index.html:3: function "shout" is not defined`,
+			`pages/index.html:4: define "contnet" shadows no block of layout "main"`,
+			`pages/matter.html:1: xun: front matter: line 2: expected key: value`,
+			`text/welcome.txt:2: function "nope" is not defined`,
+			`text/welcome.txt:3: template "footer" is not defined`,
+			`views/user.html:3: template "card" is not defined`,
+		}, problems)
+	})
+
+	t.Run("template_func", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"views/user.html": {Data: []byte(`{{greet .Name}}`)},
+		}
+
+		require.Len(t, newApp(fsys).CheckTemplates(), 1)
+
+		app := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithTemplateFunc("greet", func(s string) string { return "hi " + s }),
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		require.Empty(t, app.CheckTemplates())
+	})
+
+	t.Run("no_fsys", func(t *testing.T) {
+		require.Nil(t, New(WithMux(http.NewServeMux())).CheckTemplates())
+	})
+}
@@ -1,24 +1,64 @@
 package xun
 
 import (
+	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template/parse"
 
 	"errors"
 )
 
 // HtmlTemplate is a template that is loaded from a file system.
+//
+// The parsed template set is swapped atomically by Load, so a template can be reloaded in
+// watch mode while requests are executing it.
 type HtmlTemplate struct {
-	template *template.Template
+	parsed atomic.Pointer[htmlParsed]
 
-	name   string
-	path   string
-	layout string
+	name string
+	path string
+	// parent is the layout that the template declares, e.g. a page in layouts/admin that is
+	// in layouts/base has parent "layouts/admin" and layout "layouts/base".
+	parent string
 
 	dependencies map[string]struct{}
 	dependents   map[string]*HtmlTemplate
+
+	// preprocess converts the file to template source, e.g. markdown to html, and returns its front matter.
+	preprocess func(buf []byte) ([]byte, FrontMatter, error)
+	// reload is the reload lock of the app, that guards the components it looks up in watch mode.
+	reload *sync.RWMutex
+}
+
+// htmlParsed is the result of a Load, with everything that is read when the template is
+// executed. It is never changed after it is stored.
+type htmlParsed struct {
+	template *template.Template
+
+	// layout is the root of the layout chain, which is the template that Execute executes.
+	layout      string
+	frontMatter FrontMatter
+
+	// props are declared by <!--props: ...--> when the template is a component, see ComponentData.
+	props []componentProp
+
+	// err is the error of a file that can't be parsed, which is returned by Execute until it is fixed.
+	err error
+}
+
+var notParsed = &htmlParsed{}
+
+// current returns the parsed template set that the last Load stored.
+func (t *HtmlTemplate) current() *htmlParsed {
+	if p := t.parsed.Load(); p != nil {
+		return p
+	}
+	return notParsed
 }
 
 // NewHtmlTemplate creates a new HtmlTemplate with the given name and path.
@@ -35,32 +75,59 @@ func NewHtmlTemplate(name, path string) *HtmlTemplate {
 //
 // It parses the file, and determines the dependencies of the template.
 // The dependencies are stored in the `dependencies` field.
-func (t *HtmlTemplate) Load(fsys fs.FS, templates map[string]*HtmlTemplate, fm template.FuncMap) error { // skipcq: GO-R1005
+func (t *HtmlTemplate) Load(fsys fs.FS, templates map[string]*HtmlTemplate, fm template.FuncMap) (err error) { // skipcq: GO-R1005
 	buf, err := fs.ReadFile(fsys, t.path)
 	if err != nil {
 		return err
 	}
 
+	p := &htmlParsed{}
+	if t.preprocess != nil {
+		buf, p.frontMatter, err = t.preprocess(buf)
+		if err != nil {
+			return err
+		}
+	}
+
 	nt := template.New(t.name).Funcs(fm)
+	nt.Funcs(componentFuncs(nt, templates, t.reload))
 	dependencies := make(map[string]struct{})
+	parsed := false
 
 	defer func() {
-		t.template = nt
+		if err != nil && !parsed {
+			p.err = err
+		}
+		p.template = nt
+		t.parsed.Store(p)
 		t.dependencies = dependencies
 	}()
 
 	if len(buf) == 0 {
 		// fixed err: `template: "?" is an incomplete or empty template
 		nt, _ = nt.Parse("")
+		parsed = true
 		return nil
 	}
 
+	if p.props, err = propsOf(buf); err != nil {
+		return fmt.Errorf("template: %s:1: %w", t.path, err)
+	}
+
+	if p.props != nil {
+		buf = hideProps(buf)
+	}
+
 	nt, err = nt.Parse(string(buf))
 	if err != nil {
 		return err
 	}
+	parsed = true
 
+	trees := make([]*parse.Tree, 0, len(nt.Templates()))
 	for _, it := range nt.Templates() {
+		trees = append(trees, it.Tree)
+
 		tn := it.Name()
 		if strings.EqualFold(tn, t.name) {
 			continue
@@ -69,37 +136,40 @@ func (t *HtmlTemplate) Load(fsys fs.FS, templates map[string]*HtmlTemplate, fm t
 		dependencies[tn] = struct{}{}
 	}
 
-	layoutName := ""
-	// <!--layout:home-->   xxxxx  \n
-	if len(buf) > 11 && string(buf[0:11]) == "<!--layout:" {
-		n := len(buf) - 2
-		for i := 11; i < n; i++ {
-			if buf[i] == '-' && buf[i+1] == '-' && buf[i+2] == '>' {
-				layoutName = strings.TrimSpace(string(buf[11:i]))
-				break
-			}
+	if err := t.checkComponentCalls(trees, templates); err != nil {
+		return err
+	}
 
-			if buf[i] == '\n' {
-				break
-			}
-		}
+	if len(buf) > 11 && string(buf[0:11]) == "<!--layout:" {
+		layoutName := layoutOf(buf)
 
 		if layoutName != "" {
 			layoutName = "layouts/" + layoutName
 
+			if err := checkLayoutCycle(t, layoutName, templates); err != nil {
+				return err
+			}
+
+			p.layout = layoutName
+
 			layout, ok := templates[layoutName]
-			if ok {
+			if ok && layout.current().template != nil {
+				lp := layout.current()
+
 				// Copy all templates from layout (including block stubs) to preserve Go's standard behavior
 				// This ensures that {{block "name" .}}default{{end}} works correctly:
 				// - If page defines "name", it overrides the block
 				// - If page doesn't define "name", the default content is used
-				for _, lt := range layout.template.Templates() {
+				for _, lt := range lp.template.Templates() {
 					ltName := lt.Name()
 					// Only add templates that don't exist in the current template set
 					// This ensures page-defined templates take precedence
-					// Exception: always add the layout root template (layoutName) to ensure Execute() works
-					if nt.Lookup(ltName) == nil || ltName == layoutName {
-						_, err = nt.AddParseTree(ltName, lt.Tree)
+					// Exception: always add the layout root template (layoutName), and the root of its
+					// chain, to ensure Execute() works
+					// The tree is copied, because html/template rewrites it in place when it escapes
+					// the page, and the pages that share the layout must not see each other's rewrites.
+					if nt.Lookup(ltName) == nil || ltName == layoutName || ltName == lp.layout {
+						_, err = nt.AddParseTree(ltName, lt.Tree.Copy())
 						if err != nil {
 							return err
 						}
@@ -111,18 +181,23 @@ func (t *HtmlTemplate) Load(fsys fs.FS, templates map[string]*HtmlTemplate, fm t
 				for tn := range layout.dependencies {
 					dependencies[tn] = struct{}{}
 				}
+
+				// the layout is in a layout too, so the page is executed from the root of the chain
+				if lp.layout != "" {
+					p.layout = lp.layout
+				}
 			}
 
-			t.layout = layoutName
+			t.parent = layoutName
 		} else {
-			t.layout = ""
+			t.parent = ""
 		}
 	}
 
 	for tn := range dependencies {
 		it, ok := templates[tn]
-		if ok {
-			_, err = nt.AddParseTree(tn, it.template.Tree)
+		if ok && it.current().template != nil {
+			_, err = nt.AddParseTree(tn, it.current().template.Tree.Copy())
 			if err != nil {
 				return err
 			}
@@ -134,6 +209,53 @@ func (t *HtmlTemplate) Load(fsys fs.FS, templates map[string]*HtmlTemplate, fm t
 	return nil
 }
 
+// layoutOf returns the name of the layout that the <!--layout:name--> directive on the first
+// line of buf declares, or "" if there is none.
+func layoutOf(buf []byte) string {
+	// <!--layout:home-->   xxxxx  \n
+	if len(buf) <= 11 || string(buf[0:11]) != "<!--layout:" {
+		return ""
+	}
+
+	n := len(buf) - 2
+	for i := 11; i < n; i++ {
+		if buf[i] == '-' && buf[i+1] == '-' && buf[i+2] == '>' {
+			return strings.TrimSpace(string(buf[11:i]))
+		}
+
+		if buf[i] == '\n' {
+			break
+		}
+	}
+
+	return ""
+}
+
+// checkLayoutCycle returns an error that wraps ErrLayoutCycle if the template is in the chain
+// of the given layout, e.g. when layouts/base declares layouts/admin, which is in layouts/base.
+func checkLayoutCycle(t *HtmlTemplate, layoutName string, templates map[string]*HtmlTemplate) error {
+	chain := []string{t.path}
+	for name := layoutName; name != "" && len(chain) <= len(templates)+1; {
+		it, ok := templates[name]
+		if !ok {
+			return nil
+		}
+
+		chain = append(chain, it.path)
+		if it == t || name == t.name {
+			return errLayoutCycle(chain)
+		}
+
+		name = it.parent
+	}
+
+	return nil
+}
+
+func errLayoutCycle(chain []string) error {
+	return fmt.Errorf("%w: %s", ErrLayoutCycle, strings.Join(chain, " -> "))
+}
+
 // Reload reloads the template and all its dependents from the given file system.
 //
 // It first reloads the current template and then recursively reloads all its dependents.
@@ -157,13 +279,72 @@ func (t *HtmlTemplate) Reload(fsys fs.FS, templates map[string]*HtmlTemplate, fm
 	return nil
 }
 
+// dependsOn returns true if the template is in the layout, includes the template or calls
+// the component with the given name.
+func (t *HtmlTemplate) dependsOn(name string) bool {
+	if t.parent == name {
+		return true
+	}
+
+	if _, ok := t.dependencies[name]; ok {
+		return true
+	}
+
+	nt := t.current().template
+	if nt == nil {
+		return false
+	}
+
+	for _, it := range nt.Templates() {
+		if it.Tree == nil {
+			continue
+		}
+
+		for _, call := range componentCalls(it.Tree) {
+			if call.name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// FrontMatter returns the fields declared in the front matter of the template file.
+func (t *HtmlTemplate) FrontMatter() FrontMatter {
+	return t.current().frontMatter
+}
+
 // Execute renders the template with the given data and writes the result to the provided writer.
 //
 // If the template has a layout, it uses the layout to render the data.
 // Otherwise, it renders the data using the template itself.
 func (t *HtmlTemplate) Execute(wr io.Writer, data any) error {
-	if t.layout != "" {
-		return t.template.ExecuteTemplate(wr, t.layout, data)
+	p := t.current()
+	if p.err != nil {
+		return p.err
+	}
+
+	if p.layout != "" {
+		return p.template.ExecuteTemplate(wr, p.layout, data)
+	}
+	return p.template.Execute(wr, data)
+}
+
+// HasBlock reports whether the template can render the named block, that is a {{block}} or
+// {{define}} of the page, its layout or its components.
+func (t *HtmlTemplate) HasBlock(name string) bool {
+	nt := t.current().template
+	return nt != nil && nt.Lookup(name) != nil
+}
+
+// ExecuteBlock renders only the named block with the given data. A block that the page
+// defines shadows the block of its layout, and a block that the page doesn't define renders
+// the default content of the layout, like Execute renders them in the whole page.
+func (t *HtmlTemplate) ExecuteBlock(wr io.Writer, name string, data any) error {
+	nt := t.current().template
+	if nt == nil || nt.Lookup(name) == nil {
+		return fmt.Errorf("%w: %q in %s", ErrBlockNotFound, name, t.path)
 	}
-	return t.template.Execute(wr, data)
+	return nt.ExecuteTemplate(wr, name, data)
 }
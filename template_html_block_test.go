@@ -322,3 +322,40 @@ func TestComplexRealWorldScenario(t *testing.T) {
 	require.Contains(t, result2, "<h1>About Us</h1>")
 	require.Contains(t, result2, "We are awesome")
 }
+
+// TestBlockInSharedLayout tests that pages sharing a layout can be executed one after
+// another when the block needs a contextual escaper, e.g. inside <title>
+func TestBlockInSharedLayout(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/test.html": &fstest.MapFile{
+			Data: []byte(`<title>{{block "title" .}}Default{{end}}</title>{{block "content" .}}{{end}}`),
+		},
+		"pages/a.html": &fstest.MapFile{
+			Data: []byte(`<!--layout:test-->{{define "content"}}a{{end}}`),
+		},
+		"pages/b.html": &fstest.MapFile{
+			Data: []byte(`<!--layout:test-->{{define "title"}}B{{end}}{{define "content"}}b{{end}}`),
+		},
+	}
+
+	templates := make(map[string]*HtmlTemplate)
+	fm := template.FuncMap{}
+
+	layout := NewHtmlTemplate("layouts/test", "layouts/test.html")
+	require.NoError(t, layout.Load(fsys, templates, fm))
+	templates["layouts/test"] = layout
+
+	a := NewHtmlTemplate("pages/a", "pages/a.html")
+	require.NoError(t, a.Load(fsys, templates, fm))
+
+	b := NewHtmlTemplate("pages/b", "pages/b.html")
+	require.NoError(t, b.Load(fsys, templates, fm))
+
+	var buf strings.Builder
+	require.NoError(t, a.Execute(&buf, nil))
+	require.Equal(t, "<title>Default</title>a", buf.String())
+
+	buf.Reset()
+	require.NoError(t, b.Execute(&buf, nil))
+	require.Equal(t, "<title>B</title>b", buf.String())
+}
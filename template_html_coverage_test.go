@@ -65,7 +65,7 @@ func TestLoadLayoutNotFound(t *testing.T) {
 	require.NoError(t, err)
 
 	// Layout not found, but loading should succeed (just won't use layout)
-	require.Equal(t, "layouts/nonexistent", page.layout)
+	require.Equal(t, "layouts/nonexistent", page.current().layout)
 
 	// Execute should work (will try to use layout that doesn't exist and fail)
 	var buf strings.Builder
@@ -89,7 +89,7 @@ func TestLoadEmptyLayoutName(t *testing.T) {
 	require.NoError(t, err)
 
 	// Empty layout name should result in no layout
-	require.Empty(t, page.layout)
+	require.Empty(t, page.current().layout)
 
 	var buf strings.Builder
 	err = page.Execute(&buf, nil)
@@ -113,7 +113,7 @@ func TestLoadLayoutCommentNewline(t *testing.T) {
 	require.NoError(t, err)
 
 	// Newline should break layout parsing
-	require.Empty(t, page.layout)
+	require.Empty(t, page.current().layout)
 }
 
 // TestLoadWithDependencies tests loading templates with define blocks
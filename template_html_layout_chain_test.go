@@ -0,0 +1,94 @@
+package xun
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestLayoutChain(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`<html><title>{{block "title" .}}Site{{end}}</title><body>{{block "content" .}}base{{end}}<footer>{{block "footer" .}}(c){{end}}</footer></body></html>`)},
+		// admin is loaded before base, which it is in
+		"layouts/admin.html": {Data: []byte(`<!--layout:base-->{{define "title"}}Admin{{end}}{{define "content"}}<nav>{{block "nav" .}}menu{{end}}</nav><main>{{block "main" .}}empty{{end}}</main>{{end}}`)},
+		"layouts/zone.html":  {Data: []byte(`<!--layout:admin-->{{define "nav"}}zone{{end}}`)},
+
+		"pages/index.html":          {Data: []byte(`<!--layout:base-->{{define "content"}}home{{end}}`)},
+		"pages/admin/users.html":    {Data: []byte(`<!--layout:admin-->{{define "main"}}users{{end}}`)},
+		"pages/admin/settings.html": {Data: []byte(`<!--layout:admin-->{{define "title"}}Settings{{end}}{{define "nav"}}back{{end}}{{define "footer"}}{{end}}`)},
+		"pages/zone.html":           {Data: []byte(`<!--layout:zone-->{{define "main"}}{{.Data}}{{end}}`)},
+	}
+
+	mux := http.NewServeMux()
+
+	var logs bytes.Buffer
+	app := New(WithMux(mux), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	app.Start()
+	defer app.Close()
+
+	require.NotContains(t, logs.String(), "level=ERROR")
+
+	t.Run("inherit", func(t *testing.T) {
+		require.Equal(t, `<html><title>Site</title><body>home<footer>(c)</footer></body></html>`, serve(mux, http.MethodGet, "/", "Accept", "text/html").Body.String())
+		require.Equal(t, `<html><title>Admin</title><body><nav>menu</nav><main>users</main><footer>(c)</footer></body></html>`, serve(mux, http.MethodGet, "/admin/users", "Accept", "text/html").Body.String())
+		// a page overrides the blocks of every level
+		require.Equal(t, `<html><title>Settings</title><body><nav>back</nav><main>empty</main><footer></footer></body></html>`, serve(mux, http.MethodGet, "/admin/settings", "Accept", "text/html").Body.String())
+		require.Equal(t, `<html><title>Admin</title><body><nav>zone</nav><main></main><footer>(c)</footer></body></html>`, serve(mux, http.MethodGet, "/zone", "Accept", "text/html").Body.String())
+	})
+
+	var ve *HtmlViewEngine
+	for _, it := range app.engines {
+		if h, ok := it.(*HtmlViewEngine); ok {
+			ve = h
+		}
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		// a change of the root reloads every page in the chain
+		fsys["layouts/base.html"] = &fstest.MapFile{Data: []byte(`<html><body>{{block "content" .}}base{{end}}<footer>{{block "footer" .}}v2{{end}}</footer></body></html>`)}
+		require.NoError(t, ve.FileChanged(fsys, app, fsnotify.Event{Name: "layouts/base.html", Op: fsnotify.Write}))
+
+		require.Equal(t, `<html><body>home<footer>v2</footer></body></html>`, serve(mux, http.MethodGet, "/", "Accept", "text/html").Body.String())
+		require.Equal(t, `<html><body><nav>menu</nav><main>users</main><footer>v2</footer></body></html>`, serve(mux, http.MethodGet, "/admin/users", "Accept", "text/html").Body.String())
+		require.Equal(t, `<html><body><nav>zone</nav><main></main><footer>v2</footer></body></html>`, serve(mux, http.MethodGet, "/zone", "Accept", "text/html").Body.String())
+
+		// a change of a layout in the middle reloads the pages below it
+		fsys["layouts/admin.html"] = &fstest.MapFile{Data: []byte(`<!--layout:base-->{{define "content"}}<aside>{{block "nav" .}}menu{{end}}</aside>{{block "main" .}}{{end}}{{end}}`)}
+		require.NoError(t, ve.FileChanged(fsys, app, fsnotify.Event{Name: "layouts/admin.html", Op: fsnotify.Write}))
+
+		require.Equal(t, `<html><body><aside>menu</aside>users<footer>v2</footer></body></html>`, serve(mux, http.MethodGet, "/admin/users", "Accept", "text/html").Body.String())
+		require.Equal(t, `<html><body><aside>zone</aside><footer>v2</footer></body></html>`, serve(mux, http.MethodGet, "/zone", "Accept", "text/html").Body.String())
+	})
+
+	t.Run("cycle_on_reload", func(t *testing.T) {
+		fsys["layouts/base.html"] = &fstest.MapFile{Data: []byte(`<!--layout:zone--><html>{{block "content" .}}{{end}}</html>`)}
+		err := ve.FileChanged(fsys, app, fsnotify.Event{Name: "layouts/base.html", Op: fsnotify.Write})
+		require.ErrorIs(t, err, ErrLayoutCycle)
+		require.EqualError(t, err, "xun: layout_cycle: layouts/base.html -> layouts/zone.html -> layouts/admin.html -> layouts/base.html")
+	})
+}
+
+func TestLayoutCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/a.html":    {Data: []byte(`<!--layout:b--><a>{{block "content" .}}{{end}}</a>`)},
+		"layouts/b.html":    {Data: []byte(`<!--layout:a--><b>{{block "content" .}}{{end}}</b>`)},
+		"layouts/self.html": {Data: []byte(`<!--layout:self-->{{block "content" .}}{{end}}`)},
+		"layouts/main.html": {Data: []byte(`<!--layout:missing--><main>{{block "content" .}}{{end}}</main>`)},
+	}
+
+	var logs bytes.Buffer
+	app := New(WithMux(http.NewServeMux()), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	defer app.Close()
+
+	require.Contains(t, logs.String(), "xun: layout_cycle: layouts/a.html -> layouts/b.html -> layouts/a.html")
+	require.Contains(t, logs.String(), "xun: layout_cycle: layouts/b.html -> layouts/a.html -> layouts/b.html")
+	require.Contains(t, logs.String(), "xun: layout_cycle: layouts/self.html -> layouts/self.html")
+
+	// a missing parent is not a cycle, the layout is still loaded
+	require.NotContains(t, logs.String(), "layouts/main.html")
+}
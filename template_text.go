@@ -3,20 +3,39 @@ package xun
 import (
 	"io"
 	"io/fs"
+	"sync/atomic"
 	"text/template"
 )
 
 func NewTextTemplate(t *template.Template) *TextTemplate {
-	return &TextTemplate{template: t}
+	it := &TextTemplate{}
+	it.parsed.Store(&textParsed{template: t})
+	return it
 }
 
 // TextTemplate represents a text template that can be loaded from a file system and executed with data.
+//
+// The parsed template is swapped atomically by Load, so a template can be reloaded in watch
+// mode while requests are executing it.
 type TextTemplate struct {
+	parsed atomic.Pointer[textParsed]
+
+	name string
+}
+
+// textParsed is the result of a Load. It is never changed after it is stored.
+type textParsed struct {
 	template *template.Template
+	mime     MimeType
+	charset  string
+}
 
-	name    string
-	mime    MimeType
-	charset string
+// current returns the parsed template that the last Load stored.
+func (t *TextTemplate) current() *textParsed {
+	if p := t.parsed.Load(); p != nil {
+		return p
+	}
+	return &textParsed{}
 }
 
 // Load loads the template from the given file system.
@@ -30,9 +49,11 @@ func (t *TextTemplate) Load(fsys fs.FS, fm template.FuncMap) error {
 
 	if len(buf) == 0 {
 		nt, _ = nt.Parse("")
-		t.template = nt
-		t.mime = MimeType{Type: "text", SubType: "plain"}
-		t.charset = "; charset=utf-8"
+		t.parsed.Store(&textParsed{
+			template: nt,
+			mime:     MimeType{Type: "text", SubType: "plain"},
+			charset:  "; charset=utf-8",
+		})
 		return nil
 	}
 
@@ -41,8 +62,9 @@ func (t *TextTemplate) Load(fsys fs.FS, fm template.FuncMap) error {
 		return err
 	}
 
-	t.mime, t.charset = GetMimeType(t.name, buf)
-	t.template = nt
+	p := &textParsed{template: nt}
+	p.mime, p.charset = GetMimeType(t.name, buf)
+	t.parsed.Store(p)
 
 	return nil
 }
@@ -54,5 +76,5 @@ func (t *TextTemplate) Reload(fsys fs.FS, fm template.FuncMap) error {
 
 // Execute executes the template with the given data and writes the result to the given writer.
 func (t *TextTemplate) Execute(wr io.Writer, data any) error {
-	return t.template.Execute(wr, data)
+	return t.current().template.Execute(wr, data)
 }
@@ -1,6 +1,8 @@
 package xun
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
 	"log/slog"
 	"path/filepath"
@@ -22,6 +24,7 @@ type HtmlViewEngine struct {
 	app  *App
 
 	templates map[string]*HtmlTemplate
+	viewers   map[string]*HtmlViewer
 }
 
 // Load loads all templates from the given file system.
@@ -32,6 +35,10 @@ func (ve *HtmlViewEngine) Load(fsys fs.FS, app *App) {
 		ve.templates = map[string]*HtmlTemplate{}
 	}
 
+	if ve.viewers == nil {
+		ve.viewers = map[string]*HtmlViewer{}
+	}
+
 	ve.fsys = fsys
 	ve.app = app
 
@@ -43,19 +50,29 @@ func (ve *HtmlViewEngine) Load(fsys fs.FS, app *App) {
 
 // FileChanged is called when a file has been changed.
 //
-// It is used to reload templates when they have been changed.
+// It is used to reload templates when they have been changed, and to unregister them when
+// they have been removed. A rename is a removal of the old file and a creation of the new one.
 func (ve *HtmlViewEngine) FileChanged(fsys fs.FS, app *App, event fsnotify.Event) error { // skipcq: RVV-B0012
 
-	if event.Has(fsnotify.Remove) || !strings.EqualFold(filepath.Ext(event.Name), ".html") {
+	if !strings.EqualFold(filepath.Ext(event.Name), ".html") {
 		return nil
 	}
 
 	name := event.Name[:len(event.Name)-5]
 
+	if event.Has(fsnotify.Remove) {
+		return ve.removeTemplate(event.Name)
+	}
+
 	if event.Has(fsnotify.Write) {
 		t, ok := ve.templates[name]
 		if ok {
-			return t.Reload(fsys, ve.templates, app.funcMap)
+			if err := t.Reload(fsys, ve.templates, app.funcMap); err != nil || !strings.HasPrefix(event.Name, "pages/") {
+				return err
+			}
+
+			// the front matter may be changed
+			return routePage(app, ve.viewers, name[6:], t)
 		}
 	} else if event.Has(fsnotify.Create) {
 
@@ -75,6 +92,88 @@ func (ve *HtmlViewEngine) FileChanged(fsys fs.FS, app *App, event fsnotify.Event
 
 }
 
+// removeTemplate unregisters the template of a removed file and the page that renders it, and
+// leaves a tombstone for a removed view. Its dependents are reloaded without it, and the ones
+// that still depend on it are reported, e.g. the pages in a removed layout.
+func (ve *HtmlViewEngine) removeTemplate(path string) error {
+	name := path[:len(path)-5]
+
+	t, ok := ve.templates[name]
+	if !ok {
+		return nil
+	}
+
+	delete(ve.templates, name)
+	for _, it := range ve.templates {
+		if it.dependents[t.name] == t {
+			delete(it.dependents, t.name)
+		}
+	}
+
+	if strings.HasPrefix(path, "pages/") {
+		removePage(ve.app, ve.viewers, path[6:len(path)-5])
+	} else if strings.HasPrefix(path, "views/") {
+		// the viewer stays registered as a tombstone that renders ErrViewNotFound
+		view, locale := ve.app.splitLocale(name)
+		if v, ok := ve.viewers[view]; ok {
+			v.unsetTemplate(locale)
+		}
+	}
+
+	var errs []error
+	for _, d := range t.dependents {
+		err := d.Reload(ve.fsys, ve.templates, ve.app.funcMap)
+		if errors.Is(err, fs.ErrNotExist) {
+			// it has been removed too
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		} else if d.dependsOn(name) {
+			errs = append(errs, fmt.Errorf("xun: %s depends on %s: %w", d.path, path, fs.ErrNotExist))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// removePage unregisters the template of pages/{file}, and the page if it has no templates left.
+func removePage(app *App, viewers map[string]*HtmlViewer, file string) {
+	name, locale := app.splitLocale(file)
+
+	v, ok := viewers["pages/"+name]
+	if !ok {
+		return
+	}
+
+	if !v.unsetTemplate(locale) {
+		if locale == "" {
+			// the locale variants are served on GET without the front matter of the default template
+			app.routePage(name, v, nil) // nolint: errcheck
+		}
+		return
+	}
+
+	delete(viewers, "pages/"+name)
+	for _, pattern := range app.pageRoutes(name, v) {
+		app.removeViewer(pattern, name, v)
+	}
+}
+
+// pagePattern returns the route pattern of the page with the given name, e.g. "admin/index" is
+// served on "GET /admin/{$}".
+func pagePattern(name string) string {
+	pattern := name
+	if pattern == "index" || strings.HasSuffix(pattern, "/index") { // remove it, because index.html will be redirected to ./ in http.ServeFileFS
+		pattern = pattern[:len(pattern)-5]
+	}
+
+	_, _, pattern = splitFile(pattern)
+
+	return pattern
+}
+
 func (ve *HtmlViewEngine) loadFiles(dir string, process func(path string) error) {
 	fs.WalkDir(ve.fsys, dir, func(path string, d fs.DirEntry, _ error) error { // nolint: errcheck
 		if !strings.EqualFold(filepath.Ext(path), ".html") {
@@ -98,6 +197,7 @@ func (ve *HtmlViewEngine) loadTemplate(path string) (*HtmlTemplate, error) {
 	name := path[:len(path)-5]
 
 	t := NewHtmlTemplate(name, path)
+	t.reload = &ve.app.reloadMu
 
 	if err := t.Load(ve.fsys, ve.templates, ve.app.funcMap); err != nil {
 		return nil, err
@@ -117,21 +217,66 @@ func (ve *HtmlViewEngine) loadTemplate(path string) (*HtmlTemplate, error) {
 
 func (ve *HtmlViewEngine) loadLayouts() {
 	ve.loadFiles("layouts", func(path string) error {
-		_, err := ve.loadTemplate(path)
-		return err
+		return ve.loadLayout(path, nil)
 	})
 }
 
+// loadLayout loads the layout that a layout declares before the layout itself, so the
+// templates of the whole chain are copied into it. chain is the layouts that are waiting
+// for it, and a layout that is in its own chain is reported as ErrLayoutCycle.
+func (ve *HtmlViewEngine) loadLayout(path string, chain []string) error {
+	if _, ok := ve.templates[path[:len(path)-5]]; ok {
+		return nil
+	}
+
+	chain = append(chain, path)
+	for _, it := range chain[:len(chain)-1] {
+		if it == path {
+			return errLayoutCycle(chain)
+		}
+	}
+
+	buf, err := fs.ReadFile(ve.fsys, path)
+	if err != nil {
+		return err
+	}
+
+	if parent := layoutOf(buf); parent != "" {
+		// a missing or broken parent is reported when it is loaded itself
+		if err := ve.loadLayout("layouts/"+parent+".html", chain); errors.Is(err, ErrLayoutCycle) {
+			return err
+		}
+	}
+
+	_, err = ve.loadTemplate(path)
+	return err
+}
+
 func (ve *HtmlViewEngine) loadPages() {
+	// the locale variants are loaded after their default templates, so a page is routed by the
+	// front matter of its default template, e.g. pages/about.en.html sorts before pages/about.html
+	var variants []string
 	ve.loadFiles("pages", func(path string) error { // nolint: errcheck
+		if _, locale := ve.app.splitLocale(path[:len(path)-5]); locale != "" {
+			variants = append(variants, path)
+			return nil
+		}
 		return ve.loadPage(path)
 	})
+
+	for _, path := range variants {
+		if err := ve.loadPage(path); err != nil {
+			ve.app.logger.Error("xun: load html", slog.String("path", path), slog.Any("err", err))
+		}
+	}
 }
 
 func (ve *HtmlViewEngine) loadPage(path string) error {
 	name := path[6:] // delete prefix  "pages/"
 
 	t := NewHtmlTemplate(name, path)
+	t.preprocess = pageSource
+	t.reload = &ve.app.reloadMu
 
 	if err := t.Load(ve.fsys, ve.templates, ve.app.funcMap); err != nil {
 		return err
@@ -140,18 +285,29 @@ func (ve *HtmlViewEngine) loadPage(path string) error {
 	// delete file extension ".html"
 	ve.templates[path[:len(path)-5]] = t
 
-	if strings.HasSuffix(path, "/index.html") { // remove it, because index.html will be redirected to ./ in http.ServeFileFS
-		name = name[:len(name)-10]
+	return routePage(ve.app, ve.viewers, path[6:len(path)-5], t)
+}
+
+// routePage sets the template of pages/{file} on the viewer of its page, and registers the
+// page on the routes that the front matter of its default template declares. A page that is
+// registered already is routed again when its default template is loaded or reloaded.
+func routePage(app *App, viewers map[string]*HtmlViewer, file string, t *HtmlTemplate) error {
+	// pages/about.zh-CN.html is the zh-CN variant of pages/about.html
+	name, locale := app.splitLocale(file)
+
+	v, ok := viewers["pages/"+name]
+	if !ok {
+		v = &HtmlViewer{reload: &app.reloadMu}
+		viewers["pages/"+name] = v
 	}
 
-	_, _, pattern := splitFile(name)
-	pattern = strings.TrimSuffix(pattern, ".html")
+	v.setTemplate(locale, t)
 
-	ve.app.HandlePage(pattern, path[6:len(path)-5], &HtmlViewer{
-		template: t,
-	})
+	if ok && locale != "" {
+		return nil
+	}
 
-	return nil
+	return app.routePage(name, v, v.frontMatter())
 }
 
 func (ve *HtmlViewEngine) loadViews() {
@@ -167,9 +323,17 @@ func (ve *HtmlViewEngine) loadView(path string) error {
 		return err
 	}
 
-	ve.app.viewers[path[:len(path)-5]] = &HtmlViewer{
-		template: t,
+	// views/email/welcome.fr.html is the fr variant of views/email/welcome.html
+	name, locale := ve.app.splitLocale(path[:len(path)-5])
+
+	v, ok := ve.viewers[name]
+	if !ok {
+		v = &HtmlViewer{reload: &ve.app.reloadMu}
+		ve.viewers[name] = v
+		ve.app.viewers[name] = v
 	}
 
+	v.setTemplate(locale, t)
+
 	return nil
 }
@@ -0,0 +1,197 @@
+package xun
+
+import (
+	"bytes"
+	"html"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/yaitoo/xun/fsnotify"
+	"github.com/yaitoo/xun/markdown"
+)
+
+// MarkdownViewEngine is a view engine that renders "pages/**/*.md" as html pages.
+//
+// A markdown page may start with a front matter block:
+//
+//	---
+//	title: Getting Started
+//	layout: docs
+//	author: xun
+//	---
+//
+// The html of the page is defined as the "content" template, and the title as the "title"
+// template, of the layout from "layouts/" (or Layout if the page declares none). Without
+// any layout the html is the whole page. The front matter is the data of the page, so a
+// layout can read custom fields like {{ .Data.author }}.
+//
+// The layouts and components are the ones that HtmlViewEngine loads, so it should be
+// registered after HtmlViewEngine. A layout change reloads the pages rendered in it.
+type MarkdownViewEngine struct {
+	// Layout is the layout of the pages that don't declare one in their front matter.
+	Layout string
+
+	fsys fs.FS
+	app  *App
+
+	// html owns the components and layouts. It is a private engine that only loads them
+	// if the app has no HtmlViewEngine that has been loaded.
+	html    *HtmlViewEngine
+	private bool
+
+	templates map[string]*HtmlTemplate
+	viewers   map[string]*HtmlViewer
+}
+
+// Load loads all markdown pages from the given file system.
+func (ve *MarkdownViewEngine) Load(fsys fs.FS, app *App) {
+	if ve.templates == nil {
+		ve.templates = map[string]*HtmlTemplate{}
+	}
+
+	if ve.viewers == nil {
+		ve.viewers = map[string]*HtmlViewer{}
+	}
+
+	ve.fsys = fsys
+	ve.app = app
+
+	ve.html = nil
+	for _, it := range app.engines {
+		if h, ok := it.(*HtmlViewEngine); ok && h.templates != nil {
+			ve.html = h
+			break
+		}
+	}
+
+	ve.private = ve.html == nil
+	if ve.private {
+		ve.html = &HtmlViewEngine{
+			fsys:      fsys,
+			app:       app,
+			templates: map[string]*HtmlTemplate{},
+			viewers:   map[string]*HtmlViewer{},
+		}
+		ve.html.loadComponents()
+		ve.html.loadLayouts()
+	}
+
+	// the locale variants are loaded after their default templates, as the html pages are
+	var variants []string
+	fs.WalkDir(fsys, "pages", func(path string, d fs.DirEntry, _ error) error { // nolint: errcheck
+		if d == nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+
+		if _, locale := app.splitLocale(path[:len(path)-3]); locale != "" {
+			variants = append(variants, path)
+			return nil
+		}
+
+		if err := ve.loadPage(path); err != nil {
+			app.logger.Error("xun: load markdown", slog.String("path", path), slog.Any("err", err))
+		}
+		return nil
+	})
+
+	for _, path := range variants {
+		if err := ve.loadPage(path); err != nil {
+			app.logger.Error("xun: load markdown", slog.String("path", path), slog.Any("err", err))
+		}
+	}
+}
+
+// FileChanged reloads a markdown page when it is changed, loads it when it is created, and
+// unregisters it when it is removed.
+func (ve *MarkdownViewEngine) FileChanged(fsys fs.FS, app *App, event fsnotify.Event) error { // skipcq: RVV-B0012
+	// the private engine reloads the layouts and the pages that depend on them
+	if ve.private && (strings.HasPrefix(event.Name, "components/") || strings.HasPrefix(event.Name, "layouts/")) {
+		return ve.html.FileChanged(fsys, app, event)
+	}
+
+	if !strings.HasPrefix(event.Name, "pages/") || !strings.EqualFold(filepath.Ext(event.Name), ".md") {
+		return nil
+	}
+
+	if event.Has(fsnotify.Remove) {
+		t, ok := ve.templates[event.Name]
+		if ok {
+			delete(ve.templates, event.Name)
+			for _, it := range ve.html.templates {
+				if it.dependents[t.name] == t {
+					delete(it.dependents, t.name)
+				}
+			}
+			removePage(app, ve.viewers, event.Name[6:len(event.Name)-3])
+		}
+	} else if event.Has(fsnotify.Write) {
+		t, ok := ve.templates[event.Name]
+		if ok {
+			if err := t.Reload(fsys, ve.html.templates, app.funcMap); err != nil {
+				return err
+			}
+
+			// the front matter may be changed
+			return routePage(app, ve.viewers, event.Name[6:len(event.Name)-3], t)
+		}
+	} else if event.Has(fsnotify.Create) {
+		return ve.loadPage(event.Name)
+	}
+
+	return nil
+}
+
+func (ve *MarkdownViewEngine) loadPage(path string) error {
+	t := NewHtmlTemplate(path[6:], path)
+	t.preprocess = ve.convert
+	t.reload = &ve.app.reloadMu
+
+	if err := t.Load(ve.fsys, ve.html.templates, ve.app.funcMap); err != nil {
+		return err
+	}
+
+	ve.templates[path] = t
+
+	return routePage(ve.app, ve.viewers, path[6:len(path)-3], t)
+}
+
+// convert renders the markdown file as the template source of a page in its layout. The
+// html can't contain any template action, because every "{" is written as "&#123;".
+func (ve *MarkdownViewEngine) convert(buf []byte) ([]byte, FrontMatter, error) {
+	fm, body, err := parseFrontMatter(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fm == nil {
+		fm = FrontMatter{}
+	}
+
+	content := bytes.ReplaceAll(markdown.Convert(body), []byte("{"), []byte("&#123;"))
+
+	layout := fm.GetString("layout")
+	if layout == "" {
+		layout = ve.Layout
+	}
+
+	if layout == "" {
+		return content, fm, nil
+	}
+
+	var src bytes.Buffer
+	src.WriteString("<!--layout:" + layout + "-->")
+
+	if title := fm.GetString("title"); title != "" {
+		src.WriteString(`{{define "title"}}`)
+		src.WriteString(strings.ReplaceAll(html.EscapeString(title), "{", "&#123;"))
+		src.WriteString(`{{end}}`)
+	}
+
+	src.WriteString(`{{define "content"}}`)
+	src.Write(content)
+	src.WriteString(`{{end}}`)
+
+	return src.Bytes(), fm, nil
+}
@@ -0,0 +1,117 @@
+package xun
+
+import (
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yaitoo/xun/fsnotify"
+)
+
+func TestMarkdownViewEngine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/docs.html": {Data: []byte(`<title>{{ block "title" . }}Docs{{ end }}</title>` +
+			`<main>{{ block "content" . }}{{ end }}</main><footer>{{ .Data.author }}</footer>`)},
+		"layouts/blog.html": {Data: []byte(`<article>{{ block "content" . }}{{ end }}</article>`)},
+		"pages/docs/intro.md": {Data: []byte(`---
+title: Intro <1>
+author: xun
+---
+# Getting {{ .Started }}
+
+<script>alert(1)</script>
+
+| a | b |
+|---|---|
+| 1 | 2 |
+
+` + "```go\nfmt.Println(\"{{ x }}\")\n```\n")},
+		"pages/docs/intro.zh-CN.md": {Data: []byte("---\ntitle: 介绍\n---\n# 介绍\n")},
+		"pages/posts/hello.md":      {Data: []byte("---\nlayout: blog\n---\nHello *world*\n")},
+		"pages/plain.md":            {Data: []byte("# Plain\n")},
+		"pages/index.md":            {Data: []byte("---\nlayout: docs\n---\nhome\n")},
+		"pages/broken.md":           {Data: []byte("---\ntitle: broken\n")},
+	}
+
+	mux := http.NewServeMux()
+
+	ve := &MarkdownViewEngine{Layout: "docs"}
+	app := New(WithMux(mux), WithFsys(fsys), WithLocales("zh-CN"), WithViewEngines(&HtmlViewEngine{}, ve))
+	app.Start()
+	defer app.Close()
+
+	t.Run("page_should_render_in_layout", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/docs/intro")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, `<title>Intro &lt;1&gt;</title><main>`+
+			`<h1 id="getting-started">Getting &#123;&#123; .Started }}</h1>`+"\n"+
+			`<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>`+"\n"+
+			"<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"+
+			`<pre><code class="language-go">fmt.Println(&#34;&#123;&#123; x }}&#34;)`+"\n</code></pre>\n"+
+			`</main><footer>xun</footer>`, rw.Body.String())
+	})
+
+	t.Run("locale_variant_should_work", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/docs/intro", "Accept-Language", "zh-CN")
+		require.Equal(t, `<title>介绍</title><main><h1 id="介绍">介绍</h1>`+"\n"+`</main><footer></footer>`, rw.Body.String())
+	})
+
+	t.Run("front_matter_layout_should_work", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/posts/hello")
+		require.Equal(t, "<article><p>Hello <em>world</em></p>\n</article>", rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/")
+		require.Equal(t, "<title>Docs</title><main><p>home</p>\n</main><footer></footer>", rw.Body.String())
+	})
+
+	t.Run("broken_page_should_not_be_registered", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/broken")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+
+	t.Run("hot_reload_should_work", func(t *testing.T) {
+		fsys["pages/posts/hello.md"] = &fstest.MapFile{Data: []byte("---\nlayout: blog\n---\nHello **xun**\n")}
+		require.NoError(t, ve.FileChanged(fsys, app, fsnotify.Event{Name: "pages/posts/hello.md", Op: fsnotify.Write}))
+		rw := serve(mux, http.MethodGet, "/posts/hello")
+		require.Equal(t, "<article><p>Hello <strong>xun</strong></p>\n</article>", rw.Body.String())
+
+		// a layout change reloads the markdown pages rendered in it
+		fsys["layouts/blog.html"] = &fstest.MapFile{Data: []byte(`<section>{{ block "content" . }}{{ end }}</section>`)}
+		for _, e := range app.engines {
+			require.NoError(t, e.FileChanged(fsys, app, fsnotify.Event{Name: "layouts/blog.html", Op: fsnotify.Write}))
+		}
+		rw = serve(mux, http.MethodGet, "/posts/hello")
+		require.Equal(t, "<section><p>Hello <strong>xun</strong></p>\n</section>", rw.Body.String())
+
+		fsys["pages/posts/new.md"] = &fstest.MapFile{Data: []byte("---\nlayout: blog\n---\nnew\n")}
+		require.NoError(t, ve.FileChanged(fsys, app, fsnotify.Event{Name: "pages/posts/new.md", Op: fsnotify.Create}))
+		rw = serve(mux, http.MethodGet, "/posts/new")
+		require.Equal(t, "<section><p>new</p>\n</section>", rw.Body.String())
+
+		require.NoError(t, ve.FileChanged(fsys, app, fsnotify.Event{Name: "pages/posts/new.md", Op: fsnotify.Remove}))
+		require.NoError(t, ve.FileChanged(fsys, app, fsnotify.Event{Name: "pages/posts/new.html", Op: fsnotify.Write}))
+	})
+}
+
+func TestMarkdownViewEngineWithoutHtmlViewEngine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html": {Data: []byte(`<body>{{ block "content" . }}{{ end }}</body>`)},
+		"pages/about.md":    {Data: []byte("---\nlayout: main\n---\nabout\n")},
+		"pages/plain.md":    {Data: []byte("plain\n")},
+	}
+
+	mux := http.NewServeMux()
+
+	ve := &MarkdownViewEngine{}
+	app := New(WithMux(mux), WithFsys(fsys), WithViewEngines(ve))
+	app.Start()
+	defer app.Close()
+
+	require.Equal(t, "<body><p>about</p>\n</body>", serve(mux, http.MethodGet, "/about").Body.String())
+	require.Equal(t, "<p>plain</p>\n", serve(mux, http.MethodGet, "/plain").Body.String())
+
+	fsys["layouts/main.html"] = &fstest.MapFile{Data: []byte(`<html>{{ block "content" . }}{{ end }}</html>`)}
+	require.NoError(t, ve.FileChanged(fsys, app, fsnotify.Event{Name: "layouts/main.html", Op: fsnotify.Write}))
+	require.Equal(t, "<html><p>about</p>\n</html>", serve(mux, http.MethodGet, "/about").Body.String())
+}
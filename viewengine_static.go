@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"io"
 	"io/fs"
-	"path"
 	"reflect"
 	"strings"
 
@@ -14,6 +13,9 @@ import (
 // StaticViewEngine is a view engine that serves static files from a file system.
 type StaticViewEngine struct {
 	isEmbedFsys bool
+
+	// bundles are the files of the bundles in bundles.json by their names, e.g. "/assets/app.js"
+	bundles map[string][]string
 }
 
 // Load loads all static files from the given file system and registers them with the application.
@@ -37,6 +39,10 @@ func (ve *StaticViewEngine) Load(fsys fs.FS, app *App) {
 
 		return nil
 	})
+
+	if app.assetPipeline {
+		ve.loadBundles(fsys, app)
+	}
 }
 
 // FileChanged handles file changes for the given file system and updates the
@@ -45,26 +51,47 @@ func (ve *StaticViewEngine) Load(fsys fs.FS, app *App) {
 // If the file changed is a Create event and the path is in the "public" directory,
 // it will be registered with the application.
 //
-// If the file changed is a Write/Remove event and the path is in the "public"
-// directory, nothing will be done.
+// If the file changed is a Remove event and the path is in the "public" directory,
+// its route and its asset url serve 404 until it is created again.
+//
+// With the asset pipeline, the bundles are rebuilt if bundles.json or any of their files is changed.
 func (ve *StaticViewEngine) FileChanged(fsys fs.FS, app *App, event fsnotify.Event) error {
-	// Nothing should be updated for Write/Remove events.
-	if strings.HasPrefix(event.Name, "public/") && (event.Has(fsnotify.Create) || event.Has(fsnotify.Write)) {
+	if app.assetPipeline && event.Name == bundlesFile {
+		ve.loadBundles(fsys, app)
+		return nil
+	}
+
+	if !strings.HasPrefix(event.Name, "public/") {
+		return nil
+	}
+
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
 		ve.handle(fsys, app, event.Name)
+	} else if event.Has(fsnotify.Remove) {
+		ve.remove(app, event.Name)
+	}
+
+	if app.assetPipeline {
+		ve.buildBundles(fsys, app, "/"+strings.TrimPrefix(event.Name, "public/"))
 	}
 
 	return nil
 }
 
-func (ve *StaticViewEngine) handle(fsys fs.FS, app *App, path string) {
-
+// filePattern returns the name that public/{path} is served as, e.g. "admin/" for public/admin/index.html.
+func filePattern(path string) string {
 	pattern := path
 
 	if strings.HasSuffix(pattern, "/index.html") { // remove it, because index.html will be redirected to ./ in http.ServeFileFS
 		pattern = pattern[:len(pattern)-10]
 	}
 
-	pattern = strings.TrimPrefix(pattern, "public/")
+	return strings.TrimPrefix(pattern, "public/")
+}
+
+func (ve *StaticViewEngine) handle(fsys fs.FS, app *App, path string) {
+
+	pattern := filePattern(path)
 
 	app.HandleFile(pattern, NewFileViewer(fsys, path, ve.isEmbedFsys, "", ""))
 
@@ -83,13 +110,38 @@ func (ve *StaticViewEngine) handleAssetUrl(fsys fs.FS, app *App, fileName, patte
 	defer f.Close()            // nolint: errcheck
 
 	buf, _ := io.ReadAll(f) // nolint: errcheck
-	etag := ComputeETag(bytes.NewReader(buf))
-	ext := path.Ext(pattern)
 
-	assetURL := strings.TrimRight(pattern, ext) + "-" + strings.Trim(etag, "\"") + ext
+	if app.assetPipeline {
+		ve.handleBuiltAsset(app, pattern, minifyAsset(pattern, buf))
+		return
+	}
+
+	etag := ComputeETag(bytes.NewReader(buf))
+	assetURL := fingerprintURL(pattern, etag)
 
 	app.HandleFile(assetURL,
 		NewFileViewer(fsys, fileName, ve.isEmbedFsys, etag, cacheControl))
 
 	app.AssetURLs["/"+pattern] = "/" + assetURL
 }
+
+func (ve *StaticViewEngine) remove(app *App, path string) {
+	pattern := filePattern(path)
+
+	names := []string{pattern}
+	if assetURL, ok := app.AssetURLs["/"+pattern]; ok {
+		names = append(names, assetURL[1:])
+		delete(app.AssetURLs, "/"+pattern)
+		delete(app.AssetIntegrity, "/"+pattern)
+	}
+
+	for _, name := range names {
+		v, ok := app.viewers[name]
+		if !ok {
+			continue
+		}
+
+		_, _, pat := splitFile(name)
+		app.removeViewer(pat, name, v)
+	}
+}
@@ -3,6 +3,7 @@ package xun
 import (
 	"io/fs"
 	"log/slog"
+	"path/filepath"
 	"strings"
 
 	"github.com/yaitoo/xun/fsnotify"
@@ -14,6 +15,7 @@ type TextViewEngine struct {
 	fsys      fs.FS
 	app       *App
 	templates map[string]*TextTemplate
+	viewers   map[string]*TextViewer
 }
 
 // Load walks the file system and loads all text-based templates that match the TextViewEngine's pattern.
@@ -23,13 +25,17 @@ func (ve *TextViewEngine) Load(fsys fs.FS, app *App) {
 		ve.templates = map[string]*TextTemplate{}
 	}
 
+	if ve.viewers == nil {
+		ve.viewers = map[string]*TextViewer{}
+	}
+
 	ve.fsys = fsys
 	ve.app = app
 
 	fs.WalkDir(fsys, "text", func(path string, d fs.DirEntry, err error) error { // nolint: errcheck
 		if d != nil && !d.IsDir() {
 			if err := ve.loadText(path); err != nil {
-				slog.Error("text: load text", slog.String("path", path), slog.Any("err", err))
+				app.logger.Error("text: load text", slog.String("path", path), slog.Any("err", err))
 			}
 			return nil
 		}
@@ -44,16 +50,29 @@ func (ve *TextViewEngine) loadText(path string) error {
 		return err
 	}
 
-	ve.app.viewers[path] = NewTextViewer(t)
+	// text/mail/welcome.fr.txt is the fr variant of text/mail/welcome.txt
+	ext := filepath.Ext(path)
+	name, locale := ve.app.splitLocale(path[:len(path)-len(ext)])
+	name += ext
+
+	v, ok := ve.viewers[name]
+	if !ok {
+		v = &TextViewer{reload: &ve.app.reloadMu}
+		ve.viewers[name] = v
+		ve.app.viewers[name] = v
+	}
 
-	return err
+	v.setTemplate(locale, t)
+
+	return nil
 }
 
 // FileChanged is called when a file in the file system has changed. It checks if the change is a
 // file creation event in the "text/" directory, and if so, calls the handle method to update the
-// corresponding view in the app.
+// corresponding view in the app. A removed file leaves a tombstone that renders ErrViewNotFound.
 func (ve *TextViewEngine) FileChanged(fsys fs.FS, app *App, event fsnotify.Event) error { // skipcq: RVV-B0012
 	if event.Has(fsnotify.Remove) {
+		ve.removeText(event.Name)
 		return nil
 	}
 
@@ -75,6 +94,25 @@ func (ve *TextViewEngine) FileChanged(fsys fs.FS, app *App, event fsnotify.Event
 	return nil
 }
 
+// removeText unregisters the template of a removed file, and its view if it has no templates left.
+func (ve *TextViewEngine) removeText(path string) {
+	if _, ok := ve.templates[path]; !ok {
+		return
+	}
+
+	delete(ve.templates, path)
+
+	ext := filepath.Ext(path)
+	name, locale := ve.app.splitLocale(path[:len(path)-len(ext)])
+	name += ext
+
+	// the viewer stays registered as a tombstone, so c.View(data, name) serves 404 instead of
+	// falling back to the route viewer, and a re-created file revives it
+	if v, ok := ve.viewers[name]; ok {
+		v.unsetTemplate(locale)
+	}
+}
+
 func (ve *TextViewEngine) loadTemplate(path string) (*TextTemplate, error) {
 
 	t := &TextTemplate{
@@ -188,7 +188,10 @@ func TestWatchOnText(t *testing.T) {
 
 	require.Equal(t, fsys["text/sitemap.xml"].Data, buf)
 
-	// deleted not be handled, robots.txt still be there
+	// deleted, the view is kept as a tombstone, so the route serves 404 instead of its own viewer
+	_, ok := app.viewers["text/robots.txt"]
+	require.True(t, ok)
+
 	req, err = http.NewRequest("GET", srv.URL+"/robots.txt", nil)
 	req.Header.Set("Accept", "text/plain, */*")
 	require.NoError(t, err)
@@ -199,7 +202,7 @@ func TestWatchOnText(t *testing.T) {
 	require.NoError(t, err)
 	resp.Body.Close()
 
-	require.Equal(t, http.StatusOK, resp.StatusCode)
-	require.Equal(t, buf, []byte("User-agent: *"))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "View Not Found", string(buf))
 
 }
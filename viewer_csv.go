@@ -0,0 +1,261 @@
+package xun
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"time"
+)
+
+// CsvFilename is the routing metadata key of the file name that CsvViewer sends in
+// the Content-Disposition header.
+const CsvFilename = "csv_filename"
+
+// ErrCsvUnsupportedType is returned when CsvViewer can't write the given data as rows.
+var ErrCsvUnsupportedType = errors.New("xun: csv: unsupported type")
+
+// CsvViewer is a viewer that writes the given data as CSV to the http.ResponseWriter.
+//
+// It renders []struct (columns are named by `csv:"name"` tags or field names, `csv:"-"`
+// skips a field), [][]string, and iter.Seq or channels of structs or []string. A slice is
+// buffered like the other viewers, but an iter.Seq or a channel is streamed and flushed
+// every FlushSize bytes or FlushInterval, until the request is cancelled. A row that fails
+// after data has been sent aborts the response.
+type CsvViewer struct {
+	// Comma is the field delimiter. It is ',' if it is zero, and a '\t' switches the
+	// MIME type to "text/tab-separated-values".
+	Comma rune
+	// BOM writes a UTF-8 byte order mark first, so Excel detects the encoding.
+	BOM bool
+
+	FlushSize     int
+	FlushInterval time.Duration
+}
+
+var (
+	csvViewerMime = &MimeType{Type: "text", SubType: "csv"}
+	tsvViewerMime = &MimeType{Type: "text", SubType: "tab-separated-values"}
+
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// MimeType returns the MIME type of the CSV content.
+//
+// It returns "text/csv", or "text/tab-separated-values" if Comma is a tab.
+func (v *CsvViewer) MimeType() *MimeType {
+	if v.Comma == '\t' {
+		return tsvViewerMime
+	}
+	return csvViewerMime
+}
+
+// Render renders the given data as CSV to the http.ResponseWriter.
+//
+// It sets the Content-Type header, and the Content-Disposition header if the route
+// has CsvFilename metadata.
+func (v *CsvViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
+	header := ctx.Response.Header()
+	header.Set("Content-Type", v.MimeType().String()+"; charset=utf-8")
+
+	if ctx.Routing.Options != nil {
+		if name := ctx.Routing.Options.GetString(CsvFilename); name != "" {
+			header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		}
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(data)
+	if elem, ok := streamElem(val.Type()); ok {
+		// a stream is not ranged on HEAD, it may never end
+		if ctx.IsHead() {
+			return nil
+		}
+
+		sw := newStreamWriter(ctx.Response, v.FlushSize, v.FlushInterval)
+		defer sw.Flush()
+
+		err := v.write(sw, elem, func(fn func(reflect.Value) bool) error {
+			return rangeStream(ctx.Request.Context(), val, sw, fn)
+		}, true)
+		return abortStream(ctx, sw, err)
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return fmt.Errorf("%w: %T", ErrCsvUnsupportedType, data)
+	}
+
+	err := v.write(buf, val.Type().Elem(), func(fn func(reflect.Value) bool) error {
+		for i := 0; i < val.Len(); i++ {
+			if !fn(val.Index(i)) {
+				break
+			}
+		}
+		return nil
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	return writeBuffer(ctx, buf)
+}
+
+// write writes the rows that each yields. The Writer is flushed after every row when it
+// streams, so the streamWriter decides when the client gets the data.
+func (v *CsvViewer) write(w io.Writer, elem reflect.Type, each func(fn func(reflect.Value) bool) error, stream bool) error {
+	if v.BOM {
+		if _, err := w.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if v.Comma != 0 {
+		cw.Comma = v.Comma
+	}
+
+	var (
+		fields []csvField
+		wrote  bool
+		err    error
+	)
+
+	writeHeader := func(t reflect.Type) error {
+		fields = csvFields(t)
+		header := make([]string, len(fields))
+		for i, f := range fields {
+			header[i] = f.name
+		}
+		return cw.Write(header)
+	}
+
+	writeRow := func(row reflect.Value) bool {
+		for row.Kind() == reflect.Interface || row.Kind() == reflect.Pointer {
+			if row.IsNil() {
+				return true
+			}
+			row = row.Elem()
+		}
+
+		var record []string
+		switch {
+		case row.Kind() == reflect.Struct:
+			if !wrote {
+				if err = writeHeader(row.Type()); err != nil {
+					return false
+				}
+			}
+
+			record = make([]string, len(fields))
+			for i, f := range fields {
+				record[i] = csvFormat(row.Field(f.index))
+			}
+		case row.Kind() == reflect.Slice || row.Kind() == reflect.Array:
+			record = make([]string, row.Len())
+			for i := range record {
+				record[i] = csvFormat(row.Index(i))
+			}
+		default:
+			err = fmt.Errorf("%w: %s", ErrCsvUnsupportedType, row.Type())
+			return false
+		}
+
+		wrote = true
+		if err = cw.Write(record); err != nil {
+			return false
+		}
+
+		if stream {
+			cw.Flush()
+			err = cw.Error()
+		}
+		return err == nil
+	}
+
+	e := each(writeRow)
+
+	// an empty []struct still gets its header row
+	if e == nil && err == nil && !wrote && elem.Kind() == reflect.Struct {
+		err = writeHeader(elem)
+	}
+
+	// the rows that have been written are sent before an error is reported
+	cw.Flush()
+
+	if e != nil {
+		return e
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+type csvField struct {
+	name  string
+	index int
+}
+
+// csvFields returns the exported fields of t that are not tagged with `csv:"-"`.
+func csvFields(t reflect.Type) []csvField {
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Tag.Get("csv")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, csvField{name: name, index: i})
+	}
+	return fields
+}
+
+// csvFormat formats a cell. A nil pointer is an empty cell, and a value that implements
+// encoding.TextMarshaler (e.g. time.Time) is written in its text form.
+func csvFormat(v reflect.Value) string {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			buf, err := m.MarshalText()
+			if err == nil {
+				return string(buf)
+			}
+		}
+		v = v.Elem()
+	}
+
+	if !v.CanInterface() {
+		return fmt.Sprint(v)
+	}
+
+	it := v.Interface()
+	if m, ok := it.(encoding.TextMarshaler); ok {
+		if buf, err := m.MarshalText(); err == nil {
+			return string(buf)
+		}
+	}
+
+	return fmt.Sprint(it)
+}
@@ -0,0 +1,248 @@
+package xun
+
+import (
+	"bytes"
+	"io"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type csvUser struct {
+	ID      int    `csv:"id"`
+	Name    string `csv:"name"`
+	Email   string
+	Secret  string `csv:"-"`
+	private string
+	Created time.Time  `csv:"created"`
+	Manager *string    `csv:"manager"`
+	Deleted *time.Time `csv:"deleted"`
+}
+
+func TestCsvViewer(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	boss := "Ada, Jr."
+
+	users := []csvUser{
+		{ID: 1, Name: "Ada", Email: "ada@example.com", Secret: "x", private: "y", Created: created},
+		{ID: 2, Name: `Grace "Amazing"`, Email: "grace@example.com", Created: created, Manager: &boss},
+	}
+
+	render := func(v *CsvViewer, method string, data any, ro *RoutingOptions) (*httptest.ResponseRecorder, error) {
+		rw := httptest.NewRecorder()
+		ctx := &Context{
+			Request:  httptest.NewRequest(method, "/", nil),
+			Response: NewResponseWriter(rw),
+			Routing:  Routing{Options: ro},
+		}
+		err := v.Render(ctx, data)
+		return rw, err
+	}
+
+	t.Run("struct_slice_should_work", func(t *testing.T) {
+		rw, err := render(&CsvViewer{}, http.MethodGet, users, nil)
+		require.NoError(t, err)
+		require.Equal(t, "text/csv; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Empty(t, rw.Header().Get("Content-Disposition"))
+		require.Equal(t, "id,name,Email,created,manager,deleted\n"+
+			"1,Ada,ada@example.com,2024-01-02T03:04:05Z,,\n"+
+			"2,\"Grace \"\"Amazing\"\"\",grace@example.com,2024-01-02T03:04:05Z,\"Ada, Jr.\",\n", rw.Body.String())
+	})
+
+	t.Run("pointer_slice_should_work", func(t *testing.T) {
+		rw, err := render(&CsvViewer{}, http.MethodGet, []*csvUser{nil, &users[0]}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "id,name,Email,created,manager,deleted\n"+
+			"1,Ada,ada@example.com,2024-01-02T03:04:05Z,,\n", rw.Body.String())
+	})
+
+	t.Run("empty_struct_slice_should_write_header", func(t *testing.T) {
+		rw, err := render(&CsvViewer{}, http.MethodGet, []csvUser{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "id,name,Email,created,manager,deleted\n", rw.Body.String())
+	})
+
+	t.Run("records_should_work", func(t *testing.T) {
+		rw, err := render(&CsvViewer{Comma: ';'}, http.MethodGet, [][]string{{"a", "b;c"}, {"1", "2"}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "a;\"b;c\"\n1;2\n", rw.Body.String())
+
+		rw, err = render(&CsvViewer{}, http.MethodGet, [][]any{{"a", 1, true}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "a,1,true\n", rw.Body.String())
+	})
+
+	t.Run("tsv_and_bom_should_work", func(t *testing.T) {
+		v := &CsvViewer{Comma: '\t', BOM: true}
+		require.Equal(t, "text/tab-separated-values", v.MimeType().String())
+
+		rw, err := render(v, http.MethodGet, [][]string{{"a", "b"}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "text/tab-separated-values; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Equal(t, "\xEF\xBB\xBFa\tb\n", rw.Body.String())
+	})
+
+	t.Run("filename_should_work", func(t *testing.T) {
+		ro := &RoutingOptions{}
+		WithMetadata(CsvFilename, "users 2024.csv")(ro)
+
+		rw, err := render(&CsvViewer{}, http.MethodHead, users, ro)
+		require.NoError(t, err)
+		require.Equal(t, `attachment; filename="users 2024.csv"`, rw.Header().Get("Content-Disposition"))
+		require.Empty(t, rw.Body.String())
+	})
+
+	t.Run("seq_should_stream", func(t *testing.T) {
+		var seq iter.Seq[csvUser] = func(yield func(csvUser) bool) {
+			for _, u := range users {
+				if !yield(u) {
+					return
+				}
+			}
+		}
+
+		rw, err := render(&CsvViewer{FlushSize: 1}, http.MethodGet, seq, nil)
+		require.NoError(t, err)
+		require.True(t, rw.Flushed)
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "id,name,Email,created,manager,deleted\n"+
+			"1,Ada,ada@example.com,2024-01-02T03:04:05Z,,\n"+
+			"2,\"Grace \"\"Amazing\"\"\",grace@example.com,2024-01-02T03:04:05Z,\"Ada, Jr.\",\n", rw.Body.String())
+
+		var empty iter.Seq[csvUser] = func(yield func(csvUser) bool) {}
+		rw, err = render(&CsvViewer{}, http.MethodGet, empty, nil)
+		require.NoError(t, err)
+		require.Equal(t, "id,name,Email,created,manager,deleted\n", rw.Body.String())
+
+		var records iter.Seq[[]string] = func(yield func([]string) bool) {
+			_ = yield([]string{"a", "b"}) && yield([]string{"c", "d"})
+		}
+		rw, err = render(&CsvViewer{}, http.MethodGet, records, nil)
+		require.NoError(t, err)
+		require.Equal(t, "a,b\nc,d\n", rw.Body.String())
+	})
+
+	t.Run("unsupported_type_should_fail", func(t *testing.T) {
+		_, err := render(&CsvViewer{}, http.MethodGet, "text", nil)
+		require.ErrorIs(t, err, ErrCsvUnsupportedType)
+
+		_, err = render(&CsvViewer{}, http.MethodGet, []int{1, 2}, nil)
+		require.ErrorIs(t, err, ErrCsvUnsupportedType)
+
+		var seq iter.Seq[int] = func(yield func(int) bool) {
+			for i := 0; i < 10; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+		_, err = render(&CsvViewer{}, http.MethodGet, seq, nil)
+		require.ErrorIs(t, err, ErrCsvUnsupportedType)
+
+		rw, err := render(&CsvViewer{}, http.MethodGet, nil, nil)
+		require.NoError(t, err)
+		require.Empty(t, rw.Body.String())
+	})
+
+	t.Run("stream_error_should_abort", func(t *testing.T) {
+		var seq iter.Seq[any] = func(yield func(any) bool) {
+			_ = yield([]string{"a", "b"}) && yield(1) && yield([]string{"c", "d"})
+		}
+
+		var rw *httptest.ResponseRecorder
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			rw, _ = render(&CsvViewer{}, http.MethodGet, seq, nil)
+		})
+		require.Nil(t, rw)
+	})
+}
+
+func TestCsvStreamAborted(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	app := New(WithMux(mux), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	app.Get("/users.csv", func(c *Context) error {
+		var seq iter.Seq[any] = func(yield func(any) bool) {
+			_ = yield([]string{"a", "b"}) && yield(1) && yield([]string{"c", "d"})
+		}
+		return c.View(seq)
+	}, WithViewer(&CsvViewer{}))
+
+	app.Start()
+	defer app.Close()
+
+	resp, err := client.Get(srv.URL + "/users.csv")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("X-Log-Id"))
+
+	buf, err := io.ReadAll(resp.Body)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Equal(t, "a,b\n", string(buf))
+	// the error is logged after the flushed rows are sent, so wait for the handler to return
+	srv.Close()
+	require.Contains(t, logs.String(), `msg="xun: stream" err="xun: csv: unsupported type: int"`)
+}
+
+func TestCsvNegotiation(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	app := New(WithMux(mux))
+
+	app.Get("/users", func(c *Context) error {
+		return c.View([]csvUser{{ID: 1, Name: "Ada"}})
+	}, WithViewer(&JsonViewer{}, &CsvViewer{}), WithMetadata(CsvFilename, "users.csv"))
+
+	app.Start()
+	defer app.Close()
+
+	tests := []struct {
+		name        string
+		url         string
+		accept      string
+		contentType string
+	}{
+		{name: "default", url: "/users", contentType: "application/json"},
+		{name: "accept", url: "/users", accept: "text/csv", contentType: "text/csv; charset=utf-8"},
+		{name: "format", url: "/users?format=csv", accept: "application/json", contentType: "text/csv; charset=utf-8"},
+		{name: "format_json", url: "/users?format=JSON", accept: "text/csv", contentType: "application/json"},
+		{name: "unknown_format", url: "/users?format=unknown", accept: "text/csv", contentType: "text/csv; charset=utf-8"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+test.url, nil)
+			require.NoError(t, err)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, test.contentType, resp.Header.Get("Content-Type"))
+
+			if test.contentType != "application/json" {
+				buf, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Equal(t, `attachment; filename=users.csv`, resp.Header.Get("Content-Disposition"))
+				require.Equal(t, "id,name,Email,created,manager,deleted\n1,Ada,,0001-01-01T00:00:00Z,,\n", string(buf))
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package xun
 
 import (
+	"bytes"
 	"io"
 	"io/fs"
 	"net/http"
+	"time"
 )
 
 // NewFileViewer creates a new FileViewer instance.
@@ -28,6 +30,18 @@ func NewFileViewer(fsys fs.FS, path string, isEmbed bool, etag, cache string) *F
 	return v
 }
 
+// newContentViewer creates a FileViewer that serves the content that the asset pipeline built,
+// as the file with the given name.
+func newContentViewer(name string, data []byte, etag, cache string) *FileViewer {
+	return &FileViewer{
+		path:    name,
+		data:    data,
+		modTime: time.Now(),
+		etag:    etag,
+		cache:   cache,
+	}
+}
+
 // FileViewer is a viewer that serves a file from a file system.
 //
 // You can use it to serve a file from a file system, or to serve a file from
@@ -49,6 +63,10 @@ type FileViewer struct {
 	fsys fs.FS
 	path string
 
+	// data is served instead of the file if fsys is nil
+	data    []byte
+	modTime time.Time
+
 	etag  string
 	cache string
 }
@@ -76,6 +94,15 @@ func (v *FileViewer) Render(ctx *Context, data any) error {
 }
 
 func (v *FileViewer) serveContent(w http.ResponseWriter, r *http.Request) error {
+	if v.fsys == nil {
+		if v.cache != "" {
+			w.Header().Set("Cache-Control", v.cache)
+		}
+
+		http.ServeContent(w, r, v.path, v.modTime, bytes.NewReader(v.data))
+		return nil
+	}
+
 	f, err := v.fsys.Open(v.path)
 
 	if err != nil {
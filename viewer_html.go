@@ -1,7 +1,8 @@
 package xun
 
 import (
-	"net/http"
+	"log/slog"
+	"sync"
 )
 
 // HtmlViewer is a viewer that renders a html template.
@@ -10,8 +11,13 @@ import (
 // The template is loaded from the file system when the viewer is created.
 // The `Render` method renders the template with the given data and writes the
 // result to the http.ResponseWriter.
+//
+// Locale variants such as `pages/about.zh-CN.html` are kept next to the default
+// template, and the best variant for the request is picked by `Context.Locales`.
 type HtmlViewer struct {
 	template *HtmlTemplate
+	locales  map[string]*HtmlTemplate
+	reload   *sync.RWMutex
 }
 
 var htmlViewerMime = &MimeType{Type: "text", SubType: "html"}
@@ -26,19 +32,159 @@ func (*HtmlViewer) MimeType() *MimeType {
 // Render renders the template with the given data and writes the result to the http.ResponseWriter.
 //
 // This implementation uses the `HtmlTemplate.Execute` method to render the template.
-// The rendered result is written to the http.ResponseWriter.
+// The rendered result is written to the http.ResponseWriter. If data is nil, the
+// front matter of the template is used as data.
+//
+// If the route has WithBlockHeader and the request names a block of the template in that
+// header, only the block is rendered.
+//
+// The page is buffered, so an error returns before anything is written, unless the route
+// has WithStreaming. A HEAD request renders the page for the Content-Length header only,
+// and a streaming page is not rendered at all.
+//
+// In watch mode, the live reload script is injected before </body> of the page.
 func (v *HtmlViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
 	var err error
 	ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if ctx.Request.Method != http.MethodHead {
-		buf := BufPool.Get()
-		defer BufPool.Put(buf)
+	t := v.resolve(ctx)
+	if t == nil {
+		// it has been removed in watch mode
+		return ErrViewNotFound
+	}
+
+	if fm := t.FrontMatter(); data == nil && fm != nil {
+		data = fm
+	}
 
-		err = v.template.Execute(buf, ViewModel{TempData: ctx.TempData, Data: data})
-		if err != nil {
-			return err
+	if ctx.Routing.Options != nil {
+		if h := ctx.Routing.Options.GetString(BlockHeader); h != "" {
+			ctx.Response.Header().Add("Vary", h)
+			if block := ctx.Request.Header.Get(h); block != "" && t.HasBlock(block) {
+				return renderBlock(ctx, t, block, data)
+			}
 		}
-		_, err = buf.WriteTo(ctx.Response)
 	}
-	return err
+
+	vm := ViewModel{TempData: ctx.TempData, Data: data}
+	if ctx.Routing.Options != nil && ctx.Routing.Options.Get(HtmlStreaming) == true {
+		if ctx.IsHead() {
+			return nil
+		}
+		return v.stream(ctx, t, vm)
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	err = t.Execute(&flushWriter{w: buf, script: ctx.App.liveReloadScript()}, vm)
+	if err != nil {
+		return err
+	}
+	return writeBuffer(ctx, buf)
+}
+
+// RenderBlock renders only the named block of the template, e.g. the list of a page that
+// htmx swaps in, and writes the result to the http.ResponseWriter. It returns an error that
+// wraps ErrBlockNotFound if the template has no such block.
+func (v *HtmlViewer) RenderBlock(ctx *Context, data any, block string) error {
+	ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := v.resolve(ctx)
+	if t == nil {
+		// it has been removed in watch mode
+		return ErrViewNotFound
+	}
+
+	if fm := t.FrontMatter(); data == nil && fm != nil {
+		data = fm
+	}
+
+	return renderBlock(ctx, t, block, data)
+}
+
+func renderBlock(ctx *Context, t *HtmlTemplate, block string, data any) error {
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := t.ExecuteBlock(&flushWriter{w: buf}, block, ViewModel{TempData: ctx.TempData, Data: data}); err != nil {
+		return err
+	}
+
+	return writeBuffer(ctx, buf)
+}
+
+// stream executes the template into the response, flushing it at {{ flush }} and </head>.
+// An error before the first byte is returned as usual. After it, the error is logged with a
+// log id, which is also written as a html comment, and ErrCancelled ends the response.
+func (v *HtmlViewer) stream(ctx *Context, t *HtmlTemplate, vm ViewModel) error {
+	sw := newStreamWriter(ctx.Response, 0, 0)
+	fw := &flushWriter{w: sw, flush: sw.Flush, script: ctx.App.liveReloadScript()}
+
+	err := t.Execute(fw, vm)
+	if err == nil {
+		sw.Flush()
+		return nil
+	}
+
+	if fw.written == 0 {
+		return err
+	}
+
+	logID := nextLogID()
+	logger := slog.Default()
+	if ctx.App != nil {
+		logger = ctx.App.logger
+	}
+	logger.Error("xun: stream", slog.Any("err", err), slog.String("logid", logID))
+
+	sw.Write([]byte("<!-- xun: logid=" + logID + " -->")) // nolint: errcheck
+	sw.Flush()
+
+	return ErrCancelled
+}
+
+// setTemplate sets the default template when locale is empty, otherwise the template of the locale variant.
+func (v *HtmlViewer) setTemplate(locale string, t *HtmlTemplate) {
+	if locale == "" {
+		v.template = t
+		return
+	}
+
+	if v.locales == nil {
+		v.locales = make(map[string]*HtmlTemplate)
+	}
+	v.locales[locale] = t
+}
+
+// frontMatter returns the front matter of the default template, or nil if it has none.
+func (v *HtmlViewer) frontMatter() FrontMatter {
+	if v.template == nil {
+		return nil
+	}
+	return v.template.FrontMatter()
+}
+
+// unsetTemplate removes the default template when locale is empty, otherwise the template of
+// the locale variant. It returns true if the viewer has no templates left.
+func (v *HtmlViewer) unsetTemplate(locale string) bool {
+	if locale == "" {
+		v.template = nil
+	} else {
+		delete(v.locales, locale)
+	}
+
+	return v.template == nil && len(v.locales) == 0
+}
+
+// resolve returns the template of the best matched locale variant, and writes the
+// Content-Language and Vary headers when the viewer has any locale variants.
+func (v *HtmlViewer) resolve(ctx *Context) *HtmlTemplate {
+	defer rlock(v.reload)()
+
+	if len(v.locales) == 0 {
+		return v.template
+	}
+
+	locale, t := pickLocale(ctx.Locales(), ctx.defaultLocale(), v.template, v.locales)
+	writeLocaleHeaders(ctx, locale)
+	return t
 }
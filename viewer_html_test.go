@@ -1,10 +1,18 @@
 package xun
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"html/template"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -18,10 +26,11 @@ func TestInvalidHtmlTemplate(t *testing.T) {
 	l, err := template.New("invalid").Parse(`<p>Hello, {{.Name}}!</p><p>Age: {{.Age}}</p>`)
 	require.NoError(t, err)
 
+	ht := &HtmlTemplate{}
+	ht.parsed.Store(&htmlParsed{template: l})
+
 	v := &HtmlViewer{
-		template: &HtmlTemplate{
-			template: l,
-		},
+		template: ht,
 	}
 
 	r := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -35,3 +44,224 @@ func TestInvalidHtmlTemplate(t *testing.T) {
 	err = v.Render(ctx, Data{})
 	require.NotNil(t, err)
 }
+
+type streamPage struct {
+	next chan struct{}
+}
+
+func (p *streamPage) Wait() string {
+	<-p.next
+	return "rows"
+}
+
+func (*streamPage) Fail() (string, error) {
+	return "", errors.New("boom")
+}
+
+func TestHtmlViewerStreaming(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html":    {Data: []byte(`<html><head><title>{{block "title" .}}{{end}}</title></head><body>{{block "content" .}}{{end}}</body></html>`)},
+		"views/dashboard.html": {Data: []byte(`<!--layout:main-->{{define "title"}}Dashboard{{end}}{{define "content"}}<h1>Dashboard</h1>{{flush}}<p>{{.Data.Wait}}</p>{{end}}`)},
+		"views/broken.html":    {Data: []byte(`<!--layout:main-->{{define "content"}}<p>{{.Data.Fail}}</p>{{end}}`)},
+		"views/invalid.html":   {Data: []byte(`{{.Data.Fail}}<html></html>`)},
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	app := New(WithMux(mux), WithFsys(fsys), WithCompressor(&GzipCompressor{}),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	app.Get("/buffered", func(c *Context) error {
+		page := &streamPage{next: make(chan struct{})}
+		close(page.next)
+		return c.View(page, "views/dashboard")
+	})
+
+	app.Get("/dashboard", func(c *Context) error {
+		return c.View(c.Get("page"), "views/dashboard")
+	}, WithStreaming())
+
+	app.Get("/broken", func(c *Context) error {
+		return c.View(&streamPage{}, "views/broken")
+	}, WithStreaming())
+
+	app.Get("/invalid", func(c *Context) error {
+		return c.View(&streamPage{}, "views/invalid")
+	}, WithStreaming())
+
+	page := &streamPage{next: make(chan struct{})}
+	app.Use(func(next HandleFunc) HandleFunc {
+		return func(c *Context) error {
+			c.Set("page", page)
+			return next(c)
+		}
+	})
+
+	app.Start()
+	defer app.Close()
+
+	t.Run("buffered", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/buffered", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "<html><head><title>Dashboard</title></head><body><h1>Dashboard</h1><p>rows</p></body></html>", rw.Body.String())
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		// the chunks are read from a server while the page is still rendering
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/dashboard", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/html")
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+		gr, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		r := bufio.NewReader(gr)
+
+		// the head and the html before {{ flush }} arrive while the page is still rendering
+		head, err := r.ReadString('>')
+		for err == nil && !strings.HasSuffix(head, "<h1>Dashboard</h1>") {
+			var s string
+			s, err = r.ReadString('>')
+			head += s
+		}
+		require.NoError(t, err)
+		require.Equal(t, "<html><head><title>Dashboard</title></head><body><h1>Dashboard</h1>", head)
+
+		close(page.next)
+
+		rest, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "<p>rows</p></body></html>", string(rest))
+	})
+
+	t.Run("error_after_first_byte", func(t *testing.T) {
+		logs.Reset()
+
+		rw := serve(mux, http.MethodGet, "/broken", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Empty(t, rw.Header().Get("X-Log-Id"))
+
+		require.True(t, strings.HasPrefix(rw.Body.String(), "<html><head><title></title></head><body><p>"))
+		require.Contains(t, rw.Body.String(), "<!-- xun: logid=")
+		require.Contains(t, logs.String(), "xun: stream")
+		require.Contains(t, logs.String(), "boom")
+	})
+
+	t.Run("error_before_first_byte", func(t *testing.T) {
+		logs.Reset()
+
+		rw := serve(mux, http.MethodGet, "/invalid", "Accept", "text/html")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.NotEmpty(t, rw.Header().Get("X-Log-Id"))
+		require.Contains(t, logs.String(), "xun: handle")
+	})
+}
+
+func TestViewBlock(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html": {Data: []byte(`<html><title>{{block "title" .}}Todos{{end}}</title><body>{{block "content" .}}{{end}}{{block "footer" .}}<footer>default</footer>{{end}}</body></html>`)},
+		"pages/todos.html":  {Data: []byte(`<!--layout:main-->{{define "content"}}<h1>Todos</h1><ul id="list">{{block "list" .}}{{range .Data}}<li>{{.}}</li>{{end}}{{end}}</ul>{{end}}`)},
+		"views/card.html":   {Data: []byte(`<div>{{block "body" .}}<p>{{.Data}}</p>{{end}}</div>`)},
+	}
+
+	mux := http.NewServeMux()
+
+	var logs bytes.Buffer
+	app := New(WithMux(mux), WithFsys(fsys), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	items := []string{"a", "<b>"}
+
+	app.Get("/todos", func(c *Context) error {
+		return c.View(items)
+	}, WithBlockHeader("X-Block"))
+
+	app.Get("/blocks/{name}", func(c *Context) error {
+		return c.ViewBlock(items, c.Request.URL.Query().Get("view"), c.Request.PathValue("name"))
+	})
+
+	app.Start()
+	defer app.Close()
+
+	page := `<html><title>Todos</title><body><h1>Todos</h1><ul id="list"><li>a</li><li>&lt;b&gt;</li></ul><footer>default</footer></body></html>`
+
+	t.Run("view_block", func(t *testing.T) {
+		for _, it := range []struct {
+			path string
+			want string
+		}{
+			{"/blocks/list?view=pages/todos", `<li>a</li><li>&lt;b&gt;</li>`},
+			{"/blocks/list?view=todos", `<li>a</li><li>&lt;b&gt;</li>`},
+			// the page defines content, and shadows the block of the layout
+			{"/blocks/content?view=pages/todos", `<h1>Todos</h1><ul id="list"><li>a</li><li>&lt;b&gt;</li></ul>`},
+			// the page doesn't define them, so the defaults of the layout are rendered
+			{"/blocks/title?view=pages/todos", `Todos`},
+			{"/blocks/footer?view=pages/todos", `<footer>default</footer>`},
+			{"/blocks/body?view=views/card", `<p>[a &lt;b&gt;]</p>`},
+		} {
+			rw := serve(mux, http.MethodGet, it.path, "Accept", "text/html")
+			require.Equal(t, http.StatusOK, rw.Code, it.path)
+			require.Equal(t, "text/html; charset=utf-8", rw.Header().Get("Content-Type"), it.path)
+			require.Equal(t, it.want, rw.Body.String(), it.path)
+		}
+
+		// the page is not changed by rendering its blocks
+		rw := serve(mux, http.MethodGet, "/todos", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, page, rw.Body.String())
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/blocks/list?view=pages/missing", "Accept", "text/html")
+		require.Equal(t, http.StatusNotFound, rw.Code)
+
+		logs.Reset()
+		rw = serve(mux, http.MethodGet, "/blocks/missing?view=pages/todos", "Accept", "text/html")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Contains(t, logs.String(), `xun: block_not_found: \"missing\" in pages/todos.html`)
+	})
+
+	t.Run("block_header", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/todos", "Accept", "text/html", "X-Block", "list")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, `<li>a</li><li>&lt;b&gt;</li>`, rw.Body.String())
+		require.Equal(t, "X-Block", rw.Header().Get("Vary"))
+
+		rw = serve(mux, http.MethodGet, "/todos", "Accept", "text/html", "X-Block", "unknown")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, page, rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/todos", "Accept", "text/html")
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, page, rw.Body.String())
+		require.Equal(t, "X-Block", rw.Header().Get("Vary"))
+	})
+}
+
+func TestExecuteBlock(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/index.html": {Data: []byte(`<p>{{block "name" .}}{{.}}{{end}}</p>`)},
+	}
+
+	p := NewHtmlTemplate("index", "pages/index.html")
+	require.NoError(t, p.Load(fsys, map[string]*HtmlTemplate{}, template.FuncMap{}))
+
+	require.True(t, p.HasBlock("name"))
+	require.False(t, p.HasBlock("missing"))
+
+	var buf strings.Builder
+	require.NoError(t, p.ExecuteBlock(&buf, "name", "<xun>"))
+	require.Equal(t, "&lt;xun&gt;", buf.String())
+
+	err := p.ExecuteBlock(&buf, "missing", nil)
+	require.ErrorIs(t, err, ErrBlockNotFound)
+}
@@ -1,13 +1,21 @@
 package xun
 
 import (
-	"net/http"
+	"reflect"
+	"time"
 )
 
 // JsonViewer is a viewer that writes the given data as JSON to the http.ResponseWriter.
 //
 // It sets the Content-Type header to "application/json".
 type JsonViewer struct {
+	// Stream writes iter.Seq and channel data as a JSON array element by element, and
+	// flushes it every FlushSize bytes or FlushInterval, instead of encoding the whole
+	// payload into a buffer first.
+	Stream bool
+
+	FlushSize     int
+	FlushInterval time.Duration
 }
 
 var jsonViewerMime = &MimeType{Type: "application", SubType: "json"}
@@ -22,19 +30,63 @@ func (*JsonViewer) MimeType() *MimeType {
 // Render renders the given data as JSON to the http.ResponseWriter.
 //
 // It sets the Content-Type header to "application/json".
-func (*JsonViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
-	var err error
+func (v *JsonViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
 	ctx.Response.Header().Set("Content-Type", "application/json")
-	if ctx.Request.Method != http.MethodHead {
-		buf := BufPool.Get()
-		defer BufPool.Put(buf)
+	if v.Stream && data != nil {
+		val := reflect.ValueOf(data)
+		if _, ok := streamElem(val.Type()); ok {
+			if ctx.IsHead() {
+				return nil
+			}
+			return v.stream(ctx, val)
+		}
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := Json.NewEncoder(buf).Encode(data); err != nil {
+		return err
+	}
+	return writeBuffer(ctx, buf)
+}
+
+// stream writes the elements of an iter.Seq or a channel as a JSON array. An element that
+// fails to encode aborts the response, see abortStream.
+func (v *JsonViewer) stream(ctx *Context, val reflect.Value) error {
+	sw := newStreamWriter(ctx.Response, v.FlushSize, v.FlushInterval)
+	defer sw.Flush()
 
-		err = Json.NewEncoder(buf).Encode(data)
-		if err != nil {
-			return err
+	if _, err := sw.Write([]byte("[")); err != nil {
+		return err
+	}
+
+	var (
+		enc   = Json.NewEncoder(sw)
+		comma bool
+		err   error
+	)
+
+	e := rangeStream(ctx.Request.Context(), val, sw, func(it reflect.Value) bool {
+		if comma {
+			if _, err = sw.Write([]byte(",")); err != nil {
+				return false
+			}
 		}
-		_, err = buf.WriteTo(ctx.Response)
+		comma = true
+
+		err = enc.Encode(it.Interface())
+		return err == nil
+	})
+
+	if e != nil {
+		return e
+	}
+
+	if err != nil {
+		return abortStream(ctx, sw, err)
 	}
 
+	_, err = sw.Write([]byte("]\n"))
 	return err
 }
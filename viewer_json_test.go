@@ -1,6 +1,11 @@
 package xun
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"iter"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -42,3 +47,107 @@ func TestJsonViewerRenderError(t *testing.T) {
 	require.Equal(t, 200, rw.Code)
 
 }
+
+func TestJsonViewerStream(t *testing.T) {
+	render := func(v *JsonViewer, req *http.Request, data any) (*httptest.ResponseRecorder, error) {
+		rw := httptest.NewRecorder()
+		ctx := &Context{
+			Request:  req,
+			Response: NewResponseWriter(rw),
+		}
+		err := v.Render(ctx, data)
+		return rw, err
+	}
+
+	var seq iter.Seq[map[string]int] = func(yield func(map[string]int) bool) {
+		_ = yield(map[string]int{"id": 1}) && yield(map[string]int{"id": 2})
+	}
+
+	t.Run("seq_should_stream", func(t *testing.T) {
+		rw, err := render(&JsonViewer{Stream: true}, httptest.NewRequest(http.MethodGet, "/", nil), seq)
+		require.NoError(t, err)
+		require.True(t, rw.Flushed)
+		require.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+
+		var items []map[string]int
+		require.NoError(t, Json.NewDecoder(rw.Body).Decode(&items))
+		require.Equal(t, []map[string]int{{"id": 1}, {"id": 2}}, items)
+	})
+
+	t.Run("empty_should_be_array", func(t *testing.T) {
+		ch := make(chan int)
+		close(ch)
+
+		rw, err := render(&JsonViewer{Stream: true}, httptest.NewRequest(http.MethodGet, "/", nil), ch)
+		require.NoError(t, err)
+		require.Equal(t, "[]\n", rw.Body.String())
+	})
+
+	t.Run("non_stream_data_should_be_buffered", func(t *testing.T) {
+		rw, err := render(&JsonViewer{Stream: true}, httptest.NewRequest(http.MethodGet, "/", nil), []int{1, 2})
+		require.NoError(t, err)
+		require.False(t, rw.Flushed)
+		require.Equal(t, "[1,2]\n", rw.Body.String())
+	})
+
+	t.Run("stream_should_be_disabled_by_default", func(t *testing.T) {
+		_, err := render(&JsonViewer{}, httptest.NewRequest(http.MethodGet, "/", nil), seq)
+		require.Error(t, err)
+	})
+
+	t.Run("cancel_should_stop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		ch := make(chan int)
+		rw, err := render(&JsonViewer{Stream: true}, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx), ch)
+		require.ErrorIs(t, err, ErrCancelled)
+		require.Equal(t, "[", rw.Body.String())
+	})
+
+	t.Run("encode_error_should_fail", func(t *testing.T) {
+		ch := make(chan any, 1)
+		ch <- make(chan int)
+		close(ch)
+
+		var rw *httptest.ResponseRecorder
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			rw, _ = render(&JsonViewer{Stream: true}, httptest.NewRequest(http.MethodGet, "/", nil), ch)
+		})
+		require.Nil(t, rw)
+	})
+}
+
+func TestJsonViewerStreamAborted(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	app := New(WithMux(mux), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	app.Get("/items", func(c *Context) error {
+		var seq iter.Seq[any] = func(yield func(any) bool) {
+			_ = yield(1) && yield(make(chan int)) && yield(2)
+		}
+		return c.View(seq)
+	}, WithViewer(&JsonViewer{Stream: true}))
+
+	app.Start()
+	defer app.Close()
+
+	resp, err := client.Get(srv.URL + "/items")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// the response is interrupted rather than followed by an error status
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("X-Log-Id"))
+
+	buf, err := io.ReadAll(resp.Body)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Equal(t, "[1\n,", string(buf))
+	// the error is logged after the encoded items are sent, so wait for the handler to return
+	srv.Close()
+	require.Contains(t, logs.String(), `msg="xun: stream" err="json: unsupported type: chan int"`)
+}
@@ -0,0 +1,77 @@
+package xun
+
+import (
+	"reflect"
+	"time"
+)
+
+// NdjsonViewer is a viewer that writes the given data as newline delimited JSON
+// (https://github.com/ndjson/ndjson-spec) to the http.ResponseWriter.
+//
+// Each element of a slice, an iter.Seq or a channel is written as one line. A slice is
+// buffered like the other viewers, but an iter.Seq or a channel is streamed and flushed
+// every FlushSize bytes or FlushInterval, until the request is cancelled. An element that
+// fails to encode after a line has been sent aborts the response. Any other value is
+// written as a single line.
+type NdjsonViewer struct {
+	FlushSize     int
+	FlushInterval time.Duration
+}
+
+var ndjsonViewerMime = &MimeType{Type: "application", SubType: "x-ndjson"}
+
+// MimeType returns the MIME type of the NDJSON content.
+//
+// It returns "application/x-ndjson".
+func (*NdjsonViewer) MimeType() *MimeType {
+	return ndjsonViewerMime
+}
+
+// Render renders the given data as NDJSON to the http.ResponseWriter.
+//
+// It sets the Content-Type header to "application/x-ndjson".
+func (v *NdjsonViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
+	ctx.Response.Header().Set("Content-Type", "application/x-ndjson")
+	if data == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(data)
+	if _, ok := streamElem(val.Type()); ok {
+		// a stream is not ranged on HEAD, it may never end
+		if ctx.IsHead() {
+			return nil
+		}
+
+		sw := newStreamWriter(ctx.Response, v.FlushSize, v.FlushInterval)
+		defer sw.Flush()
+
+		var err error
+		enc := Json.NewEncoder(sw)
+		e := rangeStream(ctx.Request.Context(), val, sw, func(it reflect.Value) bool {
+			err = enc.Encode(it.Interface())
+			return err == nil
+		})
+
+		if e != nil {
+			return e
+		}
+		return abortStream(ctx, sw, err)
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	enc := Json.NewEncoder(buf)
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		for i := 0; i < val.Len(); i++ {
+			if err := enc.Encode(val.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	} else if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	return writeBuffer(ctx, buf)
+}
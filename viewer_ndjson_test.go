@@ -0,0 +1,186 @@
+package xun
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNdjsonViewer(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+
+	render := func(method string, data any) (*httptest.ResponseRecorder, error) {
+		rw := httptest.NewRecorder()
+		ctx := &Context{
+			Request:  httptest.NewRequest(method, "/", nil),
+			Response: NewResponseWriter(rw),
+		}
+		err := (&NdjsonViewer{}).Render(ctx, data)
+		return rw, err
+	}
+
+	t.Run("slice_should_work", func(t *testing.T) {
+		rw, err := render(http.MethodGet, []item{{1}, {2}})
+		require.NoError(t, err)
+		require.Equal(t, "application/x-ndjson", rw.Header().Get("Content-Type"))
+		require.False(t, rw.Flushed)
+		require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", rw.Body.String())
+	})
+
+	t.Run("value_should_work", func(t *testing.T) {
+		rw, err := render(http.MethodGet, item{1})
+		require.NoError(t, err)
+		require.Equal(t, "{\"id\":1}\n", rw.Body.String())
+
+		rw, err = render(http.MethodGet, nil)
+		require.NoError(t, err)
+		require.Empty(t, rw.Body.String())
+	})
+
+	t.Run("seq_should_stream", func(t *testing.T) {
+		var seq iter.Seq[item] = func(yield func(item) bool) {
+			_ = yield(item{1}) && yield(item{2})
+		}
+
+		rw, err := render(http.MethodGet, seq)
+		require.NoError(t, err)
+		require.True(t, rw.Flushed)
+		require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", rw.Body.String())
+	})
+
+	t.Run("chan_should_stream", func(t *testing.T) {
+		ch := make(chan item, 2)
+		ch <- item{1}
+		ch <- item{2}
+		close(ch)
+
+		rw, err := render(http.MethodGet, ch)
+		require.NoError(t, err)
+		require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", rw.Body.String())
+	})
+
+	t.Run("head_should_skip_body", func(t *testing.T) {
+		rw, err := render(http.MethodHead, []item{{1}})
+		require.NoError(t, err)
+		require.Equal(t, "application/x-ndjson", rw.Header().Get("Content-Type"))
+		require.Empty(t, rw.Body.String())
+	})
+
+	t.Run("encode_error_should_fail", func(t *testing.T) {
+		_, err := render(http.MethodGet, []any{make(chan int)})
+		require.Error(t, err)
+
+		var seq iter.Seq[any] = func(yield func(any) bool) {
+			_ = yield(1) && yield(make(chan int)) && yield(2)
+		}
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			render(http.MethodGet, seq) // nolint: errcheck
+		})
+
+		// nothing is sent yet, so the error is returned
+		seq = func(yield func(any) bool) {
+			_ = yield(make(chan int))
+		}
+		rw, err := render(http.MethodGet, seq)
+		require.Error(t, err)
+		require.Empty(t, rw.Body.String())
+	})
+}
+
+func TestNdjsonStreamWithGzip(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	app := New(WithMux(mux), WithCompressor(&GzipCompressor{}))
+
+	next := make(chan struct{})
+	done := make(chan error, 1)
+
+	app.Get("/events", func(c *Context) error {
+		ch := make(chan int)
+		go func() {
+			defer close(ch)
+			ch <- 1
+			<-next
+			ch <- 2
+		}()
+
+		err := c.View(ch)
+		done <- err
+		return err
+	}, WithViewer(&NdjsonViewer{FlushInterval: 10 * time.Millisecond}))
+
+	app.Start()
+	defer app.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	gr, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	r := bufio.NewReader(gr)
+
+	// the first line arrives before the producer sends the second one
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "1\n", line)
+
+	close(next)
+
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "2\n", line)
+
+	require.NoError(t, <-done)
+}
+
+func TestNdjsonStreamCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rw := httptest.NewRecorder()
+	c := &Context{
+		Request:  httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx),
+		Response: NewResponseWriter(rw),
+	}
+
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		cancel()
+	}()
+
+	err := (&NdjsonViewer{}).Render(c, ch)
+	require.ErrorIs(t, err, ErrCancelled)
+	require.Equal(t, "1\n", rw.Body.String())
+
+	var seq iter.Seq[int] = func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	rw = httptest.NewRecorder()
+	c.Response = NewResponseWriter(rw)
+	err = (&NdjsonViewer{}).Render(c, seq)
+	require.ErrorIs(t, err, ErrCancelled)
+	require.Empty(t, rw.Body.String())
+}
@@ -0,0 +1,212 @@
+package xun
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemJsonViewer is a viewer that writes a Problem as JSON to the http.ResponseWriter.
+//
+// It sets the Content-Type header to "application/problem+json", and the status code to
+// the Status of the problem. Data that is not a Problem is written as plain JSON.
+type ProblemJsonViewer struct {
+}
+
+var problemJsonViewerMime = &MimeType{Type: "application", SubType: "problem+json"}
+
+// MimeType returns the MIME type of the problem JSON content.
+//
+// It returns "application/problem+json".
+func (*ProblemJsonViewer) MimeType() *MimeType {
+	return problemJsonViewerMime
+}
+
+// Render renders the given data as problem JSON to the http.ResponseWriter.
+func (*ProblemJsonViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
+	ctx.Response.Header().Set("Content-Type", "application/problem+json")
+	p, ok := asProblem(data)
+	if ok {
+		data = p
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := Json.NewEncoder(buf).Encode(data); err != nil {
+		return err
+	}
+
+	if !ok {
+		return writeBuffer(ctx, buf)
+	}
+	return writeProblemBuffer(ctx, p, buf)
+}
+
+// ProblemXmlViewer is a viewer that writes a Problem as xml to the http.ResponseWriter.
+//
+// It sets the Content-Type header to "application/problem+xml", and the status code to
+// the Status of the problem. Data that is not a Problem is written as plain xml.
+type ProblemXmlViewer struct {
+}
+
+var problemXmlViewerMime = &MimeType{Type: "application", SubType: "problem+xml"}
+
+// MimeType returns the MIME type of the problem xml content.
+//
+// It returns "application/problem+xml".
+func (*ProblemXmlViewer) MimeType() *MimeType {
+	return problemXmlViewerMime
+}
+
+// Render renders the given data as problem xml to the http.ResponseWriter.
+func (*ProblemXmlViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
+	ctx.Response.Header().Set("Content-Type", "application/problem+xml; charset=utf-8")
+	p, ok := asProblem(data)
+	if ok {
+		data = p
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := xml.NewEncoder(buf).Encode(data); err != nil {
+		return err
+	}
+
+	if !ok {
+		return writeBuffer(ctx, buf)
+	}
+	return writeProblemBuffer(ctx, p, buf)
+}
+
+// ProblemHtmlViewer is a viewer that writes a Problem as a html page to the http.ResponseWriter.
+//
+// It sets the Content-Type header to "text/html; charset=utf-8", and the status code to
+// the Status of the problem. Data that is not a Problem is written as the detail of the page.
+type ProblemHtmlViewer struct {
+}
+
+var problemHtmlViewerMime = &MimeType{Type: "text", SubType: "html"}
+
+// MimeType returns the MIME type of the problem html content.
+//
+// It returns "text/html".
+func (*ProblemHtmlViewer) MimeType() *MimeType {
+	return problemHtmlViewerMime
+}
+
+var problemPage = template.Must(template.New("problem").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{ .Status }} {{ .Title }}</title></head>
+<body><h1>{{ .Title }}</h1>{{ with .Detail }}<p>{{ . }}</p>{{ end }}{{ with .Instance }}<p><small>{{ . }}</small></p>{{ end }}</body></html>
+`))
+
+// Render renders the given data as a problem html page to the http.ResponseWriter.
+func (*ProblemHtmlViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
+	ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	p, ok := asProblem(data)
+	if !ok {
+		p = &Problem{Detail: fmt.Sprint(data)}
+	}
+
+	page := *p
+	if page.Status == 0 {
+		page.Status = http.StatusInternalServerError
+	}
+	if page.Title == "" {
+		page.Title = http.StatusText(page.Status)
+	}
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := problemPage.Execute(buf, page); err != nil {
+		return err
+	}
+
+	if !ok {
+		return writeBuffer(ctx, buf)
+	}
+	return writeProblemBuffer(ctx, p, buf)
+}
+
+var (
+	problemJsonViewer = &ProblemJsonViewer{}
+	problemXmlViewer  = &ProblemXmlViewer{}
+	problemHtmlViewer = &ProblemHtmlViewer{}
+)
+
+// asProblem returns the Problem that data is, or that data wraps if it is an error.
+func asProblem(data any) (*Problem, bool) {
+	switch it := data.(type) {
+	case *Problem:
+		return it, it != nil
+	case Problem:
+		return &it, true
+	case error:
+		var p *Problem
+		if errors.As(it, &p) {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// writeProblemBuffer writes the rendered problem with its status. The headers are set before
+// the status is written, and like writeBuffer, a HEAD request gets them without buf.
+func writeProblemBuffer(ctx *Context, p *Problem, buf *bytes.Buffer) error {
+	if ctx.Response.Header().Get("Content-Encoding") == "" {
+		ctx.Response.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	}
+
+	if p.Status > 0 {
+		ctx.WriteStatus(p.Status)
+	} else {
+		ctx.WriteStatus(http.StatusInternalServerError)
+	}
+
+	if ctx.IsHead() {
+		return nil
+	}
+
+	_, err := buf.WriteTo(ctx.Response)
+	return err
+}
+
+// problemViewer returns the viewer for a problem. An Accept header that asks for
+// "application/problem+xml" or "application/problem+json" decides it, otherwise it is
+// xml if the route would render xml, a html page if the route would render html, e.g. a
+// page requested by a browser, and JSON for anything else.
+func (c *Context) problemViewer() Viewer {
+	for _, accept := range c.Accept() {
+		if accept.Type != "application" {
+			continue
+		}
+
+		switch accept.SubType {
+		case "problem+xml":
+			return problemXmlViewer
+		case "problem+json":
+			return problemJsonViewer
+		}
+	}
+
+	if v := c.negotiate(""); v != nil {
+		mime := v.MimeType()
+		if mime.SubType == "xml" || strings.HasSuffix(mime.SubType, "+xml") {
+			return problemXmlViewer
+		}
+		if mime.Type == "text" && mime.SubType == "html" {
+			return problemHtmlViewer
+		}
+	}
+
+	return problemJsonViewer
+}
@@ -0,0 +1,127 @@
+package xun
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemViewer(t *testing.T) {
+	mux := http.NewServeMux()
+
+	var logs bytes.Buffer
+	app := New(WithMux(mux), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	notFound := NewProblem(http.StatusNotFound, "user not found")
+
+	app.Get("/problem", func(c *Context) error {
+		return notFound
+	})
+
+	app.Get("/wrapped", func(c *Context) error {
+		return fmt.Errorf("load user: %w", notFound)
+	})
+
+	app.Get("/xml", func(c *Context) error {
+		return &Problem{Type: "https://example.com/probs/locked", Status: http.StatusLocked, Instance: "/orders/1"}
+	}, WithViewer(&XmlViewer{}))
+
+	app.Get("/panic", func(c *Context) error {
+		return &Problem{Detail: "database is down"}
+	})
+
+	app.Get("/view", func(c *Context) error {
+		return c.View(NewProblem(http.StatusConflict, "conflict"))
+	}, WithViewer(&ProblemJsonViewer{}))
+
+	app.Get("/error", func(c *Context) error {
+		return errors.New("plain")
+	})
+
+	app.Get("/html", func(c *Context) error {
+		return NewProblem(http.StatusForbidden, "<admin> only")
+	}, WithViewer(&HtmlViewer{}))
+
+	app.Start()
+	defer app.Close()
+	logs.Reset()
+
+	t.Run("problem_should_be_json", func(t *testing.T) {
+		for _, path := range []string{"/problem", "/wrapped"} {
+			rw := serve(mux, http.MethodGet, path, "Accept", "application/json")
+			require.Equal(t, http.StatusNotFound, rw.Code)
+			require.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+
+			logID := rw.Header().Get("X-Log-Id")
+			require.NotEmpty(t, logID)
+
+			var p Problem
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &p))
+			require.Equal(t, Problem{Title: "Not Found", Status: http.StatusNotFound, Detail: "user not found", Instance: logID}, p)
+		}
+
+		// the returned problem is not changed
+		require.Empty(t, notFound.Instance)
+		// client errors are not logged
+		require.NotContains(t, logs.String(), "level=ERROR")
+	})
+
+	t.Run("problem_should_be_xml", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/xml", "Accept", "text/xml")
+		require.Equal(t, http.StatusLocked, rw.Code)
+		require.Equal(t, "application/problem+xml; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Equal(t, `<problem xmlns="urn:ietf:rfc:7807"><instance>/orders/1</instance><status>423</status><type>https://example.com/probs/locked</type></problem>`, rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/problem", "Accept", "application/problem+xml, application/problem+json")
+		require.Equal(t, "application/problem+xml; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.True(t, strings.HasPrefix(rw.Body.String(), `<problem xmlns="urn:ietf:rfc:7807">`))
+
+		rw = serve(mux, http.MethodGet, "/xml", "Accept", "application/problem+json")
+		require.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+	})
+
+	t.Run("problem_should_be_html", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/html", "Accept", "text/html")
+		require.Equal(t, http.StatusForbidden, rw.Code)
+		require.Equal(t, "text/html; charset=utf-8", rw.Header().Get("Content-Type"))
+		require.Equal(t, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>403 Forbidden</title></head>
+<body><h1>Forbidden</h1><p>&lt;admin&gt; only</p><p><small>`+rw.Header().Get("X-Log-Id")+`</small></p></body></html>
+`, rw.Body.String())
+
+		rw = serve(mux, http.MethodGet, "/html", "Accept", "application/problem+json")
+		require.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+
+		// a route that doesn't render html keeps problem details
+		rw = serve(mux, http.MethodGet, "/problem", "Accept", "text/html")
+		require.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+	})
+
+	t.Run("server_error_should_be_logged", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/panic")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"detail":"database is down","instance":"`+rw.Header().Get("X-Log-Id")+`"}`, rw.Body.String())
+		require.Contains(t, logs.String(), "logid="+rw.Header().Get("X-Log-Id"))
+	})
+
+	t.Run("view_should_write_status", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/view")
+		require.Equal(t, http.StatusConflict, rw.Code)
+		require.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"title":"Conflict","status":409,"detail":"conflict"}`, rw.Body.String())
+	})
+
+	t.Run("error_should_not_be_problem", func(t *testing.T) {
+		rw := serve(mux, http.MethodGet, "/error")
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Empty(t, rw.Body.String())
+	})
+}
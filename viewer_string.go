@@ -2,7 +2,7 @@ package xun
 
 import (
 	"fmt"
-	"net/http"
+	"strconv"
 )
 
 // StringViewer is a viewer that writes the given data as string to the http.ResponseWriter.
@@ -30,9 +30,15 @@ func (*StringViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
 		return nil
 	}
 
-	if ctx.Request.Method != http.MethodHead {
-		_, err = fmt.Fprint(ctx.Response, data)
+	if ctx.IsHead() {
+		// the compressed length is unknown
+		if ctx.Response.Header().Get("Content-Encoding") == "" {
+			ctx.Response.Header().Set("Content-Length", strconv.Itoa(len(fmt.Sprint(data))))
+		}
+		return nil
 	}
 
+	_, err = fmt.Fprint(ctx.Response, data)
+
 	return err
 }
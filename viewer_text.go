@@ -1,40 +1,94 @@
 package xun
 
-import (
-	"net/http"
-)
+import "sync"
 
 func NewTextViewer(t *TextTemplate) *TextViewer {
 	return &TextViewer{template: t}
 }
 
 // TextViewer is a struct that holds an TextTemplate and is used to render text content.
+//
+// Locale variants such as `text/mail/welcome.fr.txt` are kept next to the default
+// template, and the best variant for the request is picked by `Context.Locales`.
 type TextViewer struct {
 	template *TextTemplate
+	locales  map[string]*TextTemplate
+	reload   *sync.RWMutex
 }
 
 // MimeType returns the MIME type for the text content rendered by the TextViewer.
 func (v *TextViewer) MimeType() *MimeType {
-	return &v.template.mime
+	defer rlock(v.reload)()
+
+	t := v.template
+	if t == nil {
+		_, t, _ = firstLocale(v.locales)
+	}
+
+	if t == nil {
+		// it has been removed in watch mode
+		return &MimeType{Type: "text", SubType: "plain"}
+	}
+	return &t.current().mime
 }
 
 // Render writes the text content rendered by the TextViewer to the provided http.ResponseWriter.
 // It sets the Content-Type header to "text/plain; charset=utf-8" and writes the rendered content to the response.
 // If there is an error executing the template, it is returned.
 func (v *TextViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
-	var err error
-	ctx.Response.Header().Set("Content-Type", v.template.mime.String()+v.template.charset)
-	if ctx.Request.Method != http.MethodHead {
-		buf := BufPool.Get()
-		defer BufPool.Put(buf)
+	it := v.resolve(ctx)
+	if it == nil {
+		// it has been removed in watch mode
+		return ErrViewNotFound
+	}
+
+	t := it.current()
+	ctx.Response.Header().Set("Content-Type", t.mime.String()+t.charset)
+
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
+
+	if err := t.template.Execute(buf, ViewModel{TempData: ctx.TempData, Data: data}); err != nil {
+		return err
+	}
+
+	return writeBuffer(ctx, buf)
+}
+
+// setTemplate sets the default template when locale is empty, otherwise the template of the locale variant.
+func (v *TextViewer) setTemplate(locale string, t *TextTemplate) {
+	if locale == "" {
+		v.template = t
+		return
+	}
+
+	if v.locales == nil {
+		v.locales = make(map[string]*TextTemplate)
+	}
+	v.locales[locale] = t
+}
+
+// unsetTemplate removes the default template when locale is empty, otherwise the template of
+// the locale variant. A viewer without templates renders ErrViewNotFound.
+func (v *TextViewer) unsetTemplate(locale string) {
+	if locale == "" {
+		v.template = nil
+		return
+	}
+
+	delete(v.locales, locale)
+}
 
-		err = v.template.Execute(buf, ViewModel{TempData: ctx.TempData, Data: data})
-		if err != nil {
-			return err
-		}
+// resolve returns the template of the best matched locale variant, and writes the
+// Content-Language and Vary headers when the viewer has any locale variants.
+func (v *TextViewer) resolve(ctx *Context) *TextTemplate {
+	defer rlock(v.reload)()
 
-		_, err = buf.WriteTo(ctx.Response)
+	if len(v.locales) == 0 {
+		return v.template
 	}
 
-	return err
+	locale, t := pickLocale(ctx.Locales(), ctx.defaultLocale(), v.template, v.locales)
+	writeLocaleHeaders(ctx, locale)
+	return t
 }
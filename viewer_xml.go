@@ -2,7 +2,6 @@ package xun
 
 import (
 	"encoding/xml"
-	"net/http"
 )
 
 // XmlViewer is a viewer that writes the given data as xml to the http.ResponseWriter.
@@ -24,18 +23,13 @@ func (*XmlViewer) MimeType() *MimeType {
 //
 // It sets the Content-Type header to "text/xml; charset=utf-8".
 func (*XmlViewer) Render(ctx *Context, data any) error { // skipcq: RVV-B0012
-	var err error
 	ctx.Response.Header().Set("Content-Type", "text/xml; charset=utf-8")
-	if ctx.Request.Method != http.MethodHead {
-		buf := BufPool.Get()
-		defer BufPool.Put(buf)
 
-		err = xml.NewEncoder(buf).Encode(data)
-		if err != nil {
-			return err
-		}
-		_, err = buf.WriteTo(ctx.Response)
-	}
+	buf := BufPool.Get()
+	defer BufPool.Put(buf)
 
-	return err
+	if err := xml.NewEncoder(buf).Encode(data); err != nil {
+		return err
+	}
+	return writeBuffer(ctx, buf)
 }